@@ -0,0 +1,118 @@
+// Package access provides the Grant aggregate, i.e. a token giving its bearer
+// access to tracking information. A grant is either a share token for a
+// single cargo, handed out when the cargo is booked, or the session of a
+// customer.
+package access
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+)
+
+// Token is the secret presented by the bearer of a grant.
+type Token string
+
+// View describes how much of a cargo the bearer of a grant may see.
+type View int
+
+// Valid views.
+const (
+	// NoView means the cargo may not be seen at all.
+	NoView View = iota
+
+	// PublicView is limited to the status and ETA of the cargo.
+	PublicView
+
+	// FullView includes the route and handling history of the cargo.
+	FullView
+)
+
+// SessionTTL is how long a customer session lasts.
+const SessionTTL = 12 * time.Hour
+
+// Grant gives access to either a single cargo or the cargos of a customer.
+type Grant struct {
+	Token      Token
+	TrackingID cargo.TrackingID
+	Customer   customer.ID
+	Issued     time.Time
+
+	// Expires is zero for grants that never expire, and Revoked is zero
+	// unless the grant has been revoked.
+	Expires time.Time
+	Revoked time.Time
+}
+
+// NewShareToken creates a grant giving the public view of a cargo to anyone
+// holding the token.
+func NewShareToken(id cargo.TrackingID, now time.Time) *Grant {
+	return &Grant{
+		Token:      NextToken(),
+		TrackingID: id,
+		Issued:     now,
+	}
+}
+
+// NewSession creates a grant giving a customer the full view of the cargos
+// it is a party of.
+func NewSession(id customer.ID, now time.Time) *Grant {
+	return &Grant{
+		Token:    NextToken(),
+		Customer: id,
+		Issued:   now,
+		Expires:  now.Add(SessionTTL),
+	}
+}
+
+// Active checks whether the grant may be used at the given time.
+func (g *Grant) Active(now time.Time) bool {
+	if !g.Revoked.IsZero() {
+		return false
+	}
+	return g.Expires.IsZero() || now.Before(g.Expires)
+}
+
+// Revoke stops the grant from being used any further.
+func (g *Grant) Revoke(now time.Time) {
+	if g.Revoked.IsZero() {
+		g.Revoked = now
+	}
+}
+
+// ViewOf returns how much of the cargo the bearer may see at the given time.
+func (g *Grant) ViewOf(c *cargo.Cargo, now time.Time) View {
+	if !g.Active(now) {
+		return NoView
+	}
+	if g.Customer != "" && c.Parties.Includes(g.Customer) {
+		return FullView
+	}
+	if g.TrackingID != "" && g.TrackingID == c.TrackingID {
+		return PublicView
+	}
+	return NoView
+}
+
+// ErrUnknown is used when a grant could not be found.
+var ErrUnknown = errors.New("unknown access token")
+
+// Repository provides access a grant store.
+type Repository interface {
+	Store(g *Grant) error
+	Find(t Token) (*Grant, error)
+	FindByCargo(id cargo.TrackingID) []*Grant
+}
+
+// NextToken generates a new random token.
+func NextToken() Token {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return Token(hex.EncodeToString(b))
+}
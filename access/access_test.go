@@ -0,0 +1,41 @@
+package access
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/location"
+)
+
+func TestViewOf(t *testing.T) {
+	now := time.Date(2016, time.May, 1, 12, 0, 0, 0, time.UTC)
+
+	c := cargo.New("ABC123", cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.AUMEL,
+	})
+	c.AttachParty(cargo.Shipper, "C1")
+
+	revoked := NewShareToken("ABC123", now)
+	revoked.Revoke(now)
+
+	tests := []struct {
+		grant *Grant
+		at    time.Time
+		want  View
+	}{
+		{NewShareToken("ABC123", now), now, PublicView},
+		{NewShareToken("XYZ789", now), now, NoView},
+		{NewSession("C1", now), now, FullView},
+		{NewSession("C1", now), now.Add(SessionTTL), NoView},
+		{NewSession("C2", now), now, NoView},
+		{revoked, now, NoView},
+	}
+
+	for i, tt := range tests {
+		if got := tt.grant.ViewOf(c, tt.at); got != tt.want {
+			t.Errorf("%d: ViewOf() = %d; want = %d", i, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Package allotment provides the Allotment aggregate, i.e. space on a voyage
+// contracted by a customer.
+package allotment
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/pborman/uuid"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/voyage"
+)
+
+// ID uniquely identifies a particular allotment.
+type ID string
+
+// Allotment is the space on a voyage reserved for a customer, measured in
+// TEU and weight.
+type Allotment struct {
+	ID       ID
+	Customer customer.ID
+	Voyage   voyage.Number
+	Capacity cargo.Measurement
+
+	// Waitlist determines whether cargos are put on the waitlist rather than
+	// rejected once the allotment has been exhausted.
+	Waitlist bool
+
+	Consumptions []Consumption
+	Waitlisted   []cargo.TrackingID
+
+	// Version is incremented every time the allotment is stored, so that
+	// concurrent changes to it can be detected.
+	Version int
+}
+
+// Consumption is the space used by a single cargo.
+type Consumption struct {
+	TrackingID  cargo.TrackingID
+	Measurement cargo.Measurement
+}
+
+// New creates a new, unused allotment.
+func New(id ID, c customer.ID, v voyage.Number, capacity cargo.Measurement, waitlist bool) *Allotment {
+	return &Allotment{
+		ID:       id,
+		Customer: c,
+		Voyage:   v,
+		Capacity: capacity,
+		Waitlist: waitlist,
+	}
+}
+
+// Copy returns a deep copy of the allotment.
+func (a *Allotment) Copy() *Allotment {
+	c := *a
+	c.Consumptions = append([]Consumption(nil), a.Consumptions...)
+	c.Waitlisted = append([]cargo.TrackingID(nil), a.Waitlisted...)
+	return &c
+}
+
+// Used returns the total space used by cargos.
+func (a *Allotment) Used() cargo.Measurement {
+	var m cargo.Measurement
+	for _, c := range a.Consumptions {
+		m.TEU += c.Measurement.TEU
+		m.Weight += c.Measurement.Weight
+	}
+	return m
+}
+
+// Remaining returns the space left in the allotment.
+func (a *Allotment) Remaining() cargo.Measurement {
+	u := a.Used()
+	return cargo.Measurement{
+		TEU:    a.Capacity.TEU - u.TEU,
+		Weight: a.Capacity.Weight - u.Weight,
+	}
+}
+
+// Holds checks whether the cargo uses space in the allotment.
+func (a *Allotment) Holds(id cargo.TrackingID) bool {
+	for _, c := range a.Consumptions {
+		if c.TrackingID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// IsWaitlisted checks whether the cargo is waiting for space in the
+// allotment.
+func (a *Allotment) IsWaitlisted(id cargo.TrackingID) bool {
+	for _, w := range a.Waitlisted {
+		if w == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Consume reserves space for a cargo. If there is not enough space left the
+// cargo is either waitlisted or rejected, depending on the allotment.
+func (a *Allotment) Consume(id cargo.TrackingID, m cargo.Measurement) error {
+	if a.Holds(id) {
+		return nil
+	}
+
+	r := a.Remaining()
+	if m.TEU > r.TEU || (a.Capacity.Weight > 0 && m.Weight > r.Weight) {
+		if a.Waitlist {
+			a.addToWaitlist(id)
+			return ErrWaitlisted
+		}
+		return ErrExhausted
+	}
+
+	a.Consumptions = append(a.Consumptions, Consumption{TrackingID: id, Measurement: m})
+	a.removeFromWaitlist(id)
+
+	return nil
+}
+
+// Release frees the space used by a cargo and removes it from the
+// waitlist.
+func (a *Allotment) Release(id cargo.TrackingID) {
+	for i, c := range a.Consumptions {
+		if c.TrackingID == id {
+			a.Consumptions = append(a.Consumptions[:i], a.Consumptions[i+1:]...)
+			break
+		}
+	}
+	a.removeFromWaitlist(id)
+}
+
+func (a *Allotment) addToWaitlist(id cargo.TrackingID) {
+	if a.IsWaitlisted(id) {
+		return
+	}
+	a.Waitlisted = append(a.Waitlisted, id)
+}
+
+func (a *Allotment) removeFromWaitlist(id cargo.TrackingID) {
+	for i, w := range a.Waitlisted {
+		if w == id {
+			a.Waitlisted = append(a.Waitlisted[:i], a.Waitlisted[i+1:]...)
+			return
+		}
+	}
+}
+
+var (
+	// ErrUnknown is used when an allotment could not be found.
+	ErrUnknown = errors.New("unknown allotment")
+
+	// ErrExhausted is used when there is not enough space left in an
+	// allotment.
+	ErrExhausted = errors.New("allotment exhausted")
+
+	// ErrWaitlisted is used when a cargo has been put on the waitlist of an
+	// exhausted allotment.
+	ErrWaitlisted = errors.New("allotment exhausted, cargo waitlisted")
+
+	// ErrConflict is used when an allotment has been changed since it was
+	// read.
+	ErrConflict = errors.New("allotment changed concurrently")
+)
+
+// Repository provides access an allotment store.
+type Repository interface {
+	// Store stores the allotment and increments its version. It fails with
+	// ErrConflict if the stored allotment has another version, i.e. if it
+	// has been stored by someone else since it was read.
+	Store(a *Allotment) error
+	Find(id ID) (*Allotment, error)
+	FindByCustomer(c customer.ID) []*Allotment
+	FindAll() []*Allotment
+}
+
+// NextID generates a new allotment ID.
+func NextID() ID {
+	return ID(strings.Split(strings.ToUpper(uuid.New()), "-")[0])
+}
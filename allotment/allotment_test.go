@@ -0,0 +1,60 @@
+package allotment
+
+import (
+	"testing"
+
+	"github.com/marcusolsson/goddd/cargo"
+)
+
+func TestConsume(t *testing.T) {
+	a := New("A1", "ACME", "V100", cargo.Measurement{TEU: 2, Weight: 1000}, false)
+
+	if err := a.Consume("ABC", cargo.Measurement{TEU: 1, Weight: 400}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Consume("ABC", cargo.Measurement{TEU: 1, Weight: 400}); err != nil {
+		t.Fatal(err)
+	}
+	if used := a.Used(); used.TEU != 1 {
+		t.Errorf("used.TEU = %d; want = %d", used.TEU, 1)
+	}
+
+	if err := a.Consume("DEF", cargo.Measurement{TEU: 1, Weight: 700}); err != ErrExhausted {
+		t.Errorf("err = %v; want = %v", err, ErrExhausted)
+	}
+	if err := a.Consume("DEF", cargo.Measurement{TEU: 1, Weight: 600}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Consume("GHI", cargo.Measurement{TEU: 1}); err != ErrExhausted {
+		t.Errorf("err = %v; want = %v", err, ErrExhausted)
+	}
+
+	a.Release("ABC")
+
+	if r := a.Remaining(); r.TEU != 1 || r.Weight != 400 {
+		t.Errorf("a.Remaining() = %+v; want = %+v", r, cargo.Measurement{TEU: 1, Weight: 400})
+	}
+}
+
+func TestConsumeWaitlist(t *testing.T) {
+	a := New("A1", "ACME", "V100", cargo.Measurement{TEU: 1}, true)
+
+	if err := a.Consume("ABC", cargo.Measurement{TEU: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Consume("DEF", cargo.Measurement{TEU: 1}); err != ErrWaitlisted {
+		t.Errorf("err = %v; want = %v", err, ErrWaitlisted)
+	}
+	if !a.IsWaitlisted("DEF") {
+		t.Errorf("DEF should be waitlisted")
+	}
+
+	a.Release("ABC")
+
+	if err := a.Consume("DEF", cargo.Measurement{TEU: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if a.IsWaitlisted("DEF") {
+		t.Errorf("DEF should no longer be waitlisted")
+	}
+}
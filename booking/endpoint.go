@@ -6,19 +6,28 @@ import (
 	"github.com/go-kit/kit/endpoint"
 	"golang.org/x/net/context"
 
+	"github.com/marcusolsson/goddd/access"
+	"github.com/marcusolsson/goddd/allotment"
 	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/document"
 	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/routing"
+	"github.com/marcusolsson/goddd/voyage"
 )
 
 type bookCargoRequest struct {
 	Origin          location.UNLocode
 	Destination     location.UNLocode
 	ArrivalDeadline time.Time
+	AutoRoute       AutoRoute
 }
 
 type bookCargoResponse struct {
-	ID  cargo.TrackingID `json:"tracking_id,omitempty"`
-	Err error            `json:"error,omitempty"`
+	ID              cargo.TrackingID `json:"tracking_id,omitempty"`
+	Itinerary       *cargo.Itinerary `json:"itinerary,omitempty"`
+	NotRoutedReason string           `json:"not_routed_reason,omitempty"`
+	Err             error            `json:"error,omitempty"`
 }
 
 func (r bookCargoResponse) error() error { return r.Err }
@@ -26,8 +35,13 @@ func (r bookCargoResponse) error() error { return r.Err }
 func makeBookCargoEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(bookCargoRequest)
-		id, err := s.BookNewCargo(req.Origin, req.Destination, req.ArrivalDeadline)
-		return bookCargoResponse{ID: id, Err: err}, nil
+		b, err := s.BookNewCargo(req.Origin, req.Destination, req.ArrivalDeadline, req.AutoRoute)
+		return bookCargoResponse{
+			ID:              b.TrackingID,
+			Itinerary:       b.Itinerary,
+			NotRoutedReason: b.NotRoutedReason,
+			Err:             err,
+		}, nil
 	}
 }
 
@@ -51,12 +65,13 @@ func makeLoadCargoEndpoint(bs Service) endpoint.Endpoint {
 }
 
 type requestRoutesRequest struct {
-	ID cargo.TrackingID
+	ID          cargo.TrackingID
+	Constraints routing.Constraints
 }
 
 type requestRoutesResponse struct {
-	Routes []cargo.Itinerary `json:"routes,omitempty"`
-	Err    error             `json:"error,omitempty"`
+	Routes []routing.Candidate `json:"routes,omitempty"`
+	Err    error               `json:"error,omitempty"`
 }
 
 func (r requestRoutesResponse) error() error { return r.Err }
@@ -64,8 +79,8 @@ func (r requestRoutesResponse) error() error { return r.Err }
 func makeRequestRoutesEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(requestRoutesRequest)
-		itin := s.RequestPossibleRoutesForCargo(req.ID)
-		return requestRoutesResponse{Routes: itin, Err: nil}, nil
+		itin, err := s.RequestPossibleRoutesForCargo(req.ID, req.Constraints)
+		return requestRoutesResponse{Routes: itin, Err: err}, nil
 	}
 }
 
@@ -107,19 +122,61 @@ func makeChangeDestinationEndpoint(s Service) endpoint.Endpoint {
 	}
 }
 
-type listCargosRequest struct{}
+type changeArrivalDeadlineRequest struct {
+	ID              cargo.TrackingID
+	ArrivalDeadline time.Time
+}
+
+type changeArrivalDeadlineResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r changeArrivalDeadlineResponse) error() error { return r.Err }
+
+func makeChangeArrivalDeadlineEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(changeArrivalDeadlineRequest)
+		err := s.ChangeArrivalDeadline(req.ID, req.ArrivalDeadline)
+		return changeArrivalDeadlineResponse{Err: err}, nil
+	}
+}
+
+type changeOriginRequest struct {
+	ID     cargo.TrackingID
+	Origin location.UNLocode
+}
+
+type changeOriginResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r changeOriginResponse) error() error { return r.Err }
+
+func makeChangeOriginEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(changeOriginRequest)
+		err := s.ChangeOrigin(req.ID, req.Origin)
+		return changeOriginResponse{Err: err}, nil
+	}
+}
+
+type listCargosRequest struct {
+	Query cargo.Query
+}
 
 type listCargosResponse struct {
-	Cargos []Cargo `json:"cargos,omitempty"`
-	Err    error   `json:"error,omitempty"`
+	Cargos     []Cargo `json:"cargos,omitempty"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+	Err        error   `json:"error,omitempty"`
 }
 
 func (r listCargosResponse) error() error { return r.Err }
 
 func makeListCargosEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		_ = request.(listCargosRequest)
-		return listCargosResponse{Cargos: s.Cargos(), Err: nil}, nil
+		req := request.(listCargosRequest)
+		cargos, next, err := s.Cargos(req.Query)
+		return listCargosResponse{Cargos: cargos, NextCursor: next, Err: err}, nil
 	}
 }
 
@@ -137,3 +194,318 @@ func makeListLocationsEndpoint(s Service) endpoint.Endpoint {
 		return listLocationsResponse{Locations: s.Locations(), Err: nil}, nil
 	}
 }
+
+type registerCustomerRequest struct {
+	Name    string
+	Address string
+	Email   string
+}
+
+type registerCustomerResponse struct {
+	ID  customer.ID `json:"id,omitempty"`
+	Err error       `json:"error,omitempty"`
+}
+
+func (r registerCustomerResponse) error() error { return r.Err }
+
+func makeRegisterCustomerEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(registerCustomerRequest)
+		id, err := s.RegisterCustomer(req.Name, req.Address, req.Email)
+		return registerCustomerResponse{ID: id, Err: err}, nil
+	}
+}
+
+type listCustomersRequest struct{}
+
+type listCustomersResponse struct {
+	Customers []Customer `json:"customers,omitempty"`
+	Err       error      `json:"error,omitempty"`
+}
+
+func makeListCustomersEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		_ = request.(listCustomersRequest)
+		return listCustomersResponse{Customers: s.Customers(), Err: nil}, nil
+	}
+}
+
+type setNotificationChannelsRequest struct {
+	ID       customer.ID
+	Channels []customer.Channel
+}
+
+type setNotificationChannelsResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r setNotificationChannelsResponse) error() error { return r.Err }
+
+func makeSetNotificationChannelsEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(setNotificationChannelsRequest)
+		err := s.SetNotificationChannels(req.ID, req.Channels)
+		return setNotificationChannelsResponse{Err: err}, nil
+	}
+}
+
+type attachPartyRequest struct {
+	ID         cargo.TrackingID
+	Role       cargo.Role
+	CustomerID customer.ID
+}
+
+type attachPartyResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r attachPartyResponse) error() error { return r.Err }
+
+func makeAttachPartyEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(attachPartyRequest)
+		err := s.AttachParty(req.ID, req.Role, req.CustomerID)
+		return attachPartyResponse{Err: err}, nil
+	}
+}
+
+type addReferenceRequest struct {
+	ID    cargo.TrackingID
+	Type  cargo.ReferenceType
+	Value string
+}
+
+type addReferenceResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r addReferenceResponse) error() error { return r.Err }
+
+func makeAddReferenceEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(addReferenceRequest)
+		err := s.AddReference(req.ID, req.Type, req.Value)
+		return addReferenceResponse{Err: err}, nil
+	}
+}
+
+type changeMeasurementRequest struct {
+	ID          cargo.TrackingID
+	Measurement cargo.Measurement
+}
+
+type changeMeasurementResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r changeMeasurementResponse) error() error { return r.Err }
+
+func makeChangeMeasurementEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(changeMeasurementRequest)
+		err := s.ChangeMeasurement(req.ID, req.Measurement)
+		return changeMeasurementResponse{Err: err}, nil
+	}
+}
+
+type cancelBookingRequest struct {
+	ID cargo.TrackingID
+}
+
+type cancelBookingResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r cancelBookingResponse) error() error { return r.Err }
+
+func makeCancelBookingEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(cancelBookingRequest)
+		err := s.CancelBooking(req.ID)
+		return cancelBookingResponse{Err: err}, nil
+	}
+}
+
+type registerAllotmentRequest struct {
+	CustomerID customer.ID
+	Voyage     voyage.Number
+	Capacity   cargo.Measurement
+	Waitlist   bool
+}
+
+type registerAllotmentResponse struct {
+	ID  allotment.ID `json:"id,omitempty"`
+	Err error        `json:"error,omitempty"`
+}
+
+func (r registerAllotmentResponse) error() error { return r.Err }
+
+func makeRegisterAllotmentEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(registerAllotmentRequest)
+		id, err := s.RegisterAllotment(req.CustomerID, req.Voyage, req.Capacity, req.Waitlist)
+		return registerAllotmentResponse{ID: id, Err: err}, nil
+	}
+}
+
+type listAllotmentsRequest struct {
+	CustomerID customer.ID
+}
+
+type listAllotmentsResponse struct {
+	Allotments []Allotment `json:"allotments,omitempty"`
+	Err        error       `json:"error,omitempty"`
+}
+
+func makeListAllotmentsEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(listAllotmentsRequest)
+		return listAllotmentsResponse{Allotments: s.Allotments(req.CustomerID), Err: nil}, nil
+	}
+}
+
+type listDocumentsRequest struct {
+	ID cargo.TrackingID
+}
+
+type listDocumentsResponse struct {
+	Documents []Document `json:"documents"`
+	Err       error      `json:"error,omitempty"`
+}
+
+func (r listDocumentsResponse) error() error { return r.Err }
+
+func makeListDocumentsEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(listDocumentsRequest)
+		docs, err := s.Documents(req.ID)
+		return listDocumentsResponse{Documents: docs, Err: err}, nil
+	}
+}
+
+type loadBillOfLadingRequest struct {
+	ID      cargo.TrackingID
+	Version int
+	Format  string
+}
+
+type loadBillOfLadingResponse struct {
+	BillOfLading document.BillOfLading
+	Format       string
+	Err          error
+}
+
+func (r loadBillOfLadingResponse) error() error { return r.Err }
+
+func makeLoadBillOfLadingEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(loadBillOfLadingRequest)
+		b, err := s.BillOfLading(req.ID, req.Version)
+		return loadBillOfLadingResponse{BillOfLading: b, Format: req.Format, Err: err}, nil
+	}
+}
+
+type issueShareTokenRequest struct {
+	ID cargo.TrackingID
+}
+
+type issueShareTokenResponse struct {
+	Token *AccessToken `json:"token,omitempty"`
+	Err   error        `json:"error,omitempty"`
+}
+
+func (r issueShareTokenResponse) error() error { return r.Err }
+
+func makeIssueShareTokenEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(issueShareTokenRequest)
+		t, err := s.IssueShareToken(req.ID)
+		if err != nil {
+			return issueShareTokenResponse{Err: err}, nil
+		}
+		return issueShareTokenResponse{Token: &t}, nil
+	}
+}
+
+type listShareTokensRequest struct {
+	ID cargo.TrackingID
+}
+
+type listShareTokensResponse struct {
+	Tokens []AccessToken `json:"tokens"`
+	Err    error         `json:"error,omitempty"`
+}
+
+func (r listShareTokensResponse) error() error { return r.Err }
+
+func makeListShareTokensEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(listShareTokensRequest)
+		tokens, err := s.ShareTokens(req.ID)
+		return listShareTokensResponse{Tokens: tokens, Err: err}, nil
+	}
+}
+
+type openSessionRequest struct {
+	CustomerID customer.ID
+}
+
+type openSessionResponse struct {
+	Session *AccessToken `json:"session,omitempty"`
+	Err     error        `json:"error,omitempty"`
+}
+
+func (r openSessionResponse) error() error { return r.Err }
+
+func makeOpenSessionEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(openSessionRequest)
+		t, err := s.OpenSession(req.CustomerID)
+		if err != nil {
+			return openSessionResponse{Err: err}, nil
+		}
+		return openSessionResponse{Session: &t}, nil
+	}
+}
+
+type revokeTokenRequest struct {
+	Token access.Token
+}
+
+type revokeTokenResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r revokeTokenResponse) error() error { return r.Err }
+
+func makeRevokeTokenEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(revokeTokenRequest)
+		err := s.RevokeToken(req.Token)
+		return revokeTokenResponse{Err: err}, nil
+	}
+}
+
+type importCargosRequest struct {
+	Rows      []ImportRow
+	AutoRoute AutoRoute
+	DryRun    bool
+}
+
+type importCargosResponse struct {
+	Report *ImportReport `json:"report,omitempty"`
+	Err    error         `json:"error,omitempty"`
+}
+
+func (r importCargosResponse) error() error { return r.Err }
+
+func makeImportCargosEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(importCargosRequest)
+		r, err := s.ImportCargos(req.Rows, req.AutoRoute, req.DryRun)
+		if err != nil {
+			return importCargosResponse{Err: err}, nil
+		}
+		return importCargosResponse{Report: &r}, nil
+	}
+}
@@ -0,0 +1,136 @@
+package booking
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/marcusolsson/goddd/location"
+)
+
+// Columns expected in a CSV import. The header row may list them in any
+// order.
+var importColumns = []string{"origin", "destination", "arrival_deadline"}
+
+// parseCSV reads import rows from CSV with a header row. Rows that cannot be
+// parsed are returned with an error, so that they can be reported alongside
+// the rest. Parsing stops as soon as there are more than MaxImportRows rows.
+func parseCSV(r io.Reader) ([]ImportRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, ErrInvalidArgument
+	}
+
+	index := make(map[string]int)
+	for i, h := range header {
+		index[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, col := range importColumns {
+		if _, ok := index[col]; !ok {
+			return nil, ErrInvalidArgument
+		}
+	}
+
+	var rows []ImportRow
+	for line := 2; ; line++ {
+		if len(rows) > MaxImportRows {
+			return nil, ErrInvalidArgument
+		}
+
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); !ok {
+				return nil, err
+			}
+			rows = append(rows, ImportRow{Line: line, Err: err})
+			continue
+		}
+
+		field := func(col string) string {
+			if i := index[col]; i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+
+		row := ImportRow{
+			Line:        line,
+			Origin:      location.UNLocode(strings.ToUpper(field("origin"))),
+			Destination: location.UNLocode(strings.ToUpper(field("destination"))),
+		}
+		row.ArrivalDeadline, row.Err = parseDeadline(field("arrival_deadline"))
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// parseJSONLines reads import rows from one JSON object per line. Blank lines
+// are skipped. Parsing stops as soon as there are more than MaxImportRows rows.
+func parseJSONLines(r io.Reader) ([]ImportRow, error) {
+	var rows []ImportRow
+
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		if len(rows) > MaxImportRows {
+			return nil, ErrInvalidArgument
+		}
+
+		text := strings.TrimSpace(s.Text())
+		if text == "" {
+			continue
+		}
+
+		var v struct {
+			Origin          string `json:"origin"`
+			Destination     string `json:"destination"`
+			ArrivalDeadline string `json:"arrival_deadline"`
+		}
+
+		row := ImportRow{Line: line}
+		if err := json.Unmarshal([]byte(text), &v); err != nil {
+			row.Err = err
+		} else {
+			row.Origin = location.UNLocode(strings.ToUpper(v.Origin))
+			row.Destination = location.UNLocode(strings.ToUpper(v.Destination))
+			row.ArrivalDeadline, row.Err = parseDeadline(v.ArrivalDeadline)
+		}
+
+		rows = append(rows, row)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// parseDeadline accepts RFC 3339 timestamps as well as plain dates, which is
+// what spreadsheets tend to export. A plain date means the end of that day in
+// UTC.
+func parseDeadline(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errors.New("missing arrival deadline")
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid arrival deadline %q", s)
+}
@@ -0,0 +1,88 @@
+package booking
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcusolsson/goddd/location"
+)
+
+func TestParseCSV(t *testing.T) {
+	in := `Destination,Origin,Arrival_Deadline
+AUMEL,sesto,2015-11-10T23:00:00Z
+CNHKG,SESTO,2015-11-12
+CNHKG,SESTO,next week
+`
+
+	rows, err := parseCSV(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("len(rows) = %d; want = %d", len(rows), 3)
+	}
+
+	if rows[0].Line != 2 || rows[0].Origin != location.SESTO || rows[0].Destination != location.AUMEL {
+		t.Errorf("rows[0] = %+v", rows[0])
+	}
+	if want := time.Date(2015, time.November, 12, 23, 59, 59, 0, time.UTC); !rows[1].ArrivalDeadline.Equal(want) {
+		t.Errorf("rows[1].ArrivalDeadline = %s; want = %s", rows[1].ArrivalDeadline, want)
+	}
+	if rows[2].Err == nil {
+		t.Errorf("rows[2] should have an error")
+	}
+
+	if _, err := parseCSV(strings.NewReader("origin,destination\n")); err != ErrInvalidArgument {
+		t.Errorf("err = %v; want = %v", err, ErrInvalidArgument)
+	}
+}
+
+func TestParseJSONLines(t *testing.T) {
+	in := `{"origin": "SESTO", "destination": "AUMEL", "arrival_deadline": "2015-11-10T23:00:00Z"}
+
+{"origin": "SESTO",
+`
+
+	rows, err := parseJSONLines(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("len(rows) = %d; want = %d", len(rows), 2)
+	}
+	if rows[0].Err != nil {
+		t.Errorf("rows[0].Err = %v", rows[0].Err)
+	}
+	if rows[1].Line != 3 || rows[1].Err == nil {
+		t.Errorf("rows[1] = %+v", rows[1])
+	}
+}
+
+func TestParseTooManyRows(t *testing.T) {
+	csv := endlessReader{head: "origin,destination,arrival_deadline\n", line: "SESTO,AUMEL,2015-11-10\n"}
+	if _, err := parseCSV(&csv); err != ErrInvalidArgument {
+		t.Errorf("err = %v; want = %v", err, ErrInvalidArgument)
+	}
+
+	jsonl := endlessReader{line: `{"origin": "SESTO", "destination": "AUMEL", "arrival_deadline": "2015-11-10"}` + "\n"}
+	if _, err := parseJSONLines(&jsonl); err != ErrInvalidArgument {
+		t.Errorf("err = %v; want = %v", err, ErrInvalidArgument)
+	}
+}
+
+// endlessReader reads the head followed by the line over and over again.
+type endlessReader struct {
+	head string
+	line string
+	buf  string
+}
+
+func (r *endlessReader) Read(p []byte) (int, error) {
+	if r.buf == "" {
+		r.buf, r.head = r.head+r.line, ""
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
@@ -1,12 +1,19 @@
 package booking
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/go-kit/kit/metrics"
 
+	"github.com/marcusolsson/goddd/access"
+	"github.com/marcusolsson/goddd/allotment"
 	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/document"
 	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/routing"
+	"github.com/marcusolsson/goddd/voyage"
 )
 
 type instrumentingService struct {
@@ -24,40 +31,44 @@ func NewInstrumentingService(counter metrics.Counter, latency metrics.TimeHistog
 	}
 }
 
-func (s *instrumentingService) BookNewCargo(origin, destination location.UNLocode, deadline time.Time) (cargo.TrackingID, error) {
+func (s *instrumentingService) BookNewCargo(origin, destination location.UNLocode, deadline time.Time, mode AutoRoute) (b Booking, err error) {
 	defer func(begin time.Time) {
 		methodField := metrics.Field{Key: "method", Value: "book"}
-		s.requestCount.With(methodField).Add(1)
+		errorField := metrics.Field{Key: "error", Value: fmt.Sprint(err != nil)}
+		s.requestCount.With(methodField).With(errorField).Add(1)
 		s.requestLatency.With(methodField).Observe(time.Since(begin))
 	}(time.Now())
 
-	return s.Service.BookNewCargo(origin, destination, deadline)
+	return s.Service.BookNewCargo(origin, destination, deadline, mode)
 }
 
 func (s *instrumentingService) LoadCargo(id cargo.TrackingID) (c Cargo, err error) {
 	defer func(begin time.Time) {
 		methodField := metrics.Field{Key: "method", Value: "load"}
-		s.requestCount.With(methodField).Add(1)
+		errorField := metrics.Field{Key: "error", Value: fmt.Sprint(err != nil)}
+		s.requestCount.With(methodField).With(errorField).Add(1)
 		s.requestLatency.With(methodField).Observe(time.Since(begin))
 	}(time.Now())
 
 	return s.Service.LoadCargo(id)
 }
 
-func (s *instrumentingService) RequestPossibleRoutesForCargo(id cargo.TrackingID) []cargo.Itinerary {
+func (s *instrumentingService) RequestPossibleRoutesForCargo(id cargo.TrackingID, c routing.Constraints) (candidates []routing.Candidate, err error) {
 	defer func(begin time.Time) {
 		methodField := metrics.Field{Key: "method", Value: "request_routes"}
-		s.requestCount.With(methodField).Add(1)
+		errorField := metrics.Field{Key: "error", Value: fmt.Sprint(err != nil)}
+		s.requestCount.With(methodField).With(errorField).Add(1)
 		s.requestLatency.With(methodField).Observe(time.Since(begin))
 	}(time.Now())
 
-	return s.Service.RequestPossibleRoutesForCargo(id)
+	return s.Service.RequestPossibleRoutesForCargo(id, c)
 }
 
 func (s *instrumentingService) AssignCargoToRoute(id cargo.TrackingID, itinerary cargo.Itinerary) (err error) {
 	defer func(begin time.Time) {
 		methodField := metrics.Field{Key: "method", Value: "assign_to_route"}
-		s.requestCount.With(methodField).Add(1)
+		errorField := metrics.Field{Key: "error", Value: fmt.Sprint(err != nil)}
+		s.requestCount.With(methodField).With(errorField).Add(1)
 		s.requestLatency.With(methodField).Observe(time.Since(begin))
 	}(time.Now())
 
@@ -67,29 +78,230 @@ func (s *instrumentingService) AssignCargoToRoute(id cargo.TrackingID, itinerary
 func (s *instrumentingService) ChangeDestination(id cargo.TrackingID, l location.UNLocode) (err error) {
 	defer func(begin time.Time) {
 		methodField := metrics.Field{Key: "method", Value: "change_destination"}
-		s.requestCount.With(methodField).Add(1)
+		errorField := metrics.Field{Key: "error", Value: fmt.Sprint(err != nil)}
+		s.requestCount.With(methodField).With(errorField).Add(1)
 		s.requestLatency.With(methodField).Observe(time.Since(begin))
 	}(time.Now())
 
 	return s.Service.ChangeDestination(id, l)
 }
 
-func (s *instrumentingService) Cargos() []Cargo {
+func (s *instrumentingService) ChangeArrivalDeadline(id cargo.TrackingID, deadline time.Time) (err error) {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "change_arrival_deadline"}
+		errorField := metrics.Field{Key: "error", Value: fmt.Sprint(err != nil)}
+		s.requestCount.With(methodField).With(errorField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.ChangeArrivalDeadline(id, deadline)
+}
+
+func (s *instrumentingService) ChangeOrigin(id cargo.TrackingID, l location.UNLocode) (err error) {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "change_origin"}
+		errorField := metrics.Field{Key: "error", Value: fmt.Sprint(err != nil)}
+		s.requestCount.With(methodField).With(errorField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.ChangeOrigin(id, l)
+}
+
+func (s *instrumentingService) Cargos(q cargo.Query) (cargos []Cargo, next string, err error) {
 	defer func(begin time.Time) {
 		methodField := metrics.Field{Key: "method", Value: "list_cargos"}
-		s.requestCount.With(methodField).Add(1)
+		errorField := metrics.Field{Key: "error", Value: fmt.Sprint(err != nil)}
+		s.requestCount.With(methodField).With(errorField).Add(1)
 		s.requestLatency.With(methodField).Observe(time.Since(begin))
 	}(time.Now())
 
-	return s.Service.Cargos()
+	return s.Service.Cargos(q)
 }
 
 func (s *instrumentingService) Locations() []Location {
 	defer func(begin time.Time) {
 		methodField := metrics.Field{Key: "method", Value: "list_locations"}
-		s.requestCount.With(methodField).Add(1)
+		errorField := metrics.Field{Key: "error", Value: "false"}
+		s.requestCount.With(methodField).With(errorField).Add(1)
 		s.requestLatency.With(methodField).Observe(time.Since(begin))
 	}(time.Now())
 
 	return s.Service.Locations()
 }
+
+func (s *instrumentingService) RegisterCustomer(name, address, email string) (id customer.ID, err error) {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "register_customer"}
+		errorField := metrics.Field{Key: "error", Value: fmt.Sprint(err != nil)}
+		s.requestCount.With(methodField).With(errorField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.RegisterCustomer(name, address, email)
+}
+
+func (s *instrumentingService) Customers() []Customer {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "list_customers"}
+		errorField := metrics.Field{Key: "error", Value: "false"}
+		s.requestCount.With(methodField).With(errorField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.Customers()
+}
+
+func (s *instrumentingService) AttachParty(id cargo.TrackingID, role cargo.Role, customerID customer.ID) (err error) {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "attach_party"}
+		errorField := metrics.Field{Key: "error", Value: fmt.Sprint(err != nil)}
+		s.requestCount.With(methodField).With(errorField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.AttachParty(id, role, customerID)
+}
+
+func (s *instrumentingService) AddReference(id cargo.TrackingID, typ cargo.ReferenceType, value string) (err error) {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "add_reference"}
+		errorField := metrics.Field{Key: "error", Value: fmt.Sprint(err != nil)}
+		s.requestCount.With(methodField).With(errorField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.AddReference(id, typ, value)
+}
+
+func (s *instrumentingService) ChangeMeasurement(id cargo.TrackingID, m cargo.Measurement) (err error) {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "change_measurement"}
+		errorField := metrics.Field{Key: "error", Value: fmt.Sprint(err != nil)}
+		s.requestCount.With(methodField).With(errorField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.ChangeMeasurement(id, m)
+}
+
+func (s *instrumentingService) CancelBooking(id cargo.TrackingID) (err error) {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "cancel"}
+		errorField := metrics.Field{Key: "error", Value: fmt.Sprint(err != nil)}
+		s.requestCount.With(methodField).With(errorField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.CancelBooking(id)
+}
+
+func (s *instrumentingService) RegisterAllotment(customerID customer.ID, voyageNumber voyage.Number, capacity cargo.Measurement, waitlist bool) (id allotment.ID, err error) {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "register_allotment"}
+		errorField := metrics.Field{Key: "error", Value: fmt.Sprint(err != nil)}
+		s.requestCount.With(methodField).With(errorField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.RegisterAllotment(customerID, voyageNumber, capacity, waitlist)
+}
+
+func (s *instrumentingService) Allotments(customerID customer.ID) []Allotment {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "list_allotments"}
+		errorField := metrics.Field{Key: "error", Value: "false"}
+		s.requestCount.With(methodField).With(errorField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.Allotments(customerID)
+}
+
+func (s *instrumentingService) Documents(id cargo.TrackingID) (docs []Document, err error) {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "list_documents"}
+		errorField := metrics.Field{Key: "error", Value: fmt.Sprint(err != nil)}
+		s.requestCount.With(methodField).With(errorField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.Documents(id)
+}
+
+func (s *instrumentingService) BillOfLading(id cargo.TrackingID, version int) (b document.BillOfLading, err error) {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "load_bill_of_lading"}
+		errorField := metrics.Field{Key: "error", Value: fmt.Sprint(err != nil)}
+		s.requestCount.With(methodField).With(errorField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.BillOfLading(id, version)
+}
+
+func (s *instrumentingService) IssueShareToken(id cargo.TrackingID) (t AccessToken, err error) {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "issue_share_token"}
+		errorField := metrics.Field{Key: "error", Value: fmt.Sprint(err != nil)}
+		s.requestCount.With(methodField).With(errorField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.IssueShareToken(id)
+}
+
+func (s *instrumentingService) ShareTokens(id cargo.TrackingID) (tokens []AccessToken, err error) {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "list_share_tokens"}
+		errorField := metrics.Field{Key: "error", Value: fmt.Sprint(err != nil)}
+		s.requestCount.With(methodField).With(errorField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.ShareTokens(id)
+}
+
+func (s *instrumentingService) SetNotificationChannels(id customer.ID, channels []customer.Channel) (err error) {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "set_notification_channels"}
+		errorField := metrics.Field{Key: "error", Value: fmt.Sprint(err != nil)}
+		s.requestCount.With(methodField).With(errorField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.SetNotificationChannels(id, channels)
+}
+
+func (s *instrumentingService) OpenSession(customerID customer.ID) (t AccessToken, err error) {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "open_session"}
+		errorField := metrics.Field{Key: "error", Value: fmt.Sprint(err != nil)}
+		s.requestCount.With(methodField).With(errorField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.OpenSession(customerID)
+}
+
+func (s *instrumentingService) RevokeToken(token access.Token) (err error) {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "revoke_token"}
+		errorField := metrics.Field{Key: "error", Value: fmt.Sprint(err != nil)}
+		s.requestCount.With(methodField).With(errorField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.RevokeToken(token)
+}
+
+func (s *instrumentingService) ImportCargos(rows []ImportRow, mode AutoRoute, dryRun bool) (r ImportReport, err error) {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "import_cargos"}
+		errorField := metrics.Field{Key: "error", Value: fmt.Sprint(err != nil)}
+		s.requestCount.With(methodField).With(errorField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.ImportCargos(rows, mode, dryRun)
+}
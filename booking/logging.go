@@ -5,8 +5,14 @@ import (
 
 	"github.com/go-kit/kit/log"
 
+	"github.com/marcusolsson/goddd/access"
+	"github.com/marcusolsson/goddd/allotment"
 	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/document"
 	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/routing"
+	"github.com/marcusolsson/goddd/voyage"
 )
 
 type loggingService struct {
@@ -19,18 +25,21 @@ func NewLoggingService(logger log.Logger, s Service) Service {
 	return &loggingService{logger, s}
 }
 
-func (s *loggingService) BookNewCargo(origin location.UNLocode, destination location.UNLocode, deadline time.Time) (id cargo.TrackingID, err error) {
+func (s *loggingService) BookNewCargo(origin location.UNLocode, destination location.UNLocode, deadline time.Time, mode AutoRoute) (b Booking, err error) {
 	defer func(begin time.Time) {
 		s.logger.Log(
 			"method", "book",
 			"origin", origin,
 			"destination", destination,
 			"arrival_deadline", deadline,
+			"auto_route", mode,
+			"routed", b.Itinerary != nil,
+			"not_routed_reason", b.NotRoutedReason,
 			"took", time.Since(begin),
 			"err", err,
 		)
 	}(time.Now())
-	return s.Service.BookNewCargo(origin, destination, deadline)
+	return s.Service.BookNewCargo(origin, destination, deadline, mode)
 }
 
 func (s *loggingService) LoadCargo(id cargo.TrackingID) (c Cargo, err error) {
@@ -45,15 +54,18 @@ func (s *loggingService) LoadCargo(id cargo.TrackingID) (c Cargo, err error) {
 	return s.Service.LoadCargo(id)
 }
 
-func (s *loggingService) RequestPossibleRoutesForCargo(id cargo.TrackingID) []cargo.Itinerary {
+func (s *loggingService) RequestPossibleRoutesForCargo(id cargo.TrackingID, c routing.Constraints) (candidates []routing.Candidate, err error) {
 	defer func(begin time.Time) {
 		s.logger.Log(
 			"method", "request_routes",
 			"tracking_id", id,
+			"constrained", !c.IsZero(),
+			"routes", len(candidates),
 			"took", time.Since(begin),
+			"err", err,
 		)
 	}(time.Now())
-	return s.Service.RequestPossibleRoutesForCargo(id)
+	return s.Service.RequestPossibleRoutesForCargo(id, c)
 }
 
 func (s *loggingService) AssignCargoToRoute(id cargo.TrackingID, itinerary cargo.Itinerary) (err error) {
@@ -81,14 +93,43 @@ func (s *loggingService) ChangeDestination(id cargo.TrackingID, l location.UNLoc
 	return s.Service.ChangeDestination(id, l)
 }
 
-func (s *loggingService) Cargos() []Cargo {
+func (s *loggingService) ChangeArrivalDeadline(id cargo.TrackingID, deadline time.Time) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "change_arrival_deadline",
+			"tracking_id", id,
+			"arrival_deadline", deadline,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.ChangeArrivalDeadline(id, deadline)
+}
+
+func (s *loggingService) ChangeOrigin(id cargo.TrackingID, l location.UNLocode) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "change_origin",
+			"tracking_id", id,
+			"origin", l,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.ChangeOrigin(id, l)
+}
+
+func (s *loggingService) Cargos(q cargo.Query) (cargos []Cargo, next string, err error) {
 	defer func(begin time.Time) {
 		s.logger.Log(
 			"method", "list_cargos",
+			"sort", q.SortBy,
+			"limit", q.Limit,
 			"took", time.Since(begin),
+			"err", err,
 		)
 	}(time.Now())
-	return s.Service.Cargos()
+	return s.Service.Cargos(q)
 }
 
 func (s *loggingService) Locations() []Location {
@@ -100,3 +141,209 @@ func (s *loggingService) Locations() []Location {
 	}(time.Now())
 	return s.Service.Locations()
 }
+
+func (s *loggingService) RegisterCustomer(name, address, email string) (id customer.ID, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "register_customer",
+			"customer_id", id,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.RegisterCustomer(name, address, email)
+}
+
+func (s *loggingService) Customers() []Customer {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "list_customers",
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+	return s.Service.Customers()
+}
+
+func (s *loggingService) AttachParty(id cargo.TrackingID, role cargo.Role, customerID customer.ID) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "attach_party",
+			"tracking_id", id,
+			"role", role,
+			"customer_id", customerID,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.AttachParty(id, role, customerID)
+}
+
+func (s *loggingService) AddReference(id cargo.TrackingID, typ cargo.ReferenceType, value string) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "add_reference",
+			"tracking_id", id,
+			"type", typ,
+			"value", value,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.AddReference(id, typ, value)
+}
+
+func (s *loggingService) ChangeMeasurement(id cargo.TrackingID, m cargo.Measurement) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "change_measurement",
+			"tracking_id", id,
+			"teu", m.TEU,
+			"weight", m.Weight,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.ChangeMeasurement(id, m)
+}
+
+func (s *loggingService) CancelBooking(id cargo.TrackingID) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "cancel",
+			"tracking_id", id,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.CancelBooking(id)
+}
+
+func (s *loggingService) RegisterAllotment(customerID customer.ID, voyageNumber voyage.Number, capacity cargo.Measurement, waitlist bool) (id allotment.ID, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "register_allotment",
+			"customer_id", customerID,
+			"voyage", voyageNumber,
+			"teu", capacity.TEU,
+			"weight", capacity.Weight,
+			"waitlist", waitlist,
+			"allotment_id", id,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.RegisterAllotment(customerID, voyageNumber, capacity, waitlist)
+}
+
+func (s *loggingService) Allotments(customerID customer.ID) []Allotment {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "list_allotments",
+			"customer_id", customerID,
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+	return s.Service.Allotments(customerID)
+}
+
+func (s *loggingService) Documents(id cargo.TrackingID) (docs []Document, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "list_documents",
+			"tracking_id", id,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.Documents(id)
+}
+
+func (s *loggingService) BillOfLading(id cargo.TrackingID, version int) (b document.BillOfLading, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "load_bill_of_lading",
+			"tracking_id", id,
+			"version", version,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.BillOfLading(id, version)
+}
+
+func (s *loggingService) IssueShareToken(id cargo.TrackingID) (t AccessToken, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "issue_share_token",
+			"tracking_id", id,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.IssueShareToken(id)
+}
+
+func (s *loggingService) ShareTokens(id cargo.TrackingID) (tokens []AccessToken, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "list_share_tokens",
+			"tracking_id", id,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.ShareTokens(id)
+}
+
+func (s *loggingService) SetNotificationChannels(id customer.ID, channels []customer.Channel) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "set_notification_channels",
+			"customer_id", id,
+			"channels", len(channels),
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.SetNotificationChannels(id, channels)
+}
+
+func (s *loggingService) OpenSession(customerID customer.ID) (t AccessToken, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "open_session",
+			"customer_id", customerID,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.OpenSession(customerID)
+}
+
+// RevokeToken leaves out the token itself, which is a secret.
+func (s *loggingService) RevokeToken(token access.Token) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "revoke_token",
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.RevokeToken(token)
+}
+
+func (s *loggingService) ImportCargos(rows []ImportRow, mode AutoRoute, dryRun bool) (r ImportReport, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "import_cargos",
+			"rows", len(rows),
+			"auto_route", mode,
+			"dry_run", dryRun,
+			"accepted", len(r.Accepted),
+			"rejected", len(r.Rejected),
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.ImportCargos(rows, mode, dryRun)
+}
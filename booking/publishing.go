@@ -0,0 +1,47 @@
+package booking
+
+import (
+	"time"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/eventbus"
+	"github.com/marcusolsson/goddd/location"
+)
+
+type publishingService struct {
+	publisher eventbus.Publisher
+	Service
+}
+
+// NewPublishingService returns a Service that publishes the cargo whenever
+// its route or route specification has changed.
+func NewPublishingService(p eventbus.Publisher, s Service) Service {
+	return &publishingService{p, s}
+}
+
+func (s *publishingService) AssignCargoToRoute(id cargo.TrackingID, itinerary cargo.Itinerary) error {
+	return s.publish(id, s.Service.AssignCargoToRoute(id, itinerary))
+}
+
+func (s *publishingService) ChangeDestination(id cargo.TrackingID, l location.UNLocode) error {
+	return s.publish(id, s.Service.ChangeDestination(id, l))
+}
+
+func (s *publishingService) ChangeArrivalDeadline(id cargo.TrackingID, deadline time.Time) error {
+	return s.publish(id, s.Service.ChangeArrivalDeadline(id, deadline))
+}
+
+func (s *publishingService) ChangeOrigin(id cargo.TrackingID, l location.UNLocode) error {
+	return s.publish(id, s.Service.ChangeOrigin(id, l))
+}
+
+func (s *publishingService) CancelBooking(id cargo.TrackingID) error {
+	return s.publish(id, s.Service.CancelBooking(id))
+}
+
+func (s *publishingService) publish(id cargo.TrackingID, err error) error {
+	if err == nil {
+		s.publisher.Publish(id)
+	}
+	return err
+}
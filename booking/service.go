@@ -4,48 +4,147 @@ package booking
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 	"time"
 
+	"github.com/marcusolsson/goddd/access"
+	"github.com/marcusolsson/goddd/allotment"
 	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/document"
 	"github.com/marcusolsson/goddd/location"
 	"github.com/marcusolsson/goddd/routing"
+	"github.com/marcusolsson/goddd/voyage"
 )
 
 // ErrInvalidArgument is returned when one or more arguments are invalid.
 var ErrInvalidArgument = errors.New("invalid argument")
 
+// MaxImportRows is the maximum number of rows in a single import.
+const MaxImportRows = 1000
+
+// ErrCargoReceived is returned when amending a booking that may only be
+// changed before the cargo has been received.
+var ErrCargoReceived = errors.New("cargo has already been received")
+
 // Service is the interface that provides booking methods.
 type Service interface {
-	// BookNewCargo registers a new cargo in the tracking system, not yet
-	// routed.
-	BookNewCargo(origin location.UNLocode, destination location.UNLocode, deadline time.Time) (cargo.TrackingID, error)
+	// BookNewCargo registers a new cargo in the tracking system. Depending on
+	// the auto-route mode, the cargo is either left unrouted or assigned to
+	// the route selected by the routing policy. A share token is issued for
+	// the cargo, giving anyone holding it the public tracking view.
+	BookNewCargo(origin location.UNLocode, destination location.UNLocode, deadline time.Time, mode AutoRoute) (Booking, error)
 
 	// LoadCargo returns a read model of a cargo.
 	LoadCargo(id cargo.TrackingID) (Cargo, error)
 
 	// RequestPossibleRoutesForCargo requests a list of itineraries describing
-	// possible routes for this cargo.
-	RequestPossibleRoutesForCargo(id cargo.TrackingID) []cargo.Itinerary
+	// possible routes for this cargo within the constraints, best first.
+	// Failures of the routing service are returned as errors rather than as
+	// an empty list.
+	RequestPossibleRoutesForCargo(id cargo.TrackingID, c routing.Constraints) ([]routing.Candidate, error)
 
 	// AssignCargoToRoute assigns a cargo to the route specified by the
-	// itinerary.
+	// itinerary. If the shipper has allotments on any of the voyages, space
+	// is consumed from them.
 	AssignCargoToRoute(id cargo.TrackingID, itinerary cargo.Itinerary) error
 
 	// ChangeDestination changes the destination of a cargo.
 	ChangeDestination(id cargo.TrackingID, destination location.UNLocode) error
 
-	// Cargos returns a list of all cargos that have been booked.
-	Cargos() []Cargo
+	// ChangeArrivalDeadline changes the arrival deadline of a cargo that has
+	// not yet been received.
+	ChangeArrivalDeadline(id cargo.TrackingID, deadline time.Time) error
+
+	// ChangeOrigin changes the origin of a cargo that has not yet been
+	// received.
+	ChangeOrigin(id cargo.TrackingID, origin location.UNLocode) error
+
+	// Cargos returns a page of booked cargos matching the query, along with
+	// a cursor to the next page.
+	Cargos(q cargo.Query) ([]Cargo, string, error)
 
 	// Locations returns a list of registered locations.
 	Locations() []Location
+
+	// RegisterCustomer registers a new customer that may be attached to
+	// cargos.
+	RegisterCustomer(name, address, email string) (customer.ID, error)
+
+	// Customers returns a list of registered customers.
+	Customers() []Customer
+
+	// SetNotificationChannels sets the channels the customer prefers to be
+	// notified over. No channels means the default channels.
+	SetNotificationChannels(id customer.ID, channels []customer.Channel) error
+
+	// AttachParty assigns a customer to a role, such as shipper or
+	// consignee, for a cargo.
+	AttachParty(id cargo.TrackingID, role cargo.Role, customerID customer.ID) error
+
+	// AddReference attaches an external reference, such as a purchase order
+	// or container number, by which the cargo can be tracked. B/L numbers
+	// are added as bills of lading are issued.
+	AddReference(id cargo.TrackingID, typ cargo.ReferenceType, value string) error
+
+	// ChangeMeasurement changes the declared size of a cargo that has not
+	// yet been received.
+	ChangeMeasurement(id cargo.TrackingID, m cargo.Measurement) error
+
+	// CancelBooking cancels the booking of a cargo that has not yet been
+	// received, releasing any allotments held by it.
+	CancelBooking(id cargo.TrackingID) error
+
+	// RegisterAllotment reserves space on a voyage for a customer.
+	RegisterAllotment(customerID customer.ID, voyageNumber voyage.Number, capacity cargo.Measurement, waitlist bool) (allotment.ID, error)
+
+	// Allotments returns the utilisation of the allotments of a customer, or
+	// of all allotments if no customer is given.
+	Allotments(customerID customer.ID) []Allotment
+
+	// ImportCargos books a cargo for each valid row, using the given
+	// auto-route mode. Invalid rows are reported rather than failing the
+	// whole import. In a dry run, rows are only validated.
+	ImportCargos(rows []ImportRow, mode AutoRoute, dryRun bool) (ImportReport, error)
+
+	// Documents returns the versions of the bill of lading of a cargo. A
+	// new version is issued whenever the cargo is assigned to a route.
+	Documents(id cargo.TrackingID) ([]Document, error)
+
+	// BillOfLading returns a version of the bill of lading of a cargo, or
+	// the current one if version is zero. The current version is issued if
+	// it is missing, as when issuing failed once the cargo was routed.
+	BillOfLading(id cargo.TrackingID, version int) (document.BillOfLading, error)
+
+	// IssueShareToken issues a new share token for a cargo.
+	IssueShareToken(id cargo.TrackingID) (AccessToken, error)
+
+	// ShareTokens returns the share tokens issued for a cargo, including
+	// those that have been revoked. The tokens themselves are left out, as
+	// they are only handed out when issued.
+	ShareTokens(id cargo.TrackingID) ([]AccessToken, error)
+
+	// OpenSession opens a session for a customer, giving the full tracking
+	// view of the cargos the customer is a party of.
+	OpenSession(customerID customer.ID) (AccessToken, error)
+
+	// RevokeToken revokes a share token or session.
+	RevokeToken(token access.Token) error
 }
 
 type service struct {
 	cargos         cargo.Repository
 	locations      location.Repository
-	handlingEvents cargo.HandlingEventRepository
+	voyages        voyage.Repository
+	customers      customer.Repository
+	allotments     allotment.Repository
 	routingService routing.Service
+	ranker         routing.Ranker
+	policy         routing.Policy
+	autoRoute      bool
+	issuer         document.Issuer
+	grants         access.Repository
 }
 
 func (s *service) AssignCargoToRoute(id cargo.TrackingID, itinerary cargo.Itinerary) error {
@@ -58,14 +157,124 @@ func (s *service) AssignCargoToRoute(id cargo.TrackingID, itinerary cargo.Itiner
 		return err
 	}
 
+	itinerary, err = s.withModes(itinerary)
+	if err != nil {
+		return err
+	}
+
+	undo, err := s.reserveAllotments(c, itinerary, c.Measurement)
+	if err != nil {
+		return err
+	}
+
 	c.AssignToRoute(itinerary)
 
-	return s.cargos.Store(c)
+	if err := s.cargos.Store(c); err != nil {
+		undo()
+		return err
+	}
+
+	_, err = s.issue(c)
+	return err
+}
+
+// withModes returns the itinerary with the mode of each leg set from its
+// voyage, rather than trusting the client to state it.
+func (s *service) withModes(itinerary cargo.Itinerary) (cargo.Itinerary, error) {
+	legs := make([]cargo.Leg, len(itinerary.Legs))
+	for i, l := range itinerary.Legs {
+		v, err := s.voyages.Find(l.VoyageNumber)
+		if err == voyage.ErrUnknown {
+			return cargo.Itinerary{}, ErrInvalidArgument
+		}
+		if err != nil {
+			return cargo.Itinerary{}, err
+		}
+		l.Mode = v.Mode
+		legs[i] = l
+	}
+	return cargo.Itinerary{Legs: legs}, nil
+}
+
+// maxReserveAttempts is the number of times allotments are read again when
+// changed concurrently while reserving space in them.
+const maxReserveAttempts = 3
+
+// reserveAllotments moves the space held by a cargo to the shipper's
+// allotments on the voyages of the itinerary. If any of the allotments is
+// exhausted, none of them are changed. The returned function restores the
+// allotments, for when the cargo cannot be stored.
+func (s *service) reserveAllotments(c *cargo.Cargo, itinerary cargo.Itinerary, m cargo.Measurement) (func(), error) {
+	for i := 0; i < maxReserveAttempts; i++ {
+		undo, err := s.tryReserveAllotments(c, itinerary, m)
+		if err != allotment.ErrConflict {
+			return undo, err
+		}
+	}
+	return nil, allotment.ErrConflict
 }
 
-func (s *service) BookNewCargo(origin, destination location.UNLocode, deadline time.Time) (cargo.TrackingID, error) {
+func (s *service) tryReserveAllotments(c *cargo.Cargo, itinerary cargo.Itinerary, m cargo.Measurement) (func(), error) {
+	undo := func() {}
+
+	if c.Parties.Shipper == "" {
+		return undo, nil
+	}
+
+	voyages := make(map[voyage.Number]bool)
+	for _, l := range itinerary.Legs {
+		voyages[l.VoyageNumber] = true
+	}
+
+	var original, changed []*allotment.Allotment
+	for _, a := range s.allotments.FindByCustomer(c.Parties.Shipper) {
+		if !a.Holds(c.TrackingID) && !a.IsWaitlisted(c.TrackingID) && !voyages[a.Voyage] {
+			continue
+		}
+
+		u := a.Copy()
+		u.Release(c.TrackingID)
+
+		if voyages[a.Voyage] {
+			if err := u.Consume(c.TrackingID, m); err != nil {
+				if err == allotment.ErrWaitlisted {
+					// The cargo no longer holds its previous space, but
+					// waits for the new amount of it.
+					if serr := s.allotments.Store(u); serr != nil {
+						return nil, serr
+					}
+				}
+				return nil, err
+			}
+		}
+
+		original = append(original, a)
+		changed = append(changed, u)
+	}
+
+	for i, a := range changed {
+		if err := s.allotments.Store(a); err != nil {
+			s.restoreAllotments(original[:i], changed[:i])
+			return nil, err
+		}
+	}
+
+	return func() { s.restoreAllotments(original, changed) }, nil
+}
+
+// restoreAllotments stores the original contents of allotments that have
+// been changed. Allotments changed again since are left alone.
+func (s *service) restoreAllotments(original, changed []*allotment.Allotment) {
+	for i, a := range original {
+		r := a.Copy()
+		r.Version = changed[i].Version
+		s.allotments.Store(r)
+	}
+}
+
+func (s *service) BookNewCargo(origin, destination location.UNLocode, deadline time.Time, mode AutoRoute) (Booking, error) {
 	if origin == "" || destination == "" || deadline.IsZero() {
-		return "", ErrInvalidArgument
+		return Booking{}, ErrInvalidArgument
 	}
 
 	id := cargo.NextTrackingID()
@@ -77,11 +286,63 @@ func (s *service) BookNewCargo(origin, destination location.UNLocode, deadline t
 
 	c := cargo.New(id, rs)
 
+	b := Booking{TrackingID: c.TrackingID}
+
+	if mode == AutoRouteOn || (mode == AutoRouteDefault && s.autoRoute) {
+		if chosen, ok := s.selectRoute(rs, &b); ok {
+			// Assign before storing the cargo for the first time, so that it
+			// is never observed as booked but unrouted.
+			c.AssignToRoute(chosen.Itinerary)
+			b.Itinerary = &chosen.Itinerary
+		}
+	}
+
 	if err := s.cargos.Store(c); err != nil {
-		return "", err
+		return Booking{}, err
+	}
+
+	if b.Itinerary != nil {
+		if _, err := s.issue(c); err != nil {
+			return Booking{}, err
+		}
+	}
+
+	g := access.NewShareToken(c.TrackingID, time.Now())
+	if err := s.grants.Store(g); err != nil {
+		return Booking{}, err
 	}
+	b.ShareToken = g.Token
 
-	return c.TrackingID, nil
+	return b, nil
+}
+
+// selectRoute fetches route candidates and lets the policy choose among them.
+// If no route qualifies, the reason is recorded on the booking. A failing
+// routing service leaves the cargo unrouted rather than failing the booking.
+func (s *service) selectRoute(rs cargo.RouteSpecification, b *Booking) (routing.Candidate, bool) {
+	itineraries, err := s.routingService.FetchRoutesForSpecification(rs, routing.Constraints{})
+	if err != nil {
+		b.NotRoutedReason = err.Error()
+		return routing.Candidate{}, false
+	}
+	if len(itineraries) == 0 {
+		b.NotRoutedReason = "no routes found"
+		return routing.Candidate{}, false
+	}
+
+	candidates := s.ranker.Rank(rs, itineraries)
+	if len(candidates) == 0 {
+		b.NotRoutedReason = "no route arrives before the deadline"
+		return routing.Candidate{}, false
+	}
+
+	chosen, ok := s.policy.Select(rs, candidates)
+	if !ok {
+		b.NotRoutedReason = "no route satisfies the routing policy"
+		return routing.Candidate{}, false
+	}
+
+	return chosen, true
 }
 
 func (s *service) LoadCargo(id cargo.TrackingID) (Cargo, error) {
@@ -94,7 +355,7 @@ func (s *service) LoadCargo(id cargo.TrackingID) (Cargo, error) {
 		return Cargo{}, err
 	}
 
-	return assemble(c, s.handlingEvents), nil
+	return assemble(c), nil
 }
 
 func (s *service) ChangeDestination(id cargo.TrackingID, destination location.UNLocode) error {
@@ -125,25 +386,97 @@ func (s *service) ChangeDestination(id cargo.TrackingID, destination location.UN
 	return nil
 }
 
-func (s *service) RequestPossibleRoutesForCargo(id cargo.TrackingID) []cargo.Itinerary {
+func (s *service) ChangeArrivalDeadline(id cargo.TrackingID, deadline time.Time) error {
+	if id == "" || deadline.IsZero() {
+		return ErrInvalidArgument
+	}
+
+	c, err := s.cargos.Find(id)
+	if err != nil {
+		return err
+	}
+
+	if c.Delivery.TransportStatus != cargo.NotReceived {
+		return ErrCargoReceived
+	}
+
+	c.SpecifyNewRoute(cargo.RouteSpecification{
+		Origin:          c.RouteSpecification.Origin,
+		Destination:     c.RouteSpecification.Destination,
+		ArrivalDeadline: deadline,
+	})
+
+	return s.cargos.Store(c)
+}
+
+func (s *service) ChangeOrigin(id cargo.TrackingID, origin location.UNLocode) error {
+	if id == "" || origin == "" {
+		return ErrInvalidArgument
+	}
+
+	c, err := s.cargos.Find(id)
+	if err != nil {
+		return err
+	}
+
+	if c.Delivery.TransportStatus != cargo.NotReceived {
+		return ErrCargoReceived
+	}
+
+	l, err := s.locations.Find(origin)
+	if err != nil {
+		return err
+	}
+
+	c.Origin = l.UNLocode
+	c.SpecifyNewRoute(cargo.RouteSpecification{
+		Origin:          l.UNLocode,
+		Destination:     c.RouteSpecification.Destination,
+		ArrivalDeadline: c.RouteSpecification.ArrivalDeadline,
+	})
+
+	return s.cargos.Store(c)
+}
+
+func (s *service) RequestPossibleRoutesForCargo(id cargo.TrackingID, constraints routing.Constraints) ([]routing.Candidate, error) {
 	if id == "" {
-		return nil
+		return nil, ErrInvalidArgument
+	}
+	if max := constraints.MaxTransshipments; max != nil && *max < 0 {
+		return nil, ErrInvalidArgument
 	}
 
 	c, err := s.cargos.Find(id)
 	if err != nil {
-		return []cargo.Itinerary{}
+		return nil, err
+	}
+
+	itineraries, err := s.routingService.FetchRoutesForSpecification(c.RouteSpecification, constraints)
+	if err != nil {
+		return nil, err
 	}
 
-	return s.routingService.FetchRoutesForSpecification(c.RouteSpecification)
+	return s.ranker.Rank(c.RouteSpecification, itineraries), nil
 }
 
-func (s *service) Cargos() []Cargo {
+func (s *service) Cargos(q cargo.Query) ([]Cargo, string, error) {
+	if q.SortBy != "" && !q.SortBy.IsValid() {
+		return nil, "", ErrInvalidArgument
+	}
+
+	page, err := s.cargos.Query(q)
+	if err == cargo.ErrInvalidCursor {
+		return nil, "", ErrInvalidArgument
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
 	var result []Cargo
-	for _, c := range s.cargos.FindAll() {
-		result = append(result, assemble(c, s.handlingEvents))
+	for _, c := range page.Cargos {
+		result = append(result, assemble(c))
 	}
-	return result
+	return result, page.NextCursor, nil
 }
 
 func (s *service) Locations() []Location {
@@ -157,14 +490,537 @@ func (s *service) Locations() []Location {
 	return result
 }
 
-// NewService creates a booking service with necessary dependencies.
-func NewService(cargos cargo.Repository, locations location.Repository, events cargo.HandlingEventRepository, rs routing.Service) Service {
+func (s *service) RegisterCustomer(name, address, email string) (customer.ID, error) {
+	if name == "" {
+		return "", ErrInvalidArgument
+	}
+
+	if email != "" {
+		var err error
+		if email, err = customer.ParseEmail(email); err != nil {
+			return "", err
+		}
+	}
+
+	c := customer.New(customer.NextID(), name, address, email)
+
+	if err := s.customers.Store(c); err != nil {
+		return "", err
+	}
+
+	return c.ID, nil
+}
+
+func (s *service) Customers() []Customer {
+	var result []Customer
+	for _, c := range s.customers.FindAll() {
+		result = append(result, Customer{
+			ID:       string(c.ID),
+			Name:     c.Name,
+			Address:  c.Address,
+			Email:    c.Email,
+			Channels: channelNames(c.Channels),
+		})
+	}
+	return result
+}
+
+func (s *service) SetNotificationChannels(id customer.ID, channels []customer.Channel) error {
+	if id == "" {
+		return ErrInvalidArgument
+	}
+
+	c, err := s.customers.Find(id)
+	if err != nil {
+		return err
+	}
+
+	for _, ch := range channels {
+		if ch != customer.Email {
+			continue
+		}
+		if _, err := customer.ParseEmail(c.Email); err != nil {
+			return err
+		}
+	}
+
+	c.Channels = channels
+
+	return s.customers.Store(c)
+}
+
+func (s *service) AttachParty(id cargo.TrackingID, role cargo.Role, customerID customer.ID) error {
+	if id == "" || customerID == "" {
+		return ErrInvalidArgument
+	}
+
+	c, err := s.cargos.Find(id)
+	if err != nil {
+		return err
+	}
+
+	cust, err := s.customers.Find(customerID)
+	if err != nil {
+		return err
+	}
+
+	if role == cargo.Shipper && cust.ID != c.Parties.Shipper && !c.Itinerary.IsEmpty() {
+		return s.changeShipper(c, cust.ID)
+	}
+
+	c.AttachParty(role, cust.ID)
+
+	return s.cargos.Store(c)
+}
+
+// changeShipper hands a routed cargo over to a new shipper, moving the space
+// it holds from the allotments of the previous shipper to those of the new
+// one. If the new shipper's allotments are exhausted, the cargo stays with
+// the previous shipper.
+func (s *service) changeShipper(c *cargo.Cargo, shipper customer.ID) error {
+	previous := c.Parties.Shipper
+
+	release, err := s.reserveAllotments(c, cargo.Itinerary{}, c.Measurement)
+	if err != nil {
+		return err
+	}
+
+	c.AttachParty(cargo.Shipper, shipper)
+
+	reserve, err := s.reserveAllotments(c, c.Itinerary, c.Measurement)
+	if err != nil {
+		c.AttachParty(cargo.Shipper, previous)
+		release()
+		return err
+	}
+
+	if err := s.cargos.Store(c); err != nil {
+		c.AttachParty(cargo.Shipper, previous)
+		reserve()
+		release()
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) AddReference(id cargo.TrackingID, typ cargo.ReferenceType, value string) error {
+	if id == "" || typ == cargo.BillOfLadingNumber {
+		return ErrInvalidArgument
+	}
+
+	ref, err := cargo.NewReference(typ, value)
+	if err != nil {
+		return err
+	}
+
+	c, err := s.cargos.Find(id)
+	if err != nil {
+		return err
+	}
+
+	if !c.AddReference(ref) {
+		return nil
+	}
+
+	return s.cargos.Store(c)
+}
+
+func (s *service) ChangeMeasurement(id cargo.TrackingID, m cargo.Measurement) error {
+	if id == "" || m.TEU <= 0 || m.Weight < 0 {
+		return ErrInvalidArgument
+	}
+
+	c, err := s.cargos.Find(id)
+	if err != nil {
+		return err
+	}
+
+	if c.Delivery.TransportStatus != cargo.NotReceived {
+		return ErrCargoReceived
+	}
+
+	undo, err := s.reserveAllotments(c, c.Itinerary, m)
+	if err != nil {
+		return err
+	}
+
+	c.Measurement = m
+
+	if err := s.cargos.Store(c); err != nil {
+		undo()
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) CancelBooking(id cargo.TrackingID) error {
+	if id == "" {
+		return ErrInvalidArgument
+	}
+
+	c, err := s.cargos.Find(id)
+	if err != nil {
+		return err
+	}
+
+	if c.Delivery.TransportStatus != cargo.NotReceived {
+		return ErrCargoReceived
+	}
+
+	undo, err := s.reserveAllotments(c, cargo.Itinerary{}, c.Measurement)
+	if err != nil {
+		return err
+	}
+
+	if err := s.cargos.Remove(id); err != nil {
+		undo()
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) RegisterAllotment(customerID customer.ID, voyageNumber voyage.Number, capacity cargo.Measurement, waitlist bool) (allotment.ID, error) {
+	if customerID == "" || voyageNumber == "" || capacity.TEU <= 0 || capacity.Weight < 0 {
+		return "", ErrInvalidArgument
+	}
+
+	if _, err := s.customers.Find(customerID); err != nil {
+		return "", err
+	}
+
+	a := allotment.New(allotment.NextID(), customerID, voyageNumber, capacity, waitlist)
+
+	if err := s.allotments.Store(a); err != nil {
+		return "", err
+	}
+
+	return a.ID, nil
+}
+
+func (s *service) Allotments(customerID customer.ID) []Allotment {
+	var allotments []*allotment.Allotment
+	if customerID == "" {
+		allotments = s.allotments.FindAll()
+	} else {
+		allotments = s.allotments.FindByCustomer(customerID)
+	}
+
+	var result []Allotment
+	for _, a := range allotments {
+		result = append(result, assembleAllotment(a))
+	}
+	return result
+}
+
+func (s *service) ImportCargos(rows []ImportRow, mode AutoRoute, dryRun bool) (ImportReport, error) {
+	if len(rows) == 0 || len(rows) > MaxImportRows {
+		return ImportReport{}, ErrInvalidArgument
+	}
+
+	report := ImportReport{
+		DryRun:   dryRun,
+		Accepted: make([]ImportedCargo, 0, len(rows)),
+		Rejected: make([]RejectedRow, 0),
+	}
+
+	for _, row := range rows {
+		if err := s.validateImportRow(row); err != nil {
+			report.Rejected = append(report.Rejected, RejectedRow{Line: row.Line, Error: err.Error()})
+			continue
+		}
+
+		if dryRun {
+			report.Accepted = append(report.Accepted, ImportedCargo{Line: row.Line})
+			continue
+		}
+
+		b, err := s.BookNewCargo(row.Origin, row.Destination, row.ArrivalDeadline, mode)
+		if err != nil {
+			report.Rejected = append(report.Rejected, RejectedRow{Line: row.Line, Error: err.Error()})
+			continue
+		}
+
+		report.Accepted = append(report.Accepted, ImportedCargo{
+			Line:            row.Line,
+			TrackingID:      string(b.TrackingID),
+			Routed:          b.Itinerary != nil,
+			NotRoutedReason: b.NotRoutedReason,
+			ShareToken:      string(b.ShareToken),
+		})
+	}
+
+	return report, nil
+}
+
+func (s *service) validateImportRow(row ImportRow) error {
+	if row.Err != nil {
+		return row.Err
+	}
+	if row.Origin == "" || row.Destination == "" || row.ArrivalDeadline.IsZero() {
+		return errors.New("origin, destination and arrival deadline are required")
+	}
+	if row.Origin == row.Destination {
+		return errors.New("origin and destination must differ")
+	}
+	for _, code := range []location.UNLocode{row.Origin, row.Destination} {
+		if _, err := s.locations.Find(code); err != nil {
+			return fmt.Errorf("%s: %v", code, err)
+		}
+	}
+	return nil
+}
+
+// issue issues the bill of lading for the route of a stored cargo and records
+// its number as a reference, so that the cargo can be tracked by it. Issuing
+// only once the route has been stored means that no version is left behind
+// for a route the cargo never took. Should issuing fail, the version is
+// issued the next time the current bill of lading is asked for.
+func (s *service) issue(c *cargo.Cargo) (*document.BillOfLading, error) {
+	b, err := s.issuer.Issue(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// The number stays the same between versions, so the cargo only needs
+	// to be stored again for the first one.
+	if c.AddReference(cargo.Reference{Type: cargo.BillOfLadingNumber, Value: string(b.Number)}) {
+		if err := s.cargos.Store(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+func (s *service) Documents(id cargo.TrackingID) ([]Document, error) {
+	if id == "" {
+		return nil, ErrInvalidArgument
+	}
+
+	if _, err := s.cargos.Find(id); err != nil {
+		return nil, err
+	}
+
+	docs := s.issuer.DocumentRepository.FindByCargo(id)
+
+	result := make([]Document, 0, len(docs))
+	for i, d := range docs {
+		result = append(result, Document{
+			Type:    "bill_of_lading",
+			Number:  string(d.Number),
+			Version: d.Version,
+			Issued:  d.Issued,
+			Current: i == len(docs)-1,
+		})
+	}
+	return result, nil
+}
+
+func (s *service) BillOfLading(id cargo.TrackingID, version int) (document.BillOfLading, error) {
+	if id == "" || version < 0 {
+		return document.BillOfLading{}, ErrInvalidArgument
+	}
+
+	c, err := s.cargos.Find(id)
+	if err != nil {
+		return document.BillOfLading{}, err
+	}
+
+	if version == 0 {
+		if c.Itinerary.IsEmpty() {
+			return document.BillOfLading{}, document.ErrNotRouted
+		}
+		b, err := s.issue(c)
+		if err != nil {
+			return document.BillOfLading{}, err
+		}
+		return *b, nil
+	}
+
+	b, err := s.issuer.DocumentRepository.Find(id, version)
+	if err != nil {
+		return document.BillOfLading{}, err
+	}
+
+	return *b, nil
+}
+
+func (s *service) IssueShareToken(id cargo.TrackingID) (AccessToken, error) {
+	if id == "" {
+		return AccessToken{}, ErrInvalidArgument
+	}
+
+	if _, err := s.cargos.Find(id); err != nil {
+		return AccessToken{}, err
+	}
+
+	g := access.NewShareToken(id, time.Now())
+	if err := s.grants.Store(g); err != nil {
+		return AccessToken{}, err
+	}
+
+	return assembleAccessToken(g, time.Now()), nil
+}
+
+func (s *service) ShareTokens(id cargo.TrackingID) ([]AccessToken, error) {
+	if id == "" {
+		return nil, ErrInvalidArgument
+	}
+
+	if _, err := s.cargos.Find(id); err != nil {
+		return nil, err
+	}
+
+	var (
+		now    = time.Now()
+		grants = s.grants.FindByCargo(id)
+		tokens = make([]AccessToken, 0, len(grants))
+	)
+	for _, g := range grants {
+		t := assembleAccessToken(g, now)
+		t.Token = ""
+		tokens = append(tokens, t)
+	}
+	sort.Stable(byIssued(tokens))
+
+	return tokens, nil
+}
+
+func (s *service) OpenSession(customerID customer.ID) (AccessToken, error) {
+	if customerID == "" {
+		return AccessToken{}, ErrInvalidArgument
+	}
+
+	if _, err := s.customers.Find(customerID); err != nil {
+		return AccessToken{}, err
+	}
+
+	g := access.NewSession(customerID, time.Now())
+	if err := s.grants.Store(g); err != nil {
+		return AccessToken{}, err
+	}
+
+	return assembleAccessToken(g, time.Now()), nil
+}
+
+func (s *service) RevokeToken(token access.Token) error {
+	if token == "" {
+		return ErrInvalidArgument
+	}
+
+	g, err := s.grants.Find(token)
+	if err != nil {
+		return err
+	}
+
+	g.Revoke(time.Now())
+
+	return s.grants.Store(g)
+}
+
+func assembleAccessToken(g *access.Grant, now time.Time) AccessToken {
+	t := AccessToken{
+		Token:      string(g.Token),
+		TrackingID: string(g.TrackingID),
+		CustomerID: string(g.Customer),
+		Issued:     g.Issued,
+		Active:     g.Active(now),
+	}
+	if !g.Expires.IsZero() {
+		expires := g.Expires
+		t.Expires = &expires
+	}
+	if !g.Revoked.IsZero() {
+		revoked := g.Revoked
+		t.Revoked = &revoked
+	}
+	return t
+}
+
+type byIssued []AccessToken
+
+func (s byIssued) Len() int           { return len(s) }
+func (s byIssued) Less(i, j int) bool { return s[i].Issued.Before(s[j].Issued) }
+func (s byIssued) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// NewService creates a booking service with necessary dependencies. Options
+// not given are left out or, for the ranker and policy, take their defaults.
+func NewService(cargos cargo.Repository, locations location.Repository, voyages voyage.Repository, issuer document.Issuer, grants access.Repository, opts Options) Service {
+	if opts.Ranker == nil {
+		opts.Ranker = routing.NewRanker(routing.DefaultWeights)
+	}
+	if opts.Policy == nil {
+		opts.Policy = routing.HighestRanked
+	}
 	return &service{
 		cargos:         cargos,
 		locations:      locations,
-		handlingEvents: events,
-		routingService: rs,
+		voyages:        voyages,
+		customers:      opts.Customers,
+		allotments:     opts.Allotments,
+		routingService: opts.Routing,
+		ranker:         opts.Ranker,
+		policy:         opts.Policy,
+		autoRoute:      opts.AutoRoute,
+		issuer:         issuer,
+		grants:         grants,
+	}
+}
+
+// Options holds the collaborators of a booking service that are only needed
+// by some of its methods.
+type Options struct {
+	// Customers is needed to register customers and attach them to cargos.
+	Customers customer.Repository
+
+	// Allotments is needed to register allotments and consume space from
+	// them.
+	Allotments allotment.Repository
+
+	// Routing is needed to request routes and to route cargos when booked.
+	Routing routing.Service
+
+	// Ranker orders the routes returned by the routing service.
+	Ranker routing.Ranker
+
+	// Policy selects the route of a cargo routed when booked.
+	Policy routing.Policy
+
+	// AutoRoute routes cargos booked with the default auto-route mode.
+	AutoRoute bool
+}
+
+// AutoRoute decides whether a cargo is routed when it is booked.
+type AutoRoute int
+
+// Valid auto-route modes.
+const (
+	AutoRouteDefault AutoRoute = iota
+	AutoRouteOn
+	AutoRouteOff
+)
+
+func (m AutoRoute) String() string {
+	switch m {
+	case AutoRouteDefault:
+		return "default"
+	case AutoRouteOn:
+		return "on"
+	case AutoRouteOff:
+		return "off"
 	}
+	return ""
+}
+
+// Booking is a read model describing the outcome of booking a cargo.
+type Booking struct {
+	TrackingID      cargo.TrackingID `json:"tracking_id"`
+	Itinerary       *cargo.Itinerary `json:"itinerary,omitempty"`
+	NotRoutedReason string           `json:"not_routed_reason,omitempty"`
+	ShareToken      access.Token     `json:"share_token"`
 }
 
 // Location is a read model for booking views.
@@ -173,25 +1029,196 @@ type Location struct {
 	Name     string `json:"name"`
 }
 
+// Customer is a read model for booking views.
+type Customer struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Address  string   `json:"address,omitempty"`
+	Email    string   `json:"email,omitempty"`
+	Channels []string `json:"channels,omitempty"`
+}
+
+func channelNames(channels []customer.Channel) []string {
+	var names []string
+	for _, c := range channels {
+		names = append(names, string(c))
+	}
+	return names
+}
+
+// ImportRow is a cargo to be booked as part of an import. Err is set if the
+// row could not be parsed.
+type ImportRow struct {
+	Line            int
+	Origin          location.UNLocode
+	Destination     location.UNLocode
+	ArrivalDeadline time.Time
+	Err             error
+}
+
+// ImportReport is a read model describing the outcome of an import.
+type ImportReport struct {
+	DryRun   bool            `json:"dry_run"`
+	Accepted []ImportedCargo `json:"accepted"`
+	Rejected []RejectedRow   `json:"rejected"`
+}
+
+// ImportedCargo is a row that was booked, or would have been booked in a dry
+// run.
+type ImportedCargo struct {
+	Line            int    `json:"line"`
+	TrackingID      string `json:"tracking_id,omitempty"`
+	Routed          bool   `json:"routed"`
+	NotRoutedReason string `json:"not_routed_reason,omitempty"`
+	ShareToken      string `json:"share_token,omitempty"`
+}
+
+// RejectedRow is a row that could not be booked.
+type RejectedRow struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// Document is a read model describing a version of a document issued for a
+// cargo.
+type Document struct {
+	Type    string    `json:"type"`
+	Number  string    `json:"number"`
+	Version int       `json:"version"`
+	Issued  time.Time `json:"issued"`
+	Current bool      `json:"current"`
+}
+
+// AccessToken is a read model describing a share token or customer session.
+type AccessToken struct {
+	Token      string     `json:"token,omitempty"`
+	TrackingID string     `json:"tracking_id,omitempty"`
+	CustomerID string     `json:"customer_id,omitempty"`
+	Issued     time.Time  `json:"issued"`
+	Expires    *time.Time `json:"expires,omitempty"`
+	Revoked    *time.Time `json:"revoked,omitempty"`
+	Active     bool       `json:"active"`
+}
+
+// Allotment is a read model describing the utilisation of an allotment.
+type Allotment struct {
+	ID             string   `json:"id"`
+	Customer       string   `json:"customer_id"`
+	Voyage         string   `json:"voyage_number"`
+	CapacityTEU    int      `json:"capacity_teu"`
+	CapacityWeight float64  `json:"capacity_weight,omitempty"`
+	UsedTEU        int      `json:"used_teu"`
+	UsedWeight     float64  `json:"used_weight"`
+	Utilisation    float64  `json:"utilisation"`
+	Cargos         []string `json:"cargos,omitempty"`
+	Waitlisted     []string `json:"waitlisted,omitempty"`
+}
+
+func assembleAllotment(a *allotment.Allotment) Allotment {
+	used := a.Used()
+
+	var cargos []string
+	for _, c := range a.Consumptions {
+		cargos = append(cargos, string(c.TrackingID))
+	}
+
+	var waitlisted []string
+	for _, id := range a.Waitlisted {
+		waitlisted = append(waitlisted, string(id))
+	}
+
+	return Allotment{
+		ID:             string(a.ID),
+		Customer:       string(a.Customer),
+		Voyage:         string(a.Voyage),
+		CapacityTEU:    a.Capacity.TEU,
+		CapacityWeight: a.Capacity.Weight,
+		UsedTEU:        used.TEU,
+		UsedWeight:     used.Weight,
+		Utilisation:    float64(used.TEU) / float64(a.Capacity.TEU),
+		Cargos:         cargos,
+		Waitlisted:     waitlisted,
+	}
+}
+
 // Cargo is a read model for booking views.
 type Cargo struct {
-	ArrivalDeadline time.Time   `json:"arrival_deadline"`
-	Destination     string      `json:"destination"`
-	Legs            []cargo.Leg `json:"legs,omitempty"`
-	Misrouted       bool        `json:"misrouted"`
-	Origin          string      `json:"origin"`
-	Routed          bool        `json:"routed"`
-	TrackingID      string      `json:"tracking_id"`
+	ArrivalDeadline      time.Time   `json:"arrival_deadline"`
+	Destination          string      `json:"destination"`
+	Legs                 []cargo.Leg `json:"legs,omitempty"`
+	Misrouted            bool        `json:"misrouted"`
+	Origin               string      `json:"origin"`
+	Routed               bool        `json:"routed"`
+	TrackingID           string      `json:"tracking_id"`
+	Shipper              string      `json:"shipper,omitempty"`
+	Consignee            string      `json:"consignee,omitempty"`
+	NotifyParties        []string    `json:"notify_parties,omitempty"`
+	References           []Reference `json:"references,omitempty"`
+	TransportStatus      string      `json:"transport_status"`
+	LastKnownLocation    string      `json:"last_known_location,omitempty"`
+	CurrentVoyage        string      `json:"current_voyage,omitempty"`
+	NextExpectedActivity *Activity   `json:"next_expected_activity,omitempty"`
+	ETA                  time.Time   `json:"eta"`
+	Misdirected          bool        `json:"misdirected"`
+	LastEventTime        time.Time   `json:"last_event_time"`
+}
+
+// Reference is a read model for booking views.
+type Reference struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
 }
 
-func assemble(c *cargo.Cargo, events cargo.HandlingEventRepository) Cargo {
+// Activity is a read model for booking views.
+type Activity struct {
+	Type         string `json:"type"`
+	Location     string `json:"location"`
+	VoyageNumber string `json:"voyage_number,omitempty"`
+}
+
+// assemble creates a read model from the cargo alone. The delivery snapshot
+// stored with the cargo is kept up to date by the inspection service, so
+// listing cargos never needs to query the handling history.
+func assemble(c *cargo.Cargo) Cargo {
+	var notify []string
+	for _, id := range c.Parties.NotifyParties {
+		notify = append(notify, string(id))
+	}
+
+	var refs []Reference
+	for _, r := range c.References {
+		refs = append(refs, Reference{Type: r.Type.String(), Value: r.Value})
+	}
+
+	d := c.Delivery
+
+	var next *Activity
+	if a := d.NextExpectedActivity; a.Type != cargo.NotHandled {
+		next = &Activity{
+			Type:         a.Type.String(),
+			Location:     string(a.Location),
+			VoyageNumber: string(a.VoyageNumber),
+		}
+	}
+
 	return Cargo{
-		TrackingID:      string(c.TrackingID),
-		Origin:          string(c.Origin),
-		Destination:     string(c.RouteSpecification.Destination),
-		Misrouted:       c.Delivery.RoutingStatus == cargo.Misrouted,
-		Routed:          !c.Itinerary.IsEmpty(),
-		ArrivalDeadline: c.RouteSpecification.ArrivalDeadline,
-		Legs:            c.Itinerary.Legs,
+		TrackingID:           string(c.TrackingID),
+		Origin:               string(c.Origin),
+		Destination:          string(c.RouteSpecification.Destination),
+		Misrouted:            d.RoutingStatus == cargo.Misrouted,
+		Routed:               !c.Itinerary.IsEmpty(),
+		ArrivalDeadline:      c.RouteSpecification.ArrivalDeadline,
+		Legs:                 c.Itinerary.Legs,
+		Shipper:              string(c.Parties.Shipper),
+		Consignee:            string(c.Parties.Consignee),
+		NotifyParties:        notify,
+		References:           refs,
+		TransportStatus:      d.TransportStatus.String(),
+		LastKnownLocation:    string(d.LastKnownLocation),
+		CurrentVoyage:        string(d.CurrentVoyage),
+		NextExpectedActivity: next,
+		ETA:                  d.ETA,
+		Misdirected:          d.IsMisdirected,
+		LastEventTime:        d.LastEvent.Completed,
 	}
 }
@@ -1,12 +1,22 @@
 package booking
 
 import (
+	"errors"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/marcusolsson/goddd/access"
+	"github.com/marcusolsson/goddd/allotment"
 	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/document"
+	"github.com/marcusolsson/goddd/inmem"
 	"github.com/marcusolsson/goddd/location"
 	"github.com/marcusolsson/goddd/mock"
+	"github.com/marcusolsson/goddd/routing"
+	"github.com/marcusolsson/goddd/voyage"
 )
 
 func TestBookNewCargo(t *testing.T) {
@@ -18,12 +28,24 @@ func TestBookNewCargo(t *testing.T) {
 
 	var cargos mockCargoRepository
 
-	s := NewService(&cargos, nil, nil, nil)
+	grants := inmem.NewGrantRepository()
 
-	id, err := s.BookNewCargo(origin, destination, deadline)
+	s := NewService(&cargos, nil, inmem.NewVoyageRepository(), newIssuer(), grants, Options{})
+
+	b, err := s.BookNewCargo(origin, destination, deadline, AutoRouteDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := b.TrackingID
+
+	g, err := grants.Find(b.ShareToken)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if g.TrackingID != id {
+		t.Errorf("g.TrackingID = %s; want = %s", g.TrackingID, id)
+	}
 
 	c, err := cargos.Find(id)
 	if err != nil {
@@ -48,14 +70,14 @@ func TestBookNewCargo(t *testing.T) {
 
 type stubRoutingService struct{}
 
-func (s *stubRoutingService) FetchRoutesForSpecification(rs cargo.RouteSpecification) []cargo.Itinerary {
+func (s *stubRoutingService) FetchRoutesForSpecification(rs cargo.RouteSpecification, c routing.Constraints) ([]cargo.Itinerary, error) {
 	legs := []cargo.Leg{
-		{LoadLocation: rs.Origin, UnloadLocation: rs.Destination},
+		{VoyageNumber: "V100", LoadLocation: rs.Origin, UnloadLocation: rs.Destination},
 	}
 
 	return []cargo.Itinerary{
 		{Legs: legs},
-	}
+	}, nil
 }
 
 func TestRequestPossibleRoutesForCargo(t *testing.T) {
@@ -69,20 +91,23 @@ func TestRequestPossibleRoutesForCargo(t *testing.T) {
 
 	var rs stubRoutingService
 
-	s := NewService(&cargos, nil, nil, &rs)
+	s := newTestService(&cargos, nil, Options{Routing: &rs})
 
-	r := s.RequestPossibleRoutesForCargo("no_such_id")
-
-	if len(r) != 0 {
-		t.Errorf("len(r) = %d; want = %d", len(r), 0)
+	if _, err := s.RequestPossibleRoutesForCargo("no_such_id", routing.Constraints{}); err != cargo.ErrUnknown {
+		t.Errorf("err = %v; want = %v", err, cargo.ErrUnknown)
 	}
 
-	id, err := s.BookNewCargo(origin, destination, deadline)
+	b, err := s.BookNewCargo(origin, destination, deadline, AutoRouteDefault)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	i := s.RequestPossibleRoutesForCargo(id)
+	id := b.TrackingID
+
+	i, err := s.RequestPossibleRoutesForCargo(id, routing.Constraints{})
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if len(i) != 1 {
 		t.Errorf("len(i) = %d; want = %d", len(i), 1)
@@ -94,7 +119,7 @@ func TestAssignCargoToRoute(t *testing.T) {
 
 	var rs stubRoutingService
 
-	s := NewService(&cargos, nil, nil, &rs)
+	s := newTestService(&cargos, nil, Options{Routing: &rs})
 
 	var (
 		origin      = location.SESTO
@@ -102,18 +127,23 @@ func TestAssignCargoToRoute(t *testing.T) {
 		deadline    = time.Date(2015, time.November, 10, 23, 0, 0, 0, time.UTC)
 	)
 
-	id, err := s.BookNewCargo(origin, destination, deadline)
+	b, err := s.BookNewCargo(origin, destination, deadline, AutoRouteDefault)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	i := s.RequestPossibleRoutesForCargo(id)
+	id := b.TrackingID
+
+	i, err := s.RequestPossibleRoutesForCargo(id, routing.Constraints{})
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if len(i) != 1 {
 		t.Errorf("len(i) = %d; want = %d", len(i), 1)
 	}
 
-	if err := s.AssignCargoToRoute(id, i[0]); err != nil {
+	if err := s.AssignCargoToRoute(id, i[0].Itinerary); err != nil {
 		t.Fatal(err)
 	}
 
@@ -135,7 +165,7 @@ func TestChangeCargoDestination(t *testing.T) {
 
 	var rs stubRoutingService
 
-	s := NewService(&cargos, &locations, nil, &rs)
+	s := newTestService(&cargos, &locations, Options{Routing: &rs})
 
 	c := cargo.New("ABC", cargo.RouteSpecification{
 		Origin:          location.SESTO,
@@ -191,7 +221,7 @@ func TestLoadCargo(t *testing.T) {
 		}, nil
 	}
 
-	s := NewService(&cargos, nil, nil, nil)
+	s := newTestService(&cargos, nil, Options{})
 
 	c, err := s.LoadCargo("test_id")
 	if err != nil {
@@ -221,6 +251,91 @@ func TestLoadCargo(t *testing.T) {
 	}
 }
 
+func TestLoadCargoDeliveryStatus(t *testing.T) {
+	var (
+		eta       = time.Date(2015, time.November, 8, 12, 0, 0, 0, time.UTC)
+		completed = time.Date(2015, time.November, 2, 9, 30, 0, 0, time.UTC)
+	)
+
+	var cargos mock.CargoRepository
+	cargos.FindFn = func(id cargo.TrackingID) (*cargo.Cargo, error) {
+		return &cargo.Cargo{
+			TrackingID: "test_id",
+			Origin:     location.SESTO,
+			RouteSpecification: cargo.RouteSpecification{
+				Origin:      location.SESTO,
+				Destination: location.AUMEL,
+			},
+			Delivery: cargo.Delivery{
+				TransportStatus:   cargo.OnboardCarrier,
+				LastKnownLocation: location.SESTO,
+				CurrentVoyage:     "V100",
+				ETA:               eta,
+				LastEvent: cargo.HandlingEvent{
+					TrackingID: "test_id",
+					Activity: cargo.HandlingActivity{
+						Type:         cargo.Load,
+						Location:     location.SESTO,
+						VoyageNumber: "V100",
+					},
+					Completed: completed,
+				},
+				NextExpectedActivity: cargo.HandlingActivity{
+					Type:         cargo.Unload,
+					Location:     location.AUMEL,
+					VoyageNumber: "V100",
+				},
+			},
+		}, nil
+	}
+
+	s := newTestService(&cargos, nil, Options{})
+
+	c, err := s.LoadCargo("test_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.TransportStatus != "Onboard carrier" {
+		t.Errorf("c.TransportStatus = %s; want = %s", c.TransportStatus, "Onboard carrier")
+	}
+	if c.LastKnownLocation != "SESTO" {
+		t.Errorf("c.LastKnownLocation = %s; want = %s", c.LastKnownLocation, "SESTO")
+	}
+	if c.CurrentVoyage != "V100" {
+		t.Errorf("c.CurrentVoyage = %s; want = %s", c.CurrentVoyage, "V100")
+	}
+	if c.ETA != eta {
+		t.Errorf("c.ETA = %s; want = %s", c.ETA, eta)
+	}
+	if c.LastEventTime != completed {
+		t.Errorf("c.LastEventTime = %s; want = %s", c.LastEventTime, completed)
+	}
+	if c.Misdirected {
+		t.Errorf("cargo should not be misdirected")
+	}
+
+	want := Activity{Type: "Unload", Location: "AUMEL", VoyageNumber: "V100"}
+	if c.NextExpectedActivity == nil || *c.NextExpectedActivity != want {
+		t.Errorf("c.NextExpectedActivity = %v; want = %v", c.NextExpectedActivity, want)
+	}
+}
+
+// newTestService creates a service with in-memory voyages, documents and
+// grants.
+func newTestService(cargos cargo.Repository, locations location.Repository, opts Options) Service {
+	return NewService(cargos, locations, inmem.NewVoyageRepository(), newIssuer(), inmem.NewGrantRepository(), opts)
+}
+
+func newIssuer() document.Issuer {
+	return document.Issuer{
+		DocumentRepository: inmem.NewDocumentRepository(),
+		LocationRepository: inmem.NewLocationRepository(),
+		VoyageRepository:   inmem.NewVoyageRepository(),
+		CustomerRepository: inmem.NewCustomerRepository(),
+	}
+}
+
 type mockCargoRepository struct {
 	cargo *cargo.Cargo
 }
@@ -237,6 +352,912 @@ func (r *mockCargoRepository) Find(id cargo.TrackingID) (*cargo.Cargo, error) {
 	return nil, cargo.ErrUnknown
 }
 
+func (r *mockCargoRepository) FindMany(ids []cargo.TrackingID) ([]*cargo.Cargo, error) {
+	var cargos []*cargo.Cargo
+	for _, id := range ids {
+		if r.cargo != nil && r.cargo.TrackingID == id {
+			cargos = append(cargos, r.cargo)
+		}
+	}
+	return cargos, nil
+}
+
+func (r *mockCargoRepository) FindByReference(value string) ([]*cargo.Cargo, error) {
+	var cargos []*cargo.Cargo
+	if r.cargo != nil {
+		for _, ref := range r.cargo.References {
+			if ref.Value == cargo.NormalizeReference(value) {
+				cargos = append(cargos, r.cargo)
+				break
+			}
+		}
+	}
+	return cargos, nil
+}
+
 func (r *mockCargoRepository) FindAll() []*cargo.Cargo {
 	return []*cargo.Cargo{r.cargo}
 }
+
+func (r *mockCargoRepository) Query(q cargo.Query) (cargo.Page, error) {
+	return cargo.Page{Cargos: r.FindAll()}, nil
+}
+
+func (r *mockCargoRepository) Remove(id cargo.TrackingID) error {
+	if r.cargo == nil {
+		return cargo.ErrUnknown
+	}
+	r.cargo = nil
+	return nil
+}
+
+func TestCargos(t *testing.T) {
+	var cargos mock.CargoRepository
+	cargos.QueryFn = func(q cargo.Query) (cargo.Page, error) {
+		if q.Cursor == "bad" {
+			return cargo.Page{}, cargo.ErrInvalidCursor
+		}
+		return cargo.Page{
+			Cargos: []*cargo.Cargo{
+				cargo.New("ABC", cargo.RouteSpecification{Origin: q.Origin}),
+			},
+			NextCursor: "next",
+		}, nil
+	}
+
+	s := newTestService(&cargos, nil, Options{})
+
+	cs, next, err := s.Cargos(cargo.Query{Origin: location.SESTO})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cs) != 1 {
+		t.Fatalf("len(cs) = %d; want = %d", len(cs), 1)
+	}
+	if cs[0].Origin != "SESTO" {
+		t.Errorf("cs[0].Origin = %s; want = %s", cs[0].Origin, "SESTO")
+	}
+	if next != "next" {
+		t.Errorf("next = %s; want = %s", next, "next")
+	}
+
+	if _, _, err := s.Cargos(cargo.Query{Cursor: "bad"}); err != ErrInvalidArgument {
+		t.Errorf("err = %v; want = %v", err, ErrInvalidArgument)
+	}
+	if _, _, err := s.Cargos(cargo.Query{SortBy: "weight"}); err != ErrInvalidArgument {
+		t.Errorf("err = %v; want = %v", err, ErrInvalidArgument)
+	}
+}
+
+func TestBookNewCargoWithAutoRoute(t *testing.T) {
+	var (
+		origin      = location.SESTO
+		destination = location.AUMEL
+		deadline    = time.Date(2015, time.November, 10, 23, 0, 0, 0, time.UTC)
+	)
+
+	var cargos mockCargoRepository
+
+	var rs stubRoutingService
+
+	s := newTestService(&cargos, nil, Options{Routing: &rs})
+
+	b, err := s.BookNewCargo(origin, destination, deadline, AutoRouteOff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Itinerary != nil {
+		t.Errorf("b.Itinerary = %v; want = nil", b.Itinerary)
+	}
+	if cargos.cargo.Delivery.RoutingStatus != cargo.NotRouted {
+		t.Errorf("RoutingStatus = %v; want = %v", cargos.cargo.Delivery.RoutingStatus, cargo.NotRouted)
+	}
+
+	b, err = s.BookNewCargo(origin, destination, deadline, AutoRouteOn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Itinerary == nil {
+		t.Fatalf("cargo should have been routed: %s", b.NotRoutedReason)
+	}
+	if cargos.cargo.Delivery.RoutingStatus != cargo.Routed {
+		t.Errorf("RoutingStatus = %v; want = %v", cargos.cargo.Delivery.RoutingStatus, cargo.Routed)
+	}
+}
+
+func TestBookNewCargoWithAutoRouteNoQualifyingRoute(t *testing.T) {
+	var cargos mockCargoRepository
+
+	var rs mock.RoutingService
+	rs.FetchRoutesFn = func(cargo.RouteSpecification, routing.Constraints) ([]cargo.Itinerary, error) {
+		return []cargo.Itinerary{}, nil
+	}
+
+	s := newTestService(&cargos, nil, Options{Routing: &rs, AutoRoute: true})
+
+	b, err := s.BookNewCargo(location.SESTO, location.AUMEL, time.Date(2015, time.November, 10, 23, 0, 0, 0, time.UTC), AutoRouteDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Itinerary != nil {
+		t.Errorf("b.Itinerary = %v; want = nil", b.Itinerary)
+	}
+	if b.NotRoutedReason == "" {
+		t.Errorf("missing reason")
+	}
+	if cargos.cargo.Delivery.RoutingStatus != cargo.NotRouted {
+		t.Errorf("RoutingStatus = %v; want = %v", cargos.cargo.Delivery.RoutingStatus, cargo.NotRouted)
+	}
+}
+
+func TestChangeArrivalDeadline(t *testing.T) {
+	var cargos mockCargoRepository
+
+	s := newTestService(&cargos, nil, Options{})
+
+	c := cargo.New("ABC", cargo.RouteSpecification{
+		Origin:          location.SESTO,
+		Destination:     location.AUMEL,
+		ArrivalDeadline: time.Date(2015, time.November, 10, 23, 0, 0, 0, time.UTC),
+	})
+	c.AssignToRoute(cargo.Itinerary{Legs: []cargo.Leg{
+		{LoadLocation: location.SESTO, UnloadLocation: location.AUMEL},
+	}})
+
+	if err := cargos.Store(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.ChangeArrivalDeadline(c.TrackingID, time.Time{}); err != ErrInvalidArgument {
+		t.Errorf("err = %v; want = %v", err, ErrInvalidArgument)
+	}
+
+	deadline := time.Date(2015, time.December, 1, 0, 0, 0, 0, time.UTC)
+
+	if err := s.ChangeArrivalDeadline(c.TrackingID, deadline); err != nil {
+		t.Fatal(err)
+	}
+
+	uc, err := cargos.Find(c.TrackingID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uc.RouteSpecification.ArrivalDeadline != deadline {
+		t.Errorf("uc.RouteSpecification.ArrivalDeadline = %s; want = %s",
+			uc.RouteSpecification.ArrivalDeadline, deadline)
+	}
+	if uc.Delivery.RoutingStatus != cargo.Routed {
+		t.Errorf("uc.Delivery.RoutingStatus = %v; want = %v", uc.Delivery.RoutingStatus, cargo.Routed)
+	}
+
+	// A route arriving after the new deadline no longer satisfies it.
+	uc.AssignToRoute(cargo.Itinerary{Legs: []cargo.Leg{
+		cargo.NewLeg("V100", location.SESTO, location.AUMEL, deadline.AddDate(0, 0, -20), deadline.AddDate(0, 0, -1)),
+	}})
+
+	if err := s.ChangeArrivalDeadline(c.TrackingID, deadline.AddDate(0, 0, -7)); err != nil {
+		t.Fatal(err)
+	}
+	if uc.Delivery.RoutingStatus != cargo.Misrouted {
+		t.Errorf("uc.Delivery.RoutingStatus = %v; want = %v", uc.Delivery.RoutingStatus, cargo.Misrouted)
+	}
+
+	uc.DeriveDeliveryProgress(cargo.HandlingHistory{HandlingEvents: []cargo.HandlingEvent{
+		{TrackingID: uc.TrackingID, Activity: cargo.HandlingActivity{Type: cargo.Receive, Location: location.SESTO}},
+	}})
+
+	if err := s.ChangeArrivalDeadline(c.TrackingID, deadline); err != ErrCargoReceived {
+		t.Errorf("err = %v; want = %v", err, ErrCargoReceived)
+	}
+}
+
+func TestChangeOrigin(t *testing.T) {
+	var cargos mockCargoRepository
+	var locations mock.LocationRepository
+
+	locations.FindFn = func(loc location.UNLocode) (*location.Location, error) {
+		if loc != location.DEHAM {
+			return nil, location.ErrUnknown
+		}
+		return location.Hamburg, nil
+	}
+
+	s := newTestService(&cargos, &locations, Options{})
+
+	c := cargo.New("ABC", cargo.RouteSpecification{
+		Origin:          location.SESTO,
+		Destination:     location.AUMEL,
+		ArrivalDeadline: time.Date(2015, time.November, 10, 23, 0, 0, 0, time.UTC),
+	})
+	c.AssignToRoute(cargo.Itinerary{Legs: []cargo.Leg{
+		{LoadLocation: location.SESTO, UnloadLocation: location.AUMEL},
+	}})
+
+	if err := cargos.Store(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.ChangeOrigin(c.TrackingID, "no_such_unlocode"); err != location.ErrUnknown {
+		t.Errorf("err = %v; want = %v", err, location.ErrUnknown)
+	}
+
+	if err := s.ChangeOrigin(c.TrackingID, location.DEHAM); err != nil {
+		t.Fatal(err)
+	}
+
+	uc, err := cargos.Find(c.TrackingID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uc.Origin != location.DEHAM {
+		t.Errorf("uc.Origin = %s; want = %s", uc.Origin, location.DEHAM)
+	}
+	if uc.Delivery.RoutingStatus != cargo.Misrouted {
+		t.Errorf("uc.Delivery.RoutingStatus = %v; want = %v", uc.Delivery.RoutingStatus, cargo.Misrouted)
+	}
+
+	uc.DeriveDeliveryProgress(cargo.HandlingHistory{HandlingEvents: []cargo.HandlingEvent{
+		{TrackingID: uc.TrackingID, Activity: cargo.HandlingActivity{Type: cargo.Receive, Location: location.DEHAM}},
+	}})
+
+	if err := s.ChangeOrigin(c.TrackingID, location.DEHAM); err != ErrCargoReceived {
+		t.Errorf("err = %v; want = %v", err, ErrCargoReceived)
+	}
+}
+
+func TestAttachParty(t *testing.T) {
+	var cargos mockCargoRepository
+
+	var customers mock.CustomerRepository
+	customers.FindFn = func(id customer.ID) (*customer.Customer, error) {
+		if id != "ACME" {
+			return nil, customer.ErrUnknown
+		}
+		return customer.New("ACME", "Acme Corp", "", ""), nil
+	}
+
+	s := newTestService(&cargos, nil, Options{Customers: &customers})
+
+	c := cargo.New("ABC", cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.AUMEL,
+	})
+
+	if err := cargos.Store(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.AttachParty(c.TrackingID, cargo.Shipper, "no_such_customer"); err != customer.ErrUnknown {
+		t.Errorf("err = %v; want = %v", err, customer.ErrUnknown)
+	}
+
+	if err := s.AttachParty(c.TrackingID, cargo.Consignee, "ACME"); err != nil {
+		t.Fatal(err)
+	}
+
+	bc, err := s.LoadCargo(c.TrackingID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bc.Consignee != "ACME" {
+		t.Errorf("bc.Consignee = %s; want = %s", bc.Consignee, "ACME")
+	}
+}
+
+func TestSetNotificationChannels(t *testing.T) {
+	customers := inmem.NewCustomerRepository()
+
+	s := newTestService(nil, nil, Options{Customers: customers})
+
+	id, err := s.RegisterCustomer("Acme Corp", "", "shipping@acme.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.SetNotificationChannels(id, []customer.Channel{customer.Email, customer.Webhook}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetNotificationChannels("NOBODY", nil); err != customer.ErrUnknown {
+		t.Errorf("err = %v; want = %v", err, customer.ErrUnknown)
+	}
+
+	if _, err := s.RegisterCustomer("Globex", "", "shipping@globex.example\r\nBcc: all@globex.example"); err != customer.ErrInvalidEmail {
+		t.Errorf("err = %v; want = %v", err, customer.ErrInvalidEmail)
+	}
+
+	// Customers without an email address can only be notified over the
+	// other channels.
+	other, err := s.RegisterCustomer("Initech", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetNotificationChannels(other, []customer.Channel{customer.Email}); err != customer.ErrInvalidEmail {
+		t.Errorf("err = %v; want = %v", err, customer.ErrInvalidEmail)
+	}
+	if err := s.SetNotificationChannels(other, []customer.Channel{customer.Webhook}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := s.Customers()
+	if len(got) != 2 {
+		t.Fatalf("len(Customers()) = %d; want = %d", len(got), 2)
+	}
+	for _, c := range got {
+		if c.ID == string(id) && !reflect.DeepEqual(c.Channels, []string{"email", "webhook"}) {
+			t.Errorf("Channels = %v; want = [email webhook]", c.Channels)
+		}
+	}
+}
+
+func TestAccessTokens(t *testing.T) {
+	var (
+		cargos    = inmem.NewCargoRepository()
+		customers = inmem.NewCustomerRepository()
+		grants    = inmem.NewGrantRepository()
+	)
+
+	s := NewService(cargos, nil, inmem.NewVoyageRepository(), newIssuer(), grants, Options{Customers: customers})
+
+	c := cargo.New("ABC", cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.AUMEL,
+	})
+	cargos.Store(c)
+	customers.Store(customer.New("ACME", "Acme Corp", "", ""))
+
+	first, err := s.IssueShareToken("ABC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.IssueShareToken("ABC"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.IssueShareToken("XYZ"); err != cargo.ErrUnknown {
+		t.Errorf("err = %v; want = %v", err, cargo.ErrUnknown)
+	}
+
+	if err := s.RevokeToken(access.Token(first.Token)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RevokeToken("unknown"); err != access.ErrUnknown {
+		t.Errorf("err = %v; want = %v", err, access.ErrUnknown)
+	}
+
+	tokens, err := s.ShareTokens("ABC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("len(tokens) = %d; want = %d", len(tokens), 2)
+	}
+	for i, tok := range tokens {
+		if tok.Token != "" {
+			t.Errorf("token %d: Token = %q; want it left out", i, tok.Token)
+		}
+		revoked := i == 0
+		if tok.Active == revoked || (tok.Revoked != nil) != revoked {
+			t.Errorf("token %d: Active = %v, Revoked = %v", i, tok.Active, tok.Revoked)
+		}
+	}
+
+	session, err := s.OpenSession("ACME")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.CustomerID != "ACME" || session.Expires == nil || !session.Active {
+		t.Errorf("session = %+v; want active session for ACME", session)
+	}
+	if _, err := s.OpenSession("NOBODY"); err != customer.ErrUnknown {
+		t.Errorf("err = %v; want = %v", err, customer.ErrUnknown)
+	}
+}
+
+func TestAttachPartyMovesAllotment(t *testing.T) {
+	var (
+		cargos     = inmem.NewCargoRepository()
+		customers  = inmem.NewCustomerRepository()
+		allotments = inmem.NewAllotmentRepository()
+	)
+
+	s := newTestService(cargos, nil, Options{Customers: customers, Allotments: allotments})
+
+	for _, c := range []*customer.Customer{
+		customer.New("ACME", "Acme Corp", "", ""),
+		customer.New("GLOBEX", "Globex", "", ""),
+		customer.New("INITECH", "Initech", "", ""),
+	} {
+		customers.Store(c)
+	}
+
+	var ids []allotment.ID
+	for _, shipper := range []customer.ID{"ACME", "GLOBEX", "INITECH"} {
+		id, err := s.RegisterAllotment(shipper, "V100", cargo.Measurement{TEU: 1}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	// INITECH has no space left.
+	other := cargo.New("XYZ", cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.AUMEL,
+	})
+	other.AttachParty(cargo.Shipper, "INITECH")
+	cargos.Store(other)
+
+	c := cargo.New("ABC", cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.AUMEL,
+	})
+	c.AttachParty(cargo.Shipper, "ACME")
+	cargos.Store(c)
+
+	itinerary := cargo.Itinerary{Legs: []cargo.Leg{
+		{VoyageNumber: "V100", LoadLocation: location.SESTO, UnloadLocation: location.AUMEL},
+	}}
+	for _, id := range []cargo.TrackingID{"XYZ", "ABC"} {
+		if err := s.AssignCargoToRoute(id, itinerary); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.AttachParty("ABC", cargo.Shipper, "GLOBEX"); err != nil {
+		t.Fatal(err)
+	}
+
+	acme, _ := allotments.Find(ids[0])
+	globex, _ := allotments.Find(ids[1])
+	if acme.Holds("ABC") || !globex.Holds("ABC") {
+		t.Errorf("space should have moved from the previous shipper to the new one")
+	}
+
+	if err := s.AttachParty("ABC", cargo.Shipper, "INITECH"); err != allotment.ErrExhausted {
+		t.Errorf("err = %v; want = %v", err, allotment.ErrExhausted)
+	}
+
+	globex, _ = allotments.Find(ids[1])
+	if !globex.Holds("ABC") {
+		t.Errorf("space should be kept when the new shipper has none left")
+	}
+	if bc, _ := s.LoadCargo("ABC"); bc.Shipper != "GLOBEX" {
+		t.Errorf("bc.Shipper = %s; want = %s", bc.Shipper, "GLOBEX")
+	}
+}
+
+func TestAssignCargoToRouteConsumesAllotment(t *testing.T) {
+	var (
+		cargos     = inmem.NewCargoRepository()
+		customers  = inmem.NewCustomerRepository()
+		allotments = inmem.NewAllotmentRepository()
+	)
+
+	s := newTestService(cargos, nil, Options{Customers: customers, Allotments: allotments})
+
+	customers.Store(customer.New("ACME", "Acme Corp", "", ""))
+
+	aid, err := s.RegisterAllotment("ACME", "V100", cargo.Measurement{TEU: 1}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	itinerary := cargo.Itinerary{Legs: []cargo.Leg{
+		{VoyageNumber: "V100", LoadLocation: location.SESTO, UnloadLocation: location.AUMEL},
+	}}
+
+	var ids []cargo.TrackingID
+	for i := 0; i < 2; i++ {
+		c := cargo.New(cargo.NextTrackingID(), cargo.RouteSpecification{
+			Origin:      location.SESTO,
+			Destination: location.AUMEL,
+		})
+		c.AttachParty(cargo.Shipper, "ACME")
+		cargos.Store(c)
+		ids = append(ids, c.TrackingID)
+	}
+
+	if err := s.AssignCargoToRoute(ids[0], itinerary); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AssignCargoToRoute(ids[1], itinerary); err != allotment.ErrExhausted {
+		t.Errorf("err = %v; want = %v", err, allotment.ErrExhausted)
+	}
+
+	c, _ := cargos.Find(ids[1])
+	if c.Delivery.RoutingStatus != cargo.NotRouted {
+		t.Errorf("c.Delivery.RoutingStatus = %v; want = %v", c.Delivery.RoutingStatus, cargo.NotRouted)
+	}
+
+	if err := s.CancelBooking(ids[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	a, _ := allotments.Find(aid)
+	if a.Used().TEU != 0 {
+		t.Errorf("a.Used().TEU = %d; want = %d", a.Used().TEU, 0)
+	}
+
+	if err := s.AssignCargoToRoute(ids[1], itinerary); err != nil {
+		t.Fatal(err)
+	}
+
+	report := s.Allotments("ACME")
+	if len(report) != 1 {
+		t.Fatalf("len(report) = %d; want = %d", len(report), 1)
+	}
+	if report[0].Utilisation != 1 {
+		t.Errorf("report[0].Utilisation = %f; want = %f", report[0].Utilisation, 1.0)
+	}
+}
+
+func TestAssignCargoToRouteSetsModes(t *testing.T) {
+	var cargos mockCargoRepository
+
+	s := newTestService(&cargos, nil, Options{})
+
+	c := cargo.New("ABC", cargo.RouteSpecification{
+		Origin:      location.NLRTM,
+		Destination: location.SESTO,
+	})
+	if err := cargos.Store(c); err != nil {
+		t.Fatal(err)
+	}
+
+	// The client leaves out the mode of the road leg.
+	if err := s.AssignCargoToRoute("ABC", cargo.Itinerary{Legs: []cargo.Leg{
+		{VoyageNumber: "T100", LoadLocation: location.NLRTM, UnloadLocation: location.DEHAM},
+		{VoyageNumber: "V400", LoadLocation: location.DEHAM, UnloadLocation: location.SESTO},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if m := cargos.cargo.Itinerary.Legs[0].Mode; m != voyage.Road {
+		t.Errorf("Legs[0].Mode = %s; want = %s", m, voyage.Road)
+	}
+
+	if err := s.AssignCargoToRoute("ABC", cargo.Itinerary{Legs: []cargo.Leg{
+		{VoyageNumber: "X999", LoadLocation: location.NLRTM, UnloadLocation: location.SESTO},
+	}}); err != ErrInvalidArgument {
+		t.Errorf("err = %v; want = %v", err, ErrInvalidArgument)
+	}
+}
+
+func TestAssignCargoToRouteConcurrently(t *testing.T) {
+	var (
+		cargos     = inmem.NewCargoRepository()
+		customers  = inmem.NewCustomerRepository()
+		allotments = inmem.NewAllotmentRepository()
+	)
+
+	s := newTestService(cargos, nil, Options{Customers: customers, Allotments: allotments})
+
+	customers.Store(customer.New("ACME", "Acme Corp", "", ""))
+
+	aid, err := s.RegisterAllotment("ACME", "V100", cargo.Measurement{TEU: 1}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	itinerary := cargo.Itinerary{Legs: []cargo.Leg{
+		{VoyageNumber: "V100", LoadLocation: location.SESTO, UnloadLocation: location.AUMEL},
+	}}
+
+	var ids []cargo.TrackingID
+	for i := 0; i < 10; i++ {
+		c := cargo.New(cargo.NextTrackingID(), cargo.RouteSpecification{
+			Origin:      location.SESTO,
+			Destination: location.AUMEL,
+		})
+		c.AttachParty(cargo.Shipper, "ACME")
+		cargos.Store(c)
+		ids = append(ids, c.TrackingID)
+	}
+
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id cargo.TrackingID) {
+			defer wg.Done()
+			s.AssignCargoToRoute(id, itinerary)
+		}(id)
+	}
+	wg.Wait()
+
+	a, _ := allotments.Find(aid)
+	if len(a.Consumptions) != 1 {
+		t.Errorf("len(a.Consumptions) = %d; want = %d", len(a.Consumptions), 1)
+	}
+}
+
+func TestAssignCargoToRouteRestoresAllotments(t *testing.T) {
+	var (
+		customers  = inmem.NewCustomerRepository()
+		allotments = inmem.NewAllotmentRepository()
+	)
+
+	c := cargo.New("ABC", cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.AUMEL,
+	})
+	c.AttachParty(cargo.Shipper, "ACME")
+
+	var cargos mock.CargoRepository
+	cargos.FindFn = func(id cargo.TrackingID) (*cargo.Cargo, error) {
+		return c, nil
+	}
+	cargos.StoreFn = func(c *cargo.Cargo) error {
+		return errors.New("unavailable")
+	}
+
+	s := newTestService(&cargos, nil, Options{Customers: customers, Allotments: allotments})
+
+	customers.Store(customer.New("ACME", "Acme Corp", "", ""))
+
+	aid, err := s.RegisterAllotment("ACME", "V100", cargo.Measurement{TEU: 1}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.AssignCargoToRoute("ABC", cargo.Itinerary{Legs: []cargo.Leg{
+		{VoyageNumber: "V100", LoadLocation: location.SESTO, UnloadLocation: location.AUMEL},
+	}}); err == nil {
+		t.Fatal("expected error storing cargo")
+	}
+
+	a, _ := allotments.Find(aid)
+	if a.Holds("ABC") {
+		t.Errorf("allotment holds space for a cargo that was not routed")
+	}
+
+	if docs, _ := s.Documents("ABC"); len(docs) != 0 {
+		t.Errorf("bill of lading issued for a route the cargo was not assigned")
+	}
+}
+
+func TestChangeMeasurementWaitlistsCargo(t *testing.T) {
+	var (
+		cargos     = inmem.NewCargoRepository()
+		customers  = inmem.NewCustomerRepository()
+		allotments = inmem.NewAllotmentRepository()
+	)
+
+	s := newTestService(cargos, nil, Options{Customers: customers, Allotments: allotments})
+
+	customers.Store(customer.New("ACME", "Acme Corp", "", ""))
+
+	aid, err := s.RegisterAllotment("ACME", "V100", cargo.Measurement{TEU: 2}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := cargo.New("ABC", cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.AUMEL,
+	})
+	c.Measurement = cargo.Measurement{TEU: 1}
+	c.AttachParty(cargo.Shipper, "ACME")
+	cargos.Store(c)
+
+	if err := s.AssignCargoToRoute("ABC", cargo.Itinerary{Legs: []cargo.Leg{
+		{VoyageNumber: "V100", LoadLocation: location.SESTO, UnloadLocation: location.AUMEL},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.ChangeMeasurement("ABC", cargo.Measurement{TEU: 3}); err != allotment.ErrWaitlisted {
+		t.Errorf("err = %v; want = %v", err, allotment.ErrWaitlisted)
+	}
+
+	a, _ := allotments.Find(aid)
+	if a.Holds("ABC") || !a.IsWaitlisted("ABC") {
+		t.Errorf("Holds = %v, IsWaitlisted = %v; want = false, true", a.Holds("ABC"), a.IsWaitlisted("ABC"))
+	}
+}
+
+func TestDocuments(t *testing.T) {
+	var cargos mockCargoRepository
+
+	s := newTestService(&cargos, nil, Options{})
+
+	b, err := s.BookNewCargo(location.SESTO, location.AUMEL, time.Date(2015, time.November, 10, 23, 0, 0, 0, time.UTC), AutoRouteOff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	docs, err := s.Documents(b.TrackingID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 0 {
+		t.Errorf("len(docs) = %d; want = %d", len(docs), 0)
+	}
+	if _, err := s.BillOfLading(b.TrackingID, 0); err != document.ErrNotRouted {
+		t.Errorf("err = %v; want = %v", err, document.ErrNotRouted)
+	}
+
+	for _, v := range []voyage.Number{"V100", "V300"} {
+		itinerary := cargo.Itinerary{Legs: []cargo.Leg{
+			{VoyageNumber: v, LoadLocation: location.SESTO, UnloadLocation: location.AUMEL},
+		}}
+		if err := s.AssignCargoToRoute(b.TrackingID, itinerary); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	docs, err = s.Documents(b.TrackingID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("len(docs) = %d; want = %d", len(docs), 2)
+	}
+	if !docs[1].Current || docs[0].Current {
+		t.Errorf("only the latest version should be current")
+	}
+
+	bl, err := s.BillOfLading(b.TrackingID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bl.Legs[0].VoyageNumber != "V100" {
+		t.Errorf("bl.Legs[0].VoyageNumber = %s; want = %s", bl.Legs[0].VoyageNumber, "V100")
+	}
+	if _, err := s.BillOfLading(b.TrackingID, 3); err != document.ErrUnknown {
+		t.Errorf("err = %v; want = %v", err, document.ErrUnknown)
+	}
+
+	// The B/L number stays the same between versions, so it is only added
+	// as a reference once.
+	want := []cargo.Reference{{Type: cargo.BillOfLadingNumber, Value: docs[0].Number}}
+	if !reflect.DeepEqual(cargos.cargo.References, want) {
+		t.Errorf("References = %v; want = %v", cargos.cargo.References, want)
+	}
+}
+
+func TestAssignCargoToRouteIssuingFails(t *testing.T) {
+	cargos := inmem.NewCargoRepository()
+
+	c := cargo.New("ABC", cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.AUMEL,
+	})
+	if err := cargos.Store(c); err != nil {
+		t.Fatal(err)
+	}
+
+	// The voyage is known to booking, but not yet to the issuer.
+	voyages := &mock.VoyageRepository{
+		FindFn: func(voyage.Number) (*voyage.Voyage, error) {
+			return nil, voyage.ErrUnknown
+		},
+	}
+	issuer := newIssuer()
+	issuer.VoyageRepository = voyages
+
+	s := NewService(cargos, nil, inmem.NewVoyageRepository(), issuer, inmem.NewGrantRepository(), Options{})
+
+	itinerary := cargo.Itinerary{Legs: []cargo.Leg{
+		{VoyageNumber: "V100", LoadLocation: location.SESTO, UnloadLocation: location.AUMEL},
+	}}
+
+	if err := s.AssignCargoToRoute("ABC", itinerary); err != voyage.ErrUnknown {
+		t.Errorf("err = %v; want = %v", err, voyage.ErrUnknown)
+	}
+	if docs, _ := s.Documents("ABC"); len(docs) != 0 {
+		t.Errorf("len(docs) = %d; want = %d", len(docs), 0)
+	}
+
+	// The cargo keeps its new route, and the bill of lading is issued once
+	// it is asked for.
+	voyages.FindFn = func(n voyage.Number) (*voyage.Voyage, error) {
+		return voyage.New(n, voyage.Schedule{}), nil
+	}
+
+	bl, err := s.BillOfLading("ABC", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bl.Version != 1 || bl.Legs[0].VoyageNumber != "V100" {
+		t.Errorf("Version = %d, VoyageNumber = %s; want = 1, V100", bl.Version, bl.Legs[0].VoyageNumber)
+	}
+	if stored, _ := cargos.Find("ABC"); len(stored.References) != 1 {
+		t.Errorf("len(References) = %d; want = %d", len(stored.References), 1)
+	}
+}
+
+func TestAddReference(t *testing.T) {
+	var cargos mockCargoRepository
+
+	s := newTestService(&cargos, nil, Options{})
+
+	c := cargo.New("ABC", cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.AUMEL,
+	})
+	cargos.Store(c)
+
+	tests := []struct {
+		typ   cargo.ReferenceType
+		value string
+		err   error
+	}{
+		{cargo.CustomerReference, "po-4711", nil},
+		{cargo.CustomerReference, "PO-4711", nil},
+		{cargo.ContainerNumber, "CSQU3054383", nil},
+		{cargo.ContainerNumber, "CSQU3054384", cargo.ErrInvalidReference},
+		{cargo.BillOfLadingNumber, "BL1234", ErrInvalidArgument},
+	}
+
+	for _, tt := range tests {
+		if err := s.AddReference("ABC", tt.typ, tt.value); err != tt.err {
+			t.Errorf("AddReference(%s, %q) = %v; want = %v", tt.typ, tt.value, err, tt.err)
+		}
+	}
+
+	bc, err := s.LoadCargo("ABC")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Reference{
+		{Type: "Customer reference", Value: "PO-4711"},
+		{Type: "Container number", Value: "CSQU3054383"},
+	}
+	if !reflect.DeepEqual(bc.References, want) {
+		t.Errorf("bc.References = %v; want = %v", bc.References, want)
+	}
+}
+
+func TestImportCargos(t *testing.T) {
+	var cargos mock.CargoRepository
+	cargos.StoreFn = func(c *cargo.Cargo) error {
+		return nil
+	}
+
+	var locations mock.LocationRepository
+	locations.FindFn = func(code location.UNLocode) (*location.Location, error) {
+		if code == "XXXXX" {
+			return nil, location.ErrUnknown
+		}
+		return &location.Location{UNLocode: code}, nil
+	}
+
+	s := newTestService(&cargos, &locations, Options{})
+
+	deadline := time.Date(2015, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	rows := []ImportRow{
+		{Line: 2, Origin: location.SESTO, Destination: location.AUMEL, ArrivalDeadline: deadline},
+		{Line: 3, Origin: location.SESTO, Destination: "XXXXX", ArrivalDeadline: deadline},
+		{Line: 4, Origin: location.SESTO, Destination: location.SESTO, ArrivalDeadline: deadline},
+	}
+
+	r, err := s.ImportCargos(rows, AutoRouteOff, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Accepted) != 1 || len(r.Rejected) != 2 {
+		t.Fatalf("accepted = %d, rejected = %d; want = 1, 2", len(r.Accepted), len(r.Rejected))
+	}
+	if cargos.StoreInvoked {
+		t.Errorf("dry run should not book any cargos")
+	}
+
+	r, err = s.ImportCargos(rows, AutoRouteOff, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Accepted[0].TrackingID == "" {
+		t.Errorf("missing tracking id")
+	}
+	if r.Rejected[0].Line != 3 {
+		t.Errorf("r.Rejected[0].Line = %d; want = %d", r.Rejected[0].Line, 3)
+	}
+
+	if _, err := s.ImportCargos(nil, AutoRouteOff, false); err != ErrInvalidArgument {
+		t.Errorf("err = %v; want = %v", err, ErrInvalidArgument)
+	}
+}
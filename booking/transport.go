@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	kitlog "github.com/go-kit/kit/log"
@@ -11,8 +13,14 @@ import (
 	"github.com/gorilla/mux"
 	"golang.org/x/net/context"
 
+	"github.com/marcusolsson/goddd/access"
+	"github.com/marcusolsson/goddd/allotment"
 	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/document"
 	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/routing"
+	"github.com/marcusolsson/goddd/voyage"
 )
 
 // MakeHandler returns a handler for the booking service.
@@ -57,6 +65,20 @@ func MakeHandler(ctx context.Context, bs Service, logger kitlog.Logger) http.Han
 		encodeResponse,
 		opts...,
 	)
+	changeArrivalDeadlineHandler := kithttp.NewServer(
+		ctx,
+		makeChangeArrivalDeadlineEndpoint(bs),
+		decodeChangeArrivalDeadlineRequest,
+		encodeResponse,
+		opts...,
+	)
+	changeOriginHandler := kithttp.NewServer(
+		ctx,
+		makeChangeOriginEndpoint(bs),
+		decodeChangeOriginRequest,
+		encodeResponse,
+		opts...,
+	)
 	listCargosHandler := kithttp.NewServer(
 		ctx,
 		makeListCargosEndpoint(bs),
@@ -72,20 +94,169 @@ func MakeHandler(ctx context.Context, bs Service, logger kitlog.Logger) http.Han
 		opts...,
 	)
 
+	registerCustomerHandler := kithttp.NewServer(
+		ctx,
+		makeRegisterCustomerEndpoint(bs),
+		decodeRegisterCustomerRequest,
+		encodeResponse,
+		opts...,
+	)
+	attachPartyHandler := kithttp.NewServer(
+		ctx,
+		makeAttachPartyEndpoint(bs),
+		decodeAttachPartyRequest,
+		encodeResponse,
+		opts...,
+	)
+	addReferenceHandler := kithttp.NewServer(
+		ctx,
+		makeAddReferenceEndpoint(bs),
+		decodeAddReferenceRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	changeMeasurementHandler := kithttp.NewServer(
+		ctx,
+		makeChangeMeasurementEndpoint(bs),
+		decodeChangeMeasurementRequest,
+		encodeResponse,
+		opts...,
+	)
+	cancelBookingHandler := kithttp.NewServer(
+		ctx,
+		makeCancelBookingEndpoint(bs),
+		decodeCancelBookingRequest,
+		encodeResponse,
+		opts...,
+	)
+	registerAllotmentHandler := kithttp.NewServer(
+		ctx,
+		makeRegisterAllotmentEndpoint(bs),
+		decodeRegisterAllotmentRequest,
+		encodeResponse,
+		opts...,
+	)
+	listAllotmentsHandler := kithttp.NewServer(
+		ctx,
+		makeListAllotmentsEndpoint(bs),
+		decodeListAllotmentsRequest,
+		encodeResponse,
+		opts...,
+	)
+	importCargosHandler := kithttp.NewServer(
+		ctx,
+		makeImportCargosEndpoint(bs),
+		decodeImportCargosRequest,
+		encodeResponse,
+		opts...,
+	)
+	listDocumentsHandler := kithttp.NewServer(
+		ctx,
+		makeListDocumentsEndpoint(bs),
+		decodeListDocumentsRequest,
+		encodeResponse,
+		opts...,
+	)
+	loadBillOfLadingHandler := kithttp.NewServer(
+		ctx,
+		makeLoadBillOfLadingEndpoint(bs),
+		decodeLoadBillOfLadingRequest,
+		encodeBillOfLadingResponse,
+		opts...,
+	)
+	setNotificationChannelsHandler := kithttp.NewServer(
+		ctx,
+		makeSetNotificationChannelsEndpoint(bs),
+		decodeSetNotificationChannelsRequest,
+		encodeResponse,
+		opts...,
+	)
+	revokeTokenHandler := kithttp.NewServer(
+		ctx,
+		makeRevokeTokenEndpoint(bs),
+		decodeRevokeTokenRequest,
+		encodeResponse,
+		opts...,
+	)
+
 	r := mux.NewRouter()
 
 	r.Handle("/booking/v1/cargos", bookCargoHandler).Methods("POST")
 	r.Handle("/booking/v1/cargos", listCargosHandler).Methods("GET")
+	r.Handle("/booking/v1/cargos/import", importCargosHandler).Methods("POST")
 	r.Handle("/booking/v1/cargos/{id}", loadCargoHandler).Methods("GET")
 	r.Handle("/booking/v1/cargos/{id}/request_routes", requestRoutesHandler).Methods("GET")
 	r.Handle("/booking/v1/cargos/{id}/assign_to_route", assignToRouteHandler).Methods("POST")
 	r.Handle("/booking/v1/cargos/{id}/change_destination", changeDestinationHandler).Methods("POST")
+	r.Handle("/booking/v1/cargos/{id}/change_arrival_deadline", changeArrivalDeadlineHandler).Methods("POST")
+	r.Handle("/booking/v1/cargos/{id}/change_origin", changeOriginHandler).Methods("POST")
+	r.Handle("/booking/v1/cargos/{id}/parties", attachPartyHandler).Methods("POST")
+	r.Handle("/booking/v1/cargos/{id}/references", addReferenceHandler).Methods("POST")
+	r.Handle("/booking/v1/cargos/{id}/change_measurement", changeMeasurementHandler).Methods("POST")
+	r.Handle("/booking/v1/cargos/{id}/cancel", cancelBookingHandler).Methods("POST")
+	r.Handle("/booking/v1/cargos/{id}/documents", listDocumentsHandler).Methods("GET")
+	r.Handle("/booking/v1/cargos/{id}/documents/bill_of_lading", loadBillOfLadingHandler).Methods("GET")
+	r.Handle("/booking/v1/tokens/{token}/revoke", revokeTokenHandler).Methods("POST")
 	r.Handle("/booking/v1/locations", listLocationsHandler).Methods("GET")
+	r.Handle("/booking/v1/customers", registerCustomerHandler).Methods("POST")
+	r.Handle("/booking/v1/customers/{id}/channels", setNotificationChannelsHandler).Methods("PUT")
+	r.Handle("/booking/v1/allotments", registerAllotmentHandler).Methods("POST")
+	r.Handle("/booking/v1/allotments", listAllotmentsHandler).Methods("GET")
 	r.Handle("/booking/v1/docs", http.StripPrefix("/booking/v1/docs", http.FileServer(http.Dir("booking/docs"))))
 
 	return r
 }
 
+// MakeOperatorHandler returns a handler for the parts of the booking service
+// handing out access to tracking information, such as share tokens and
+// customer sessions. It is meant to be served apart from MakeHandler, where
+// only operators can reach it.
+func MakeOperatorHandler(ctx context.Context, bs Service, logger kitlog.Logger) http.Handler {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorLogger(logger),
+		kithttp.ServerErrorEncoder(encodeError),
+	}
+
+	listCustomersHandler := kithttp.NewServer(
+		ctx,
+		makeListCustomersEndpoint(bs),
+		decodeListCustomersRequest,
+		encodeResponse,
+		opts...,
+	)
+	issueShareTokenHandler := kithttp.NewServer(
+		ctx,
+		makeIssueShareTokenEndpoint(bs),
+		decodeIssueShareTokenRequest,
+		encodeResponse,
+		opts...,
+	)
+	listShareTokensHandler := kithttp.NewServer(
+		ctx,
+		makeListShareTokensEndpoint(bs),
+		decodeListShareTokensRequest,
+		encodeResponse,
+		opts...,
+	)
+	openSessionHandler := kithttp.NewServer(
+		ctx,
+		makeOpenSessionEndpoint(bs),
+		decodeOpenSessionRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	r := mux.NewRouter()
+
+	r.Handle("/booking/v1/cargos/{id}/share_tokens", issueShareTokenHandler).Methods("POST")
+	r.Handle("/booking/v1/cargos/{id}/share_tokens", listShareTokensHandler).Methods("GET")
+	r.Handle("/booking/v1/customers", listCustomersHandler).Methods("GET")
+	r.Handle("/booking/v1/customers/{id}/sessions", openSessionHandler).Methods("POST")
+
+	return r
+}
+
 var errBadRoute = errors.New("bad route")
 
 func decodeBookCargoRequest(_ context.Context, r *http.Request) (interface{}, error) {
@@ -93,6 +264,7 @@ func decodeBookCargoRequest(_ context.Context, r *http.Request) (interface{}, er
 		Origin          string    `json:"origin"`
 		Destination     string    `json:"destination"`
 		ArrivalDeadline time.Time `json:"arrival_deadline"`
+		AutoRoute       *bool     `json:"auto_route"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -103,9 +275,63 @@ func decodeBookCargoRequest(_ context.Context, r *http.Request) (interface{}, er
 		Origin:          location.UNLocode(body.Origin),
 		Destination:     location.UNLocode(body.Destination),
 		ArrivalDeadline: body.ArrivalDeadline,
+		AutoRoute:       autoRouteMode(body.AutoRoute),
 	}, nil
 }
 
+func autoRouteMode(b *bool) AutoRoute {
+	switch {
+	case b == nil:
+		return AutoRouteDefault
+	case *b:
+		return AutoRouteOn
+	}
+	return AutoRouteOff
+}
+
+// maxImportSize is the maximum size of the body of an import, which is
+// plenty for MaxImportRows rows.
+const maxImportSize = 4 << 20
+
+func decodeImportCargosRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vals := r.URL.Query()
+
+	var req importCargosRequest
+
+	if v := vals.Get("dry_run"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, ErrInvalidArgument
+		}
+		req.DryRun = b
+	}
+
+	if v := vals.Get("auto_route"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, ErrInvalidArgument
+		}
+		req.AutoRoute = autoRouteMode(&b)
+	}
+
+	body := http.MaxBytesReader(nil, r.Body, maxImportSize)
+
+	var err error
+	switch ct := r.Header.Get("Content-Type"); {
+	case strings.HasPrefix(ct, "text/csv"):
+		req.Rows, err = parseCSV(body)
+	case strings.HasPrefix(ct, "application/x-ndjson"), strings.HasPrefix(ct, "application/jsonl"):
+		req.Rows, err = parseJSONLines(body)
+	default:
+		return nil, ErrInvalidArgument
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
 func decodeLoadCargoRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	vars := mux.Vars(r)
 	id, ok := vars["id"]
@@ -121,7 +347,31 @@ func decodeRequestRoutesRequest(_ context.Context, r *http.Request) (interface{}
 	if !ok {
 		return nil, errBadRoute
 	}
-	return requestRoutesRequest{ID: cargo.TrackingID(id)}, nil
+
+	vals := r.URL.Query()
+
+	c := routing.Constraints{
+		Carriers: vals["carrier"],
+	}
+
+	for _, a := range vals["avoid"] {
+		c.Avoid = append(c.Avoid, location.UNLocode(a))
+	}
+
+	if v := vals.Get("max_transshipments"); v != "" {
+		max, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, ErrInvalidArgument
+		}
+		c.MaxTransshipments = &max
+	}
+
+	var err error
+	if c.DepartAfter, err = parseTime(vals.Get("depart_after")); err != nil {
+		return nil, ErrInvalidArgument
+	}
+
+	return requestRoutesRequest{ID: cargo.TrackingID(id), Constraints: c}, nil
 }
 
 func decodeAssignToRouteRequest(_ context.Context, r *http.Request) (interface{}, error) {
@@ -163,14 +413,407 @@ func decodeChangeDestinationRequest(_ context.Context, r *http.Request) (interfa
 	}, nil
 }
 
+func decodeChangeArrivalDeadlineRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+
+	var body struct {
+		ArrivalDeadline time.Time `json:"arrival_deadline"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	return changeArrivalDeadlineRequest{
+		ID:              cargo.TrackingID(id),
+		ArrivalDeadline: body.ArrivalDeadline,
+	}, nil
+}
+
+func decodeChangeOriginRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+
+	var body struct {
+		Origin string `json:"origin"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	return changeOriginRequest{
+		ID:     cargo.TrackingID(id),
+		Origin: location.UNLocode(body.Origin),
+	}, nil
+}
+
 func decodeListCargosRequest(_ context.Context, r *http.Request) (interface{}, error) {
-	return listCargosRequest{}, nil
+	vals := r.URL.Query()
+
+	q := cargo.Query{
+		Origin:      location.UNLocode(vals.Get("origin")),
+		Destination: location.UNLocode(vals.Get("destination")),
+		Voyage:      voyage.Number(vals.Get("voyage")),
+		Customer:    customer.ID(vals.Get("customer")),
+		Cursor:      vals.Get("cursor"),
+	}
+
+	if v := vals.Get("routing_status"); v != "" {
+		s, ok := routingStatuses[v]
+		if !ok {
+			return nil, ErrInvalidArgument
+		}
+		q.RoutingStatus = &s
+	}
+
+	if v := vals.Get("transport_status"); v != "" {
+		s, ok := transportStatuses[v]
+		if !ok {
+			return nil, ErrInvalidArgument
+		}
+		q.TransportStatus = &s
+	}
+
+	if v := vals.Get("misdirected"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, ErrInvalidArgument
+		}
+		q.Misdirected = &b
+	}
+
+	var err error
+	if q.DeadlineFrom, err = parseTime(vals.Get("deadline_from")); err != nil {
+		return nil, ErrInvalidArgument
+	}
+	if q.DeadlineTo, err = parseTime(vals.Get("deadline_to")); err != nil {
+		return nil, ErrInvalidArgument
+	}
+
+	if v := vals.Get("sort"); v != "" {
+		if strings.HasPrefix(v, "-") {
+			q.Descending = true
+			v = v[1:]
+		}
+		q.SortBy = cargo.SortField(v)
+	}
+
+	if v := vals.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit < 0 {
+			return nil, ErrInvalidArgument
+		}
+	}
+
+	return listCargosRequest{Query: q}, nil
+}
+
+var routingStatuses = map[string]cargo.RoutingStatus{
+	"not_routed": cargo.NotRouted,
+	"misrouted":  cargo.Misrouted,
+	"routed":     cargo.Routed,
+}
+
+var transportStatuses = map[string]cargo.TransportStatus{
+	"not_received":    cargo.NotReceived,
+	"in_port":         cargo.InPort,
+	"onboard_carrier": cargo.OnboardCarrier,
+	"claimed":         cargo.Claimed,
+	"unknown":         cargo.Unknown,
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
 }
 
 func decodeListLocationsRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	return listLocationsRequest{}, nil
 }
 
+func decodeRegisterCustomerRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var body struct {
+		Name    string `json:"name"`
+		Address string `json:"address"`
+		Email   string `json:"email"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	return registerCustomerRequest{
+		Name:    body.Name,
+		Address: body.Address,
+		Email:   body.Email,
+	}, nil
+}
+
+func decodeListCustomersRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return listCustomersRequest{}, nil
+}
+
+func decodeAttachPartyRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+
+	var body struct {
+		Role       string `json:"role"`
+		CustomerID string `json:"customer_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	role, ok := roles[body.Role]
+	if !ok {
+		return nil, ErrInvalidArgument
+	}
+
+	return attachPartyRequest{
+		ID:         cargo.TrackingID(id),
+		Role:       role,
+		CustomerID: customer.ID(body.CustomerID),
+	}, nil
+}
+
+func decodeAddReferenceRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+
+	var body struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	typ, ok := referenceTypes[body.Type]
+	if !ok {
+		return nil, ErrInvalidArgument
+	}
+
+	return addReferenceRequest{
+		ID:    cargo.TrackingID(id),
+		Type:  typ,
+		Value: body.Value,
+	}, nil
+}
+
+func decodeChangeMeasurementRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+
+	var body struct {
+		TEU    int     `json:"teu"`
+		Weight float64 `json:"weight"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	return changeMeasurementRequest{
+		ID:          cargo.TrackingID(id),
+		Measurement: cargo.Measurement{TEU: body.TEU, Weight: body.Weight},
+	}, nil
+}
+
+func decodeCancelBookingRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+	return cancelBookingRequest{ID: cargo.TrackingID(id)}, nil
+}
+
+func decodeRegisterAllotmentRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var body struct {
+		CustomerID   string  `json:"customer_id"`
+		VoyageNumber string  `json:"voyage_number"`
+		TEU          int     `json:"teu"`
+		Weight       float64 `json:"weight"`
+		Waitlist     bool    `json:"waitlist"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	return registerAllotmentRequest{
+		CustomerID: customer.ID(body.CustomerID),
+		Voyage:     voyage.Number(body.VoyageNumber),
+		Capacity:   cargo.Measurement{TEU: body.TEU, Weight: body.Weight},
+		Waitlist:   body.Waitlist,
+	}, nil
+}
+
+func decodeListAllotmentsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return listAllotmentsRequest{CustomerID: customer.ID(r.URL.Query().Get("customer"))}, nil
+}
+
+func decodeListDocumentsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+	return listDocumentsRequest{ID: cargo.TrackingID(id)}, nil
+}
+
+func decodeIssueShareTokenRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+	return issueShareTokenRequest{ID: cargo.TrackingID(id)}, nil
+}
+
+func decodeListShareTokensRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+	return listShareTokensRequest{ID: cargo.TrackingID(id)}, nil
+}
+
+func decodeSetNotificationChannelsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+
+	var body struct {
+		Channels []string `json:"channels"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	var channels []customer.Channel
+	for _, s := range body.Channels {
+		c, err := customer.ParseChannel(s)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, c)
+	}
+
+	return setNotificationChannelsRequest{
+		ID:       customer.ID(id),
+		Channels: channels,
+	}, nil
+}
+
+func decodeOpenSessionRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+	return openSessionRequest{CustomerID: customer.ID(id)}, nil
+}
+
+func decodeRevokeTokenRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	token, ok := vars["token"]
+	if !ok {
+		return nil, errBadRoute
+	}
+	return revokeTokenRequest{Token: access.Token(token)}, nil
+}
+
+func decodeLoadBillOfLadingRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+
+	req := loadBillOfLadingRequest{
+		ID:     cargo.TrackingID(id),
+		Format: "html",
+	}
+
+	vals := r.URL.Query()
+
+	if v := vals.Get("version"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, ErrInvalidArgument
+		}
+		req.Version = n
+	}
+
+	switch f := vals.Get("format"); f {
+	case "html", "text":
+		req.Format = f
+	case "":
+		if strings.HasPrefix(r.Header.Get("Accept"), "text/plain") {
+			req.Format = "text"
+		}
+	default:
+		return nil, ErrInvalidArgument
+	}
+
+	return req, nil
+}
+
+// encodeBillOfLadingResponse renders the bill of lading rather than encoding
+// it as JSON.
+func encodeBillOfLadingResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(loadBillOfLadingResponse)
+	if resp.Err != nil {
+		encodeError(ctx, resp.Err, w)
+		return nil
+	}
+	if resp.Format == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		return document.RenderText(w, &resp.BillOfLading)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	return document.RenderHTML(w, &resp.BillOfLading)
+}
+
+// referenceTypes are the types of references that may be added. B/L numbers
+// are only added as bills of lading are issued.
+var referenceTypes = map[string]cargo.ReferenceType{
+	"customer_reference": cargo.CustomerReference,
+	"container_number":   cargo.ContainerNumber,
+}
+
+var roles = map[string]cargo.Role{
+	"shipper":      cargo.Shipper,
+	"consignee":    cargo.Consignee,
+	"notify_party": cargo.NotifyParty,
+}
+
 func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	if e, ok := response.(errorer); ok && e.error() != nil {
 		encodeError(ctx, e.error(), w)
@@ -186,11 +829,21 @@ type errorer interface {
 
 // encode errors from business-logic
 func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+	if e, ok := err.(kithttp.Error); ok && e.Domain == kithttp.DomainDecode {
+		err = e.Err
+	}
+
 	switch err {
-	case cargo.ErrUnknown:
+	case cargo.ErrUnknown, customer.ErrUnknown, document.ErrUnknown, access.ErrUnknown:
 		w.WriteHeader(http.StatusNotFound)
-	case ErrInvalidArgument:
+	case ErrInvalidArgument, cargo.ErrInvalidReference, customer.ErrUnknownChannel, customer.ErrInvalidEmail:
 		w.WriteHeader(http.StatusBadRequest)
+	case ErrCargoReceived, allotment.ErrExhausted, allotment.ErrWaitlisted, allotment.ErrConflict, document.ErrConflict, document.ErrNotRouted:
+		w.WriteHeader(http.StatusConflict)
+	case routing.ErrUnavailable:
+		w.WriteHeader(http.StatusServiceUnavailable)
+	case routing.ErrTimeout:
+		w.WriteHeader(http.StatusGatewayTimeout)
 	default:
 		w.WriteHeader(http.StatusInternalServerError)
 	}
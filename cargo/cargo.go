@@ -21,6 +21,9 @@ type Cargo struct {
 	RouteSpecification RouteSpecification
 	Itinerary          Itinerary
 	Delivery           Delivery
+	Parties            Parties
+	Measurement        Measurement
+	References         []Reference
 }
 
 // SpecifyNewRoute specifies a new route for this cargo.
@@ -41,6 +44,13 @@ func (c *Cargo) DeriveDeliveryProgress(history HandlingHistory) {
 	c.Delivery = DeriveDeliveryFrom(c.RouteSpecification, c.Itinerary, history)
 }
 
+// IsLate checks whether the cargo is expected to arrive after its arrival
+// deadline.
+func (c *Cargo) IsLate() bool {
+	deadline, eta := c.RouteSpecification.ArrivalDeadline, c.Delivery.ETA
+	return !deadline.IsZero() && !eta.IsZero() && eta.After(deadline)
+}
+
 // New creates a new, unrouted cargo.
 func New(id TrackingID, rs RouteSpecification) *Cargo {
 	itinerary := Itinerary{}
@@ -51,6 +61,7 @@ func New(id TrackingID, rs RouteSpecification) *Cargo {
 		Origin:             rs.Origin,
 		RouteSpecification: rs,
 		Delivery:           DeriveDeliveryFrom(rs, itinerary, history),
+		Measurement:        Measurement{TEU: 1},
 	}
 }
 
@@ -58,7 +69,18 @@ func New(id TrackingID, rs RouteSpecification) *Cargo {
 type Repository interface {
 	Store(cargo *Cargo) error
 	Find(id TrackingID) (*Cargo, error)
+
+	// FindMany returns the cargos matching any of the tracking IDs. Unknown
+	// tracking IDs are left out.
+	FindMany(ids []TrackingID) ([]*Cargo, error)
+
+	// FindByReference returns the cargos carrying a reference of any type
+	// with the given value, ordered by tracking ID.
+	FindByReference(value string) ([]*Cargo, error)
+
 	FindAll() []*Cargo
+	Query(q Query) (Page, error)
+	Remove(id TrackingID) error
 }
 
 // ErrUnknown is used when a cargo could not be found.
@@ -79,11 +101,18 @@ type RouteSpecification struct {
 }
 
 // IsSatisfiedBy checks whether provided itinerary satisfies this
-// specification.
+// specification. Itineraries without an arrival time are taken to arrive
+// in time.
 func (s RouteSpecification) IsSatisfiedBy(itinerary Itinerary) bool {
-	return itinerary.Legs != nil &&
-		s.Origin == itinerary.InitialDepartureLocation() &&
-		s.Destination == itinerary.FinalArrivalLocation()
+	if itinerary.Legs == nil ||
+		s.Origin != itinerary.InitialDepartureLocation() ||
+		s.Destination != itinerary.FinalArrivalLocation() {
+		return false
+	}
+
+	arrival := itinerary.FinalArrivalTime()
+
+	return s.ArrivalDeadline.IsZero() || arrival.IsZero() || !arrival.After(s.ArrivalDeadline)
 }
 
 // RoutingStatus describes status of cargo routing.
@@ -135,3 +164,10 @@ func (s TransportStatus) String() string {
 	}
 	return ""
 }
+
+// Measurement describes the space a cargo occupies on a carrier, in
+// twenty-foot equivalent units and kilograms.
+type Measurement struct {
+	TEU    int
+	Weight float64
+}
@@ -68,6 +68,31 @@ func TestRoutingStatus(t *testing.T) {
 		t.Errorf("RoutingStatus = %v; want = %v",
 			c.Delivery.RoutingStatus, Routed)
 	}
+
+	// An itinerary arriving after the deadline does not satisfy it.
+	late := Itinerary{
+		Legs: []Leg{
+			NewLeg("V100", location.SESTO, location.AUMEL,
+				time.Date(2009, time.March, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2009, time.March, 20, 0, 0, 0, 0, time.UTC)),
+		},
+	}
+
+	c.AssignToRoute(late)
+	if c.Delivery.RoutingStatus != Routed {
+		t.Errorf("RoutingStatus = %v; want = %v",
+			c.Delivery.RoutingStatus, Routed)
+	}
+
+	c.SpecifyNewRoute(RouteSpecification{
+		Origin:          location.SESTO,
+		Destination:     location.AUMEL,
+		ArrivalDeadline: time.Date(2009, time.March, 15, 0, 0, 0, 0, time.UTC),
+	})
+	if c.Delivery.RoutingStatus != Misrouted {
+		t.Errorf("RoutingStatus = %v; want = %v",
+			c.Delivery.RoutingStatus, Misrouted)
+	}
 }
 
 func TestLastKnownLocation_WhenNoEvents(t *testing.T) {
@@ -184,3 +209,29 @@ func populateCargoClaimedInMelbourne() *Cargo {
 
 	return c
 }
+
+func TestAttachParty(t *testing.T) {
+	c := New("ABC", RouteSpecification{})
+
+	c.AttachParty(Shipper, "S1")
+	c.AttachParty(Consignee, "C1")
+	c.AttachParty(NotifyParty, "N1")
+	c.AttachParty(NotifyParty, "N1")
+	c.AttachParty(NotifyParty, "N2")
+
+	if c.Parties.Shipper != "S1" {
+		t.Errorf("c.Parties.Shipper = %s; want = %s", c.Parties.Shipper, "S1")
+	}
+	if c.Parties.Consignee != "C1" {
+		t.Errorf("c.Parties.Consignee = %s; want = %s", c.Parties.Consignee, "C1")
+	}
+	if len(c.Parties.NotifyParties) != 2 {
+		t.Errorf("len(c.Parties.NotifyParties) = %d; want = %d", len(c.Parties.NotifyParties), 2)
+	}
+	if !c.Parties.Includes("N2") {
+		t.Errorf("parties should include N2")
+	}
+	if c.Parties.Includes("X") {
+		t.Errorf("parties should not include X")
+	}
+}
@@ -100,16 +100,16 @@ func calculateUnloadedAtDestination(event HandlingEvent, rs RouteSpecification)
 		return false
 	}
 
-	return event.Activity.Type == Unload && rs.Destination == event.Activity.Location
+	return (event.Activity.Type == Unload || event.Activity.Type == GateIn) && rs.Destination == event.Activity.Location
 }
 
 func calculateTransportStatus(event HandlingEvent) TransportStatus {
 	switch event.Activity.Type {
 	case NotHandled:
 		return NotReceived
-	case Load:
+	case Load, GateOut:
 		return OnboardCarrier
-	case Unload:
+	case Unload, GateIn:
 		return InPort
 	case Receive:
 		return InPort
@@ -134,19 +134,18 @@ func calculateNextExpectedActivity(d Delivery) HandlingActivity {
 	case NotHandled:
 		return HandlingActivity{Type: Receive, Location: d.RouteSpecification.Origin}
 	case Receive:
-		l := d.Itinerary.Legs[0]
-		return HandlingActivity{Type: Load, Location: l.LoadLocation, VoyageNumber: l.VoyageNumber}
-	case Load:
+		return d.Itinerary.Legs[0].LoadActivity()
+	case Load, GateOut:
 		for _, l := range d.Itinerary.Legs {
 			if l.LoadLocation == d.LastEvent.Activity.Location {
-				return HandlingActivity{Type: Unload, Location: l.UnloadLocation, VoyageNumber: l.VoyageNumber}
+				return l.UnloadActivity()
 			}
 		}
-	case Unload:
+	case Unload, GateIn:
 		for i, l := range d.Itinerary.Legs {
 			if l.UnloadLocation == d.LastEvent.Activity.Location {
 				if i < len(d.Itinerary.Legs)-1 {
-					return HandlingActivity{Type: Load, Location: d.Itinerary.Legs[i+1].LoadLocation, VoyageNumber: d.Itinerary.Legs[i+1].VoyageNumber}
+					return d.Itinerary.Legs[i+1].LoadActivity()
 				}
 
 				return HandlingActivity{Type: Claim, Location: l.UnloadLocation}
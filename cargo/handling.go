@@ -30,6 +30,8 @@ type HandlingActivity struct {
 type HandlingEvent struct {
 	TrackingID TrackingID
 	Activity   HandlingActivity
+	Registered time.Time
+	Completed  time.Time
 }
 
 // HandlingEventType describes type of a handling event.
@@ -43,6 +45,8 @@ const (
 	Receive
 	Claim
 	Customs
+	GateIn
+	GateOut
 )
 
 func (t HandlingEventType) String() string {
@@ -59,6 +63,10 @@ func (t HandlingEventType) String() string {
 		return "Claim"
 	case Customs:
 		return "Customs"
+	case GateIn:
+		return "GateIn"
+	case GateOut:
+		return "GateOut"
 	}
 
 	return ""
@@ -82,6 +90,27 @@ func (h HandlingHistory) MostRecentlyCompletedEvent() (HandlingEvent, error) {
 type HandlingEventRepository interface {
 	Store(e HandlingEvent)
 	QueryHandlingHistory(TrackingID) HandlingHistory
+
+	// QueryHandlingHistories returns the handling history of each of the
+	// cargos in one go.
+	QueryHandlingHistories([]TrackingID) map[TrackingID]HandlingHistory
+}
+
+// ErrModeMismatch is used when a cargo is handled in a way that does not
+// match the transport mode of the voyage, e.g. loaded onto a truck.
+var ErrModeMismatch = errors.New("handling does not match transport mode")
+
+// handledBy returns whether a cargo may be handled in the given way on the
+// voyage. Trucks pass through the gates of terminals instead of being loaded
+// and unloaded.
+func handledBy(t HandlingEventType, v *voyage.Voyage) bool {
+	switch t {
+	case Load, Unload:
+		return v == nil || v.Mode != voyage.Road
+	case GateIn, GateOut:
+		return v != nil && v.Mode == voyage.Road
+	}
+	return true
 }
 
 // HandlingEventFactory creates handling events.
@@ -99,13 +128,18 @@ func (f *HandlingEventFactory) CreateHandlingEvent(registered time.Time, complet
 		return HandlingEvent{}, err
 	}
 
-	if _, err := f.VoyageRepository.Find(voyageNumber); err != nil {
+	v, err := f.VoyageRepository.Find(voyageNumber)
+	if err != nil {
 		// TODO: This is pretty ugly, but when creating a Receive event, the voyage number is not known.
 		if len(voyageNumber) > 0 {
 			return HandlingEvent{}, err
 		}
 	}
 
+	if !handledBy(eventType, v) {
+		return HandlingEvent{}, ErrModeMismatch
+	}
+
 	if _, err := f.LocationRepository.Find(unLocode); err != nil {
 		return HandlingEvent{}, err
 	}
@@ -117,5 +151,7 @@ func (f *HandlingEventFactory) CreateHandlingEvent(registered time.Time, complet
 			Location:     unLocode,
 			VoyageNumber: voyageNumber,
 		},
+		Registered: registered,
+		Completed:  completed,
 	}, nil
 }
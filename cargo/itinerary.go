@@ -14,6 +14,7 @@ type Leg struct {
 	UnloadLocation location.UNLocode `json:"to"`
 	LoadTime       time.Time         `json:"load_time"`
 	UnloadTime     time.Time         `json:"unload_time"`
+	Mode           voyage.Mode       `json:"mode"`
 }
 
 // NewLeg creates a new itinerary leg.
@@ -27,6 +28,25 @@ func NewLeg(voyageNumber voyage.Number, loadLocation, unloadLocation location.UN
 	}
 }
 
+// LoadActivity returns the handling that starts the leg. Trucks pass
+// through the gate of the terminal rather than being loaded.
+func (l Leg) LoadActivity() HandlingActivity {
+	t := Load
+	if l.Mode == voyage.Road {
+		t = GateOut
+	}
+	return HandlingActivity{Type: t, Location: l.LoadLocation, VoyageNumber: l.VoyageNumber}
+}
+
+// UnloadActivity returns the handling that ends the leg.
+func (l Leg) UnloadActivity() HandlingActivity {
+	t := Unload
+	if l.Mode == voyage.Road {
+		t = GateIn
+	}
+	return HandlingActivity{Type: t, Location: l.UnloadLocation, VoyageNumber: l.VoyageNumber}
+}
+
 // Itinerary specifies steps required to transport a cargo from its origin to
 // destination.
 type Itinerary struct {
@@ -69,16 +89,16 @@ func (i Itinerary) IsExpected(event HandlingEvent) bool {
 	switch event.Activity.Type {
 	case Receive:
 		return i.InitialDepartureLocation() == event.Activity.Location
-	case Load:
+	case Load, GateOut:
 		for _, l := range i.Legs {
-			if l.LoadLocation == event.Activity.Location && l.VoyageNumber == event.Activity.VoyageNumber {
+			if l.LoadActivity() == event.Activity {
 				return true
 			}
 		}
 		return false
-	case Unload:
+	case Unload, GateIn:
 		for _, l := range i.Legs {
-			if l.UnloadLocation == event.Activity.Location && l.VoyageNumber == event.Activity.VoyageNumber {
+			if l.UnloadActivity() == event.Activity {
 				return true
 			}
 		}
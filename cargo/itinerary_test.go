@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/voyage"
 )
 
 func TestItinerary_CreateEmpty(t *testing.T) {
@@ -75,3 +76,61 @@ func TestItinerary_IsExpected(t *testing.T) {
 		}
 	}
 }
+
+func TestItinerary_IsExpected_ByTruck(t *testing.T) {
+	i := Itinerary{Legs: []Leg{
+		{
+			VoyageNumber:   "T100",
+			LoadLocation:   location.NLRTM,
+			UnloadLocation: location.DEHAM,
+			Mode:           voyage.Road,
+		},
+		{
+			VoyageNumber:   "V400",
+			LoadLocation:   location.DEHAM,
+			UnloadLocation: location.SESTO,
+		},
+	}}
+
+	tests := []struct {
+		act HandlingActivity
+		exp bool
+	}{
+		{HandlingActivity{Type: GateOut, Location: location.NLRTM, VoyageNumber: "T100"}, true},
+		{HandlingActivity{Type: Load, Location: location.NLRTM, VoyageNumber: "T100"}, false},
+		{HandlingActivity{Type: GateIn, Location: location.DEHAM, VoyageNumber: "T100"}, true},
+		{HandlingActivity{Type: Unload, Location: location.DEHAM, VoyageNumber: "T100"}, false},
+		{HandlingActivity{Type: Load, Location: location.DEHAM, VoyageNumber: "V400"}, true},
+		{HandlingActivity{Type: GateOut, Location: location.DEHAM, VoyageNumber: "V400"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := i.IsExpected(HandlingEvent{Activity: tt.act}); got != tt.exp {
+			t.Errorf("IsExpected(%v) = %v; want = %v", tt.act, got, tt.exp)
+		}
+	}
+
+	rs := RouteSpecification{Origin: location.NLRTM, Destination: location.SESTO}
+
+	history := HandlingHistory{HandlingEvents: []HandlingEvent{
+		{Activity: HandlingActivity{Type: Receive, Location: location.NLRTM}},
+	}}
+
+	d := DeriveDeliveryFrom(rs, i, history)
+	if want := (HandlingActivity{Type: GateOut, Location: location.NLRTM, VoyageNumber: "T100"}); d.NextExpectedActivity != want {
+		t.Errorf("NextExpectedActivity = %v; want = %v", d.NextExpectedActivity, want)
+	}
+
+	history.HandlingEvents = append(history.HandlingEvents,
+		HandlingEvent{Activity: HandlingActivity{Type: GateOut, Location: location.NLRTM, VoyageNumber: "T100"}},
+		HandlingEvent{Activity: HandlingActivity{Type: GateIn, Location: location.DEHAM, VoyageNumber: "T100"}},
+	)
+
+	d = DeriveDeliveryFrom(rs, i, history)
+	if want := (HandlingActivity{Type: Load, Location: location.DEHAM, VoyageNumber: "V400"}); d.NextExpectedActivity != want {
+		t.Errorf("NextExpectedActivity = %v; want = %v", d.NextExpectedActivity, want)
+	}
+	if d.TransportStatus != InPort {
+		t.Errorf("TransportStatus = %v; want = %v", d.TransportStatus, InPort)
+	}
+}
@@ -0,0 +1,64 @@
+package cargo
+
+import "github.com/marcusolsson/goddd/customer"
+
+// Role describes the part a customer plays in the transportation of a
+// cargo.
+type Role int
+
+// Valid roles.
+const (
+	Shipper Role = iota
+	Consignee
+	NotifyParty
+)
+
+func (r Role) String() string {
+	switch r {
+	case Shipper:
+		return "Shipper"
+	case Consignee:
+		return "Consignee"
+	case NotifyParty:
+		return "Notify party"
+	}
+	return ""
+}
+
+// Parties are the customers involved in the transportation of a cargo.
+type Parties struct {
+	Shipper       customer.ID
+	Consignee     customer.ID
+	NotifyParties []customer.ID
+}
+
+// Includes checks whether the customer has any role for the cargo.
+func (p Parties) Includes(id customer.ID) bool {
+	if p.Shipper == id || p.Consignee == id {
+		return true
+	}
+	for _, n := range p.NotifyParties {
+		if n == id {
+			return true
+		}
+	}
+	return false
+}
+
+// AttachParty assigns a customer to a role for this cargo. A cargo has a
+// single shipper and consignee but may have several notify parties.
+func (c *Cargo) AttachParty(role Role, id customer.ID) {
+	switch role {
+	case Shipper:
+		c.Parties.Shipper = id
+	case Consignee:
+		c.Parties.Consignee = id
+	case NotifyParty:
+		for _, n := range c.Parties.NotifyParties {
+			if n == id {
+				return
+			}
+		}
+		c.Parties.NotifyParties = append(c.Parties.NotifyParties, id)
+	}
+}
@@ -0,0 +1,219 @@
+package cargo
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/voyage"
+)
+
+// Default and maximum number of cargos returned by a single query.
+const (
+	DefaultQueryLimit = 50
+	MaxQueryLimit     = 500
+)
+
+// ErrInvalidCursor is used when a pagination cursor could not be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// SortField describes which attribute a cargo listing is ordered by.
+type SortField string
+
+// Valid sort fields.
+const (
+	SortByTrackingID      SortField = "tracking_id"
+	SortByOrigin          SortField = "origin"
+	SortByDestination     SortField = "destination"
+	SortByArrivalDeadline SortField = "arrival_deadline"
+)
+
+// IsValid checks whether the sort field is known.
+func (f SortField) IsValid() bool {
+	switch f {
+	case SortByTrackingID, SortByOrigin, SortByDestination, SortByArrivalDeadline:
+		return true
+	}
+	return false
+}
+
+// Key returns a value for the sort field that orders lexicographically in
+// the same way as the underlying attribute.
+func (f SortField) Key(c *Cargo) string {
+	switch f {
+	case SortByOrigin:
+		return string(c.Origin)
+	case SortByDestination:
+		return string(c.RouteSpecification.Destination)
+	case SortByArrivalDeadline:
+		return c.RouteSpecification.ArrivalDeadline.UTC().Format(sortableTime)
+	}
+	return string(c.TrackingID)
+}
+
+// sortableTime is a fixed-width time layout which orders the same way
+// lexicographically as chronologically.
+const sortableTime = "2006-01-02T15:04:05.000000000Z"
+
+// ParseSortableTime parses a key produced by SortByArrivalDeadline.
+func ParseSortableTime(s string) (time.Time, error) {
+	return time.Parse(sortableTime, s)
+}
+
+// Query describes a filtered, sorted and paginated listing of cargos. Zero
+// values are ignored when filtering.
+type Query struct {
+	Origin          location.UNLocode
+	Destination     location.UNLocode
+	RoutingStatus   *RoutingStatus
+	TransportStatus *TransportStatus
+	Misdirected     *bool
+	Claimed         *bool
+	DeadlineFrom    time.Time
+	DeadlineTo      time.Time
+	Voyage          voyage.Number
+	Customer        customer.ID
+
+	// Late selects cargos by whether they are expected to arrive after
+	// their arrival deadline.
+	Late *bool
+
+	// NeedsAttentionBy selects the cargos a dispatcher may need to look at
+	// by the given time: cargos that are not routed, misrouted or
+	// misdirected, and unclaimed cargos that are late or have an arrival
+	// deadline before then.
+	NeedsAttentionBy time.Time
+
+	SortBy     SortField
+	Descending bool
+
+	// Cursor is the opaque position returned with the previous page.
+	Cursor string
+
+	// Limit is the maximum number of cargos to return.
+	Limit int
+}
+
+// Normalize returns a copy of the query with defaults applied.
+func (q Query) Normalize() Query {
+	if q.SortBy == "" {
+		q.SortBy = SortByTrackingID
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultQueryLimit
+	}
+	if q.Limit > MaxQueryLimit {
+		q.Limit = MaxQueryLimit
+	}
+	return q
+}
+
+// Matches checks whether a cargo satisfies the filters of the query.
+func (q Query) Matches(c *Cargo) bool {
+	if q.Origin != "" && c.Origin != q.Origin {
+		return false
+	}
+	if q.Destination != "" && c.RouteSpecification.Destination != q.Destination {
+		return false
+	}
+	if q.RoutingStatus != nil && c.Delivery.RoutingStatus != *q.RoutingStatus {
+		return false
+	}
+	if q.TransportStatus != nil && c.Delivery.TransportStatus != *q.TransportStatus {
+		return false
+	}
+	if q.Misdirected != nil && c.Delivery.IsMisdirected != *q.Misdirected {
+		return false
+	}
+	if q.Claimed != nil && (c.Delivery.TransportStatus == Claimed) != *q.Claimed {
+		return false
+	}
+	if q.Late != nil && c.IsLate() != *q.Late {
+		return false
+	}
+	if !q.DeadlineFrom.IsZero() && c.RouteSpecification.ArrivalDeadline.Before(q.DeadlineFrom) {
+		return false
+	}
+	if !q.DeadlineTo.IsZero() && c.RouteSpecification.ArrivalDeadline.After(q.DeadlineTo) {
+		return false
+	}
+	if q.Customer != "" && !c.Parties.Includes(q.Customer) {
+		return false
+	}
+	if !q.NeedsAttentionBy.IsZero() && !c.needsAttention(q.NeedsAttentionBy) {
+		return false
+	}
+	if q.Voyage != "" {
+		for _, l := range c.Itinerary.Legs {
+			if l.VoyageNumber == q.Voyage {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (c *Cargo) needsAttention(by time.Time) bool {
+	d := c.Delivery
+	if d.RoutingStatus == NotRouted || d.RoutingStatus == Misrouted || d.IsMisdirected {
+		return true
+	}
+	if d.TransportStatus == Claimed {
+		return false
+	}
+	deadline := c.RouteSpecification.ArrivalDeadline
+	return !deadline.IsZero() && !deadline.After(by) || c.IsLate()
+}
+
+// Cursor is the decoded position of the last cargo of a page.
+type Cursor struct {
+	Key string     `json:"k"`
+	ID  TrackingID `json:"id"`
+}
+
+// After checks whether a cargo comes after the cursor in a listing sorted
+// by the given field.
+func (cur Cursor) After(c *Cargo, f SortField, desc bool) bool {
+	k := f.Key(c)
+	if k == cur.Key {
+		if desc {
+			return c.TrackingID < cur.ID
+		}
+		return c.TrackingID > cur.ID
+	}
+	if desc {
+		return k < cur.Key
+	}
+	return k > cur.Key
+}
+
+// NewCursor returns an opaque cursor positioned at the given cargo.
+func NewCursor(c *Cargo, f SortField) string {
+	b, _ := json.Marshal(Cursor{Key: f.Key(c), ID: c.TrackingID})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor decodes an opaque cursor.
+func DecodeCursor(s string) (Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var cur Cursor
+	if err := json.Unmarshal(b, &cur); err != nil || cur.ID == "" {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return cur, nil
+}
+
+// Page is a single page of a cargo listing.
+type Page struct {
+	Cargos []*Cargo
+
+	// NextCursor is empty when there are no more cargos.
+	NextCursor string
+}
@@ -0,0 +1,82 @@
+package cargo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marcusolsson/goddd/location"
+)
+
+func TestQueryMatches(t *testing.T) {
+	c := New("ABC", RouteSpecification{
+		Origin:          location.SESTO,
+		Destination:     location.AUMEL,
+		ArrivalDeadline: time.Date(2009, time.March, 13, 0, 0, 0, 0, time.UTC),
+	})
+	c.AssignToRoute(Itinerary{Legs: []Leg{
+		{VoyageNumber: "V100", LoadLocation: location.SESTO, UnloadLocation: location.AUMEL},
+	}})
+
+	c.Delivery.ETA = time.Date(2009, time.March, 14, 0, 0, 0, 0, time.UTC)
+
+	routed, notRouted := Routed, NotRouted
+	yes, no := true, false
+
+	tests := []struct {
+		q    Query
+		want bool
+	}{
+		{Query{}, true},
+		{Query{Origin: location.SESTO}, true},
+		{Query{Origin: location.AUMEL}, false},
+		{Query{Destination: location.AUMEL}, true},
+		{Query{RoutingStatus: &routed}, true},
+		{Query{RoutingStatus: &notRouted}, false},
+		{Query{Voyage: "V100"}, true},
+		{Query{Voyage: "V200"}, false},
+		{Query{DeadlineFrom: time.Date(2009, time.March, 1, 0, 0, 0, 0, time.UTC)}, true},
+		{Query{DeadlineTo: time.Date(2009, time.March, 1, 0, 0, 0, 0, time.UTC)}, false},
+		{Query{Claimed: &no}, true},
+		{Query{Claimed: &yes}, false},
+		{Query{Late: &yes}, true},
+		{Query{Late: &no}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.q.Matches(c); got != tt.want {
+			t.Errorf("%+v.Matches() = %v; want = %v", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+	var (
+		early = New("BBB", RouteSpecification{ArrivalDeadline: time.Date(2009, time.March, 1, 0, 0, 0, 0, time.UTC)})
+		late  = New("AAA", RouteSpecification{ArrivalDeadline: time.Date(2009, time.March, 2, 0, 0, 0, 0, time.UTC)})
+	)
+
+	cur, err := DecodeCursor(NewCursor(early, SortByArrivalDeadline))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cur.After(late, SortByArrivalDeadline, false) {
+		t.Errorf("late cargo should come after cursor")
+	}
+	if cur.After(late, SortByArrivalDeadline, true) {
+		t.Errorf("late cargo should not come after cursor when descending")
+	}
+
+	cur, err = DecodeCursor(NewCursor(early, SortByTrackingID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cur.After(late, SortByTrackingID, true) {
+		t.Errorf("AAA should come after BBB when descending")
+	}
+
+	if _, err := DecodeCursor("not a cursor"); err != ErrInvalidCursor {
+		t.Errorf("err = %v; want = %v", err, ErrInvalidCursor)
+	}
+}
@@ -0,0 +1,101 @@
+package cargo
+
+import (
+	"errors"
+	"strings"
+)
+
+// ReferenceType describes what an external reference to a cargo refers to.
+type ReferenceType int
+
+// Valid reference types.
+const (
+	CustomerReference ReferenceType = iota
+	ContainerNumber
+	BillOfLadingNumber
+)
+
+func (t ReferenceType) String() string {
+	switch t {
+	case CustomerReference:
+		return "Customer reference"
+	case ContainerNumber:
+		return "Container number"
+	case BillOfLadingNumber:
+		return "B/L number"
+	}
+	return ""
+}
+
+// Reference is an identifier other than the tracking ID by which a cargo is
+// known, such as the purchase order number of the consignee. Several cargos
+// may share a reference.
+type Reference struct {
+	Type  ReferenceType
+	Value string
+}
+
+// ErrInvalidReference is used when a reference is empty, or a container
+// number is malformed.
+var ErrInvalidReference = errors.New("invalid reference")
+
+// NormalizeReference returns the form in which references are stored and
+// looked up, so that they may be given in any case and with surrounding
+// spaces.
+func NormalizeReference(v string) string {
+	return strings.ToUpper(strings.TrimSpace(v))
+}
+
+// NewReference returns a normalized reference, or an error if the value is
+// not valid for the given type.
+func NewReference(typ ReferenceType, value string) (Reference, error) {
+	r := Reference{Type: typ, Value: NormalizeReference(value)}
+	if r.Value == "" {
+		return Reference{}, ErrInvalidReference
+	}
+	if typ == ContainerNumber && !validContainerNumber(r.Value) {
+		return Reference{}, ErrInvalidReference
+	}
+	return r, nil
+}
+
+// AddReference attaches a reference to this cargo. It returns false if the
+// cargo already carries the reference.
+func (c *Cargo) AddReference(r Reference) bool {
+	for _, ref := range c.References {
+		if ref == r {
+			return false
+		}
+	}
+	c.References = append(c.References, r)
+	return true
+}
+
+// validContainerNumber checks the owner code, serial number and check digit
+// of a container number, as specified by ISO 6346.
+func validContainerNumber(v string) bool {
+	if len(v) != 11 {
+		return false
+	}
+
+	var sum int
+	for i := 0; i < 10; i++ {
+		ch := v[i]
+
+		var n int
+		switch {
+		case i < 4 && ch >= 'A' && ch <= 'Z':
+			// Letters count from 10, skipping multiples of 11.
+			n = int(ch-'A') + 10
+			n += (n - 1) / 10
+		case i >= 4 && ch >= '0' && ch <= '9':
+			n = int(ch - '0')
+		default:
+			return false
+		}
+
+		sum += n << uint(i)
+	}
+
+	return v[10] == byte('0'+sum%11%10)
+}
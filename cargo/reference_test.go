@@ -0,0 +1,46 @@
+package cargo
+
+import "testing"
+
+func TestNewReference(t *testing.T) {
+	tests := []struct {
+		typ   ReferenceType
+		value string
+		want  string
+		err   error
+	}{
+		{CustomerReference, " po-4711 ", "PO-4711", nil},
+		{CustomerReference, "  ", "", ErrInvalidReference},
+		{ContainerNumber, "csqu3054383", "CSQU3054383", nil},
+		{ContainerNumber, "CSQU3054384", "", ErrInvalidReference},
+		{ContainerNumber, "CSQU305438", "", ErrInvalidReference},
+		{ContainerNumber, "C5QU3054383", "", ErrInvalidReference},
+	}
+
+	for _, tt := range tests {
+		r, err := NewReference(tt.typ, tt.value)
+		if err != tt.err {
+			t.Errorf("NewReference(%s, %q) err = %v; want = %v", tt.typ, tt.value, err, tt.err)
+			continue
+		}
+		if r.Value != tt.want {
+			t.Errorf("NewReference(%s, %q) = %q; want = %q", tt.typ, tt.value, r.Value, tt.want)
+		}
+	}
+}
+
+func TestAddReference(t *testing.T) {
+	c := New("ABC", RouteSpecification{})
+
+	r := Reference{Type: CustomerReference, Value: "PO-4711"}
+
+	if !c.AddReference(r) {
+		t.Errorf("AddReference() = false; want = true")
+	}
+	if c.AddReference(r) {
+		t.Errorf("AddReference() = true; want = false")
+	}
+	if len(c.References) != 1 {
+		t.Errorf("len(c.References) = %d; want = %d", len(c.References), 1)
+	}
+}
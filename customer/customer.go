@@ -0,0 +1,87 @@
+// Package customer provides the Customer aggregate.
+package customer
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+
+	"github.com/pborman/uuid"
+)
+
+// ID uniquely identifies a particular customer.
+type ID string
+
+// Customer is a party involved in the transportation of a cargo, such as
+// its shipper or consignee.
+type Customer struct {
+	ID      ID
+	Name    string
+	Address string
+	Email   string
+
+	// Channels are the channels the customer prefers to be notified over.
+	// Customers without a preference are notified over the default
+	// channels.
+	Channels []Channel
+}
+
+// New creates a new customer.
+func New(id ID, name, address, email string) *Customer {
+	return &Customer{
+		ID:      id,
+		Name:    name,
+		Address: address,
+		Email:   email,
+	}
+}
+
+// Channel is a means of notifying a customer.
+type Channel string
+
+// Supported notification channels.
+const (
+	Email   Channel = "email"
+	Webhook Channel = "webhook"
+	Log     Channel = "log"
+)
+
+// ErrUnknownChannel is used when a notification channel is not supported.
+var ErrUnknownChannel = errors.New("unknown notification channel")
+
+// ParseChannel returns the channel with the given name.
+func ParseChannel(s string) (Channel, error) {
+	switch c := Channel(strings.ToLower(s)); c {
+	case Email, Webhook, Log:
+		return c, nil
+	}
+	return "", ErrUnknownChannel
+}
+
+// ErrInvalidEmail is used when an email address could not be parsed.
+var ErrInvalidEmail = errors.New("invalid email address")
+
+// ParseEmail parses a single email address, returning it without any display
+// name, e.g. "shipping@acme.example" for "Acme <shipping@acme.example>".
+func ParseEmail(s string) (string, error) {
+	a, err := mail.ParseAddress(s)
+	if err != nil {
+		return "", ErrInvalidEmail
+	}
+	return a.Address, nil
+}
+
+// ErrUnknown is used when a customer could not be found.
+var ErrUnknown = errors.New("unknown customer")
+
+// Repository provides access a customer store.
+type Repository interface {
+	Store(c *Customer) error
+	Find(id ID) (*Customer, error)
+	FindAll() []*Customer
+}
+
+// NextID generates a new customer ID.
+func NextID() ID {
+	return ID(strings.ToUpper(uuid.New()))
+}
@@ -0,0 +1,106 @@
+package dispatching
+
+import (
+	"github.com/go-kit/kit/endpoint"
+	"golang.org/x/net/context"
+
+	"github.com/marcusolsson/goddd/cargo"
+)
+
+type queueRequest struct {
+	Query Query
+}
+
+type queueResponse struct {
+	Items      []Item `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Err        error  `json:"error,omitempty"`
+}
+
+func (r queueResponse) error() error { return r.Err }
+
+func makeQueueEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(queueRequest)
+		items, next, err := s.Queue(req.Query)
+		return queueResponse{Items: items, NextCursor: next, Err: err}, nil
+	}
+}
+
+type claimRequest struct {
+	ID         cargo.TrackingID
+	Dispatcher string
+}
+
+type claimResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r claimResponse) error() error { return r.Err }
+
+func makeClaimEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(claimRequest)
+		err := s.Claim(req.ID, req.Dispatcher)
+		return claimResponse{Err: err}, nil
+	}
+}
+
+type assignRequest struct {
+	ID         cargo.TrackingID
+	Dispatcher string
+}
+
+type assignResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r assignResponse) error() error { return r.Err }
+
+func makeAssignEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(assignRequest)
+		err := s.Assign(req.ID, req.Dispatcher)
+		return assignResponse{Err: err}, nil
+	}
+}
+
+type addNoteRequest struct {
+	ID     cargo.TrackingID
+	Author string
+	Text   string
+}
+
+type addNoteResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r addNoteResponse) error() error { return r.Err }
+
+func makeAddNoteEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(addNoteRequest)
+		err := s.AddNote(req.ID, req.Author, req.Text)
+		return addNoteResponse{Err: err}, nil
+	}
+}
+
+type resolveRequest struct {
+	ID         cargo.TrackingID
+	Dispatcher string
+	Resolution string
+}
+
+type resolveResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r resolveResponse) error() error { return r.Err }
+
+func makeResolveEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(resolveRequest)
+		err := s.Resolve(req.ID, req.Dispatcher, req.Resolution)
+		return resolveResponse{Err: err}, nil
+	}
+}
@@ -0,0 +1,74 @@
+package dispatching
+
+import (
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+
+	"github.com/marcusolsson/goddd/cargo"
+)
+
+type instrumentingService struct {
+	requestCount   metrics.Counter
+	requestLatency metrics.TimeHistogram
+	Service
+}
+
+// NewInstrumentingService returns an instance of an instrumenting Service.
+func NewInstrumentingService(counter metrics.Counter, latency metrics.TimeHistogram, s Service) Service {
+	return &instrumentingService{
+		requestCount:   counter,
+		requestLatency: latency,
+		Service:        s,
+	}
+}
+
+func (s *instrumentingService) Queue(q Query) ([]Item, string, error) {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "queue"}
+		s.requestCount.With(methodField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.Queue(q)
+}
+
+func (s *instrumentingService) Claim(id cargo.TrackingID, dispatcher string) error {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "claim"}
+		s.requestCount.With(methodField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.Claim(id, dispatcher)
+}
+
+func (s *instrumentingService) Assign(id cargo.TrackingID, dispatcher string) error {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "assign"}
+		s.requestCount.With(methodField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.Assign(id, dispatcher)
+}
+
+func (s *instrumentingService) AddNote(id cargo.TrackingID, author, text string) error {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "add_note"}
+		s.requestCount.With(methodField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.AddNote(id, author, text)
+}
+
+func (s *instrumentingService) Resolve(id cargo.TrackingID, dispatcher, resolution string) error {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "resolve"}
+		s.requestCount.With(methodField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.Resolve(id, dispatcher, resolution)
+}
@@ -0,0 +1,86 @@
+package dispatching
+
+import (
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/marcusolsson/goddd/cargo"
+)
+
+type loggingService struct {
+	logger log.Logger
+	Service
+}
+
+// NewLoggingService returns a new instance of a logging Service.
+func NewLoggingService(logger log.Logger, s Service) Service {
+	return &loggingService{logger, s}
+}
+
+func (s *loggingService) Queue(q Query) (items []Item, next string, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "queue",
+			"assignee", q.Assignee,
+			"include_resolved", q.IncludeResolved,
+			"limit", q.Limit,
+			"took", time.Since(begin),
+			"count", len(items),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.Queue(q)
+}
+
+func (s *loggingService) Claim(id cargo.TrackingID, dispatcher string) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "claim",
+			"tracking_id", id,
+			"dispatcher", dispatcher,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.Claim(id, dispatcher)
+}
+
+func (s *loggingService) Assign(id cargo.TrackingID, dispatcher string) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "assign",
+			"tracking_id", id,
+			"dispatcher", dispatcher,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.Assign(id, dispatcher)
+}
+
+func (s *loggingService) AddNote(id cargo.TrackingID, author, text string) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "add_note",
+			"tracking_id", id,
+			"author", author,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.AddNote(id, author, text)
+}
+
+func (s *loggingService) Resolve(id cargo.TrackingID, dispatcher, resolution string) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "resolve",
+			"tracking_id", id,
+			"dispatcher", dispatcher,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.Resolve(id, dispatcher, resolution)
+}
@@ -0,0 +1,301 @@
+// Package dispatching provides the use-case of following up on cargos that
+// need attention, such as misrouted cargos. Used by views facing a
+// dispatcher.
+package dispatching
+
+import (
+	"errors"
+	"time"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/task"
+)
+
+// ErrInvalidArgument is returned when one or more arguments are invalid.
+var ErrInvalidArgument = errors.New("invalid argument")
+
+// Service is the interface that provides dispatching methods.
+type Service interface {
+	// Queue returns a page of the cargos needing attention, most urgent
+	// first, along with a cursor to the next page. Since cargos are read a
+	// page at a time before being filtered, a page may hold fewer items
+	// than the limit even though more follow.
+	Queue(q Query) ([]Item, string, error)
+
+	// Claim assigns a cargo to the dispatcher, unless another dispatcher has
+	// already claimed it.
+	Claim(id cargo.TrackingID, dispatcher string) error
+
+	// Assign hands a cargo over to a dispatcher. An empty dispatcher returns
+	// the cargo to the queue.
+	Assign(id cargo.TrackingID, dispatcher string) error
+
+	// AddNote adds a note to a cargo in the queue.
+	AddNote(id cargo.TrackingID, author, text string) error
+
+	// Resolve removes a cargo from the queue until it needs attention for a
+	// new reason.
+	Resolve(id cargo.TrackingID, dispatcher, resolution string) error
+}
+
+// Query describes a page of the queue.
+type Query struct {
+	// Assignee selects the cargos claimed by a dispatcher.
+	Assignee string
+
+	// IncludeResolved includes the cargos that have been resolved.
+	IncludeResolved bool
+
+	// Cursor is the opaque position returned with the previous page.
+	Cursor string
+
+	// Limit is the maximum number of cargos to read.
+	Limit int
+}
+
+type service struct {
+	cargos  cargo.Repository
+	tasks   task.Repository
+	horizon time.Duration
+}
+
+func (s *service) Queue(q Query) ([]Item, string, error) {
+	now := time.Now()
+
+	page, err := s.cargos.Query(cargo.Query{
+		NeedsAttentionBy: now.Add(s.horizon),
+		SortBy:           cargo.SortByArrivalDeadline,
+		Cursor:           q.Cursor,
+		Limit:            q.Limit,
+	})
+	if err == cargo.ErrInvalidCursor {
+		return nil, "", ErrInvalidArgument
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	ids := make([]cargo.TrackingID, 0, len(page.Cargos))
+	for _, c := range page.Cargos {
+		ids = append(ids, c.TrackingID)
+	}
+
+	found, err := s.tasks.FindMany(ids)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tasks := make(map[cargo.TrackingID]*task.Task)
+	for _, t := range found {
+		tasks[t.TrackingID] = t
+	}
+
+	items := make([]Item, 0)
+	for _, c := range page.Cargos {
+		reasons := reasonsFor(c, now, s.horizon)
+		if len(reasons) == 0 {
+			continue
+		}
+
+		t, ok := tasks[c.TrackingID]
+		if !ok {
+			t = task.New(c.TrackingID)
+		}
+		if t.Status == task.Resolved && !t.Covers(reasons) {
+			t = reopened(t)
+		}
+		if t.Status == task.Resolved && !q.IncludeResolved {
+			continue
+		}
+		if q.Assignee != "" && t.Assignee != q.Assignee {
+			continue
+		}
+
+		items = append(items, assemble(c, t, reasons))
+	}
+
+	return items, page.NextCursor, nil
+}
+
+// reopened returns a copy of a resolved task as it is shown in the queue once
+// the cargo needs attention for a new reason. The stored task is left as is,
+// until a dispatcher picks it up again.
+func reopened(t *task.Task) *task.Task {
+	r := *t
+	r.Assign("")
+	return &r
+}
+
+func (s *service) Claim(id cargo.TrackingID, dispatcher string) error {
+	if id == "" || dispatcher == "" {
+		return ErrInvalidArgument
+	}
+
+	t, err := s.findTask(id)
+	if err != nil {
+		return err
+	}
+
+	if err := t.Claim(dispatcher); err != nil {
+		return err
+	}
+
+	return s.tasks.Store(t)
+}
+
+func (s *service) Assign(id cargo.TrackingID, dispatcher string) error {
+	if id == "" {
+		return ErrInvalidArgument
+	}
+
+	t, err := s.findTask(id)
+	if err != nil {
+		return err
+	}
+
+	t.Assign(dispatcher)
+
+	return s.tasks.Store(t)
+}
+
+func (s *service) AddNote(id cargo.TrackingID, author, text string) error {
+	if id == "" || author == "" || text == "" {
+		return ErrInvalidArgument
+	}
+
+	t, err := s.findTask(id)
+	if err != nil {
+		return err
+	}
+
+	t.AddNote(author, text, time.Now())
+
+	return s.tasks.Store(t)
+}
+
+func (s *service) Resolve(id cargo.TrackingID, dispatcher, resolution string) error {
+	if id == "" || dispatcher == "" || resolution == "" {
+		return ErrInvalidArgument
+	}
+
+	c, err := s.cargos.Find(id)
+	if err != nil {
+		return err
+	}
+
+	t, err := s.tasks.Find(id)
+	if err == task.ErrUnknown {
+		t = task.New(id)
+	} else if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	t.Resolve(dispatcher, resolution, reasonsFor(c, now, s.horizon), now)
+
+	return s.tasks.Store(t)
+}
+
+// findTask returns the task of a cargo, creating it if the cargo has not been
+// worked on before.
+func (s *service) findTask(id cargo.TrackingID) (*task.Task, error) {
+	if _, err := s.cargos.Find(id); err != nil {
+		return nil, err
+	}
+
+	t, err := s.tasks.Find(id)
+	if err == task.ErrUnknown {
+		return task.New(id), nil
+	}
+	return t, err
+}
+
+// reasonsFor returns the reasons a cargo needs attention. A cargo is at risk
+// of missing its deadline if the deadline falls within the horizon, or if the
+// cargo is expected to arrive after it, and it has neither been claimed nor
+// been unloaded at its destination in time.
+func reasonsFor(c *cargo.Cargo, now time.Time, horizon time.Duration) []task.Reason {
+	var reasons []task.Reason
+
+	switch c.Delivery.RoutingStatus {
+	case cargo.NotRouted:
+		reasons = append(reasons, task.NotRouted)
+	case cargo.Misrouted:
+		reasons = append(reasons, task.Misrouted)
+	}
+
+	if c.Delivery.IsMisdirected {
+		reasons = append(reasons, task.Misdirected)
+	}
+
+	deadline := c.RouteSpecification.ArrivalDeadline
+	arrived := c.Delivery.IsUnloadedAtDestination && !c.Delivery.LastEvent.Completed.After(deadline)
+
+	if !deadline.IsZero() && !arrived && c.Delivery.TransportStatus != cargo.Claimed {
+		if deadline.Before(now.Add(horizon)) || c.IsLate() {
+			reasons = append(reasons, task.DeadlineRisk)
+		}
+	}
+
+	return reasons
+}
+
+// NewService creates a dispatching service with necessary dependencies.
+// Cargos with a deadline within the horizon are considered at risk.
+func NewService(cargos cargo.Repository, tasks task.Repository, horizon time.Duration) Service {
+	return &service{
+		cargos:  cargos,
+		tasks:   tasks,
+		horizon: horizon,
+	}
+}
+
+// Item is a read model for the work queue.
+type Item struct {
+	TrackingID      string    `json:"tracking_id"`
+	Origin          string    `json:"origin"`
+	Destination     string    `json:"destination"`
+	ArrivalDeadline time.Time `json:"arrival_deadline"`
+	ETA             time.Time `json:"eta"`
+	Reasons         []string  `json:"reasons"`
+	Status          string    `json:"status"`
+	Assignee        string    `json:"assignee,omitempty"`
+	Notes           []Note    `json:"notes,omitempty"`
+	Resolution      string    `json:"resolution,omitempty"`
+}
+
+// Note is a read model for the work queue.
+type Note struct {
+	Author  string    `json:"author"`
+	Text    string    `json:"text"`
+	Created time.Time `json:"created"`
+}
+
+func assemble(c *cargo.Cargo, t *task.Task, reasons []task.Reason) Item {
+	var rs []string
+	for _, r := range reasons {
+		rs = append(rs, string(r))
+	}
+
+	var notes []Note
+	for _, n := range t.Notes {
+		notes = append(notes, Note{
+			Author:  n.Author,
+			Text:    n.Text,
+			Created: n.Created,
+		})
+	}
+
+	return Item{
+		TrackingID:      string(c.TrackingID),
+		Origin:          string(c.Origin),
+		Destination:     string(c.RouteSpecification.Destination),
+		ArrivalDeadline: c.RouteSpecification.ArrivalDeadline,
+		ETA:             c.Delivery.ETA,
+		Reasons:         rs,
+		Status:          t.Status.String(),
+		Assignee:        t.Assignee,
+		Notes:           notes,
+		Resolution:      t.Resolution,
+	}
+}
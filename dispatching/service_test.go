@@ -0,0 +1,261 @@
+package dispatching
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/inmem"
+	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/task"
+)
+
+func TestQueue(t *testing.T) {
+	cargos := inmem.NewCargoRepository()
+
+	var (
+		soon  = time.Now().Add(24 * time.Hour)
+		later = time.Now().Add(30 * 24 * time.Hour)
+	)
+
+	unrouted := cargo.New("AAA", cargo.RouteSpecification{
+		Origin:          location.SESTO,
+		Destination:     location.AUMEL,
+		ArrivalDeadline: later,
+	})
+	urgent := cargo.New("BBB", cargo.RouteSpecification{
+		Origin:          location.SESTO,
+		Destination:     location.AUMEL,
+		ArrivalDeadline: soon,
+	})
+	fine := cargo.New("CCC", cargo.RouteSpecification{
+		Origin:          location.SESTO,
+		Destination:     location.AUMEL,
+		ArrivalDeadline: later,
+	})
+	fine.Delivery.RoutingStatus = cargo.Routed
+	late := cargo.New("DDD", cargo.RouteSpecification{
+		Origin:          location.SESTO,
+		Destination:     location.AUMEL,
+		ArrivalDeadline: later,
+	})
+	late.Delivery.RoutingStatus = cargo.Routed
+	late.Delivery.ETA = later.Add(24 * time.Hour)
+	claimed := cargo.New("EEE", cargo.RouteSpecification{
+		Origin:          location.SESTO,
+		Destination:     location.AUMEL,
+		ArrivalDeadline: soon,
+	})
+	claimed.Delivery.RoutingStatus = cargo.Routed
+	claimed.Delivery.TransportStatus = cargo.Claimed
+
+	for _, c := range []*cargo.Cargo{unrouted, urgent, fine, late, claimed} {
+		if err := cargos.Store(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewService(cargos, inmem.NewTaskRepository(), 72*time.Hour)
+
+	items, _, err := s.Queue(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("len(items) = %d; want = %d", len(items), 3)
+	}
+	if items[0].TrackingID != "BBB" {
+		t.Errorf("items[0].TrackingID = %s; want = %s", items[0].TrackingID, "BBB")
+	}
+	if want := []string{"not_routed", "deadline_risk"}; !equal(items[0].Reasons, want) {
+		t.Errorf("items[0].Reasons = %v; want = %v", items[0].Reasons, want)
+	}
+	if items[2].TrackingID != "DDD" {
+		t.Errorf("items[2].TrackingID = %s; want = %s", items[2].TrackingID, "DDD")
+	}
+	if want := []string{"deadline_risk"}; !equal(items[2].Reasons, want) {
+		t.Errorf("items[2].Reasons = %v; want = %v", items[2].Reasons, want)
+	}
+
+	if err := s.Claim("AAA", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Claim("AAA", "bob"); err != task.ErrClaimed {
+		t.Errorf("err = %v; want = %v", err, task.ErrClaimed)
+	}
+	if err := s.AddNote("AAA", "alice", "waiting for schedule"); err != nil {
+		t.Fatal(err)
+	}
+
+	items, _, err = s.Queue(Query{Assignee: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("len(items) = %d; want = %d", len(items), 1)
+	}
+	if items[0].Status != "Claimed" {
+		t.Errorf("items[0].Status = %s; want = %s", items[0].Status, "Claimed")
+	}
+	if len(items[0].Notes) != 1 {
+		t.Errorf("len(items[0].Notes) = %d; want = %d", len(items[0].Notes), 1)
+	}
+
+	if err := s.Claim("ZZZ", "alice"); err != cargo.ErrUnknown {
+		t.Errorf("err = %v; want = %v", err, cargo.ErrUnknown)
+	}
+}
+
+func TestQueuePages(t *testing.T) {
+	cargos := inmem.NewCargoRepository()
+
+	deadline := time.Now().Add(24 * time.Hour)
+
+	// Only the cargos with an even index need attention.
+	for i, id := range []cargo.TrackingID{"AAA", "BBB", "CCC", "DDD", "EEE"} {
+		c := cargo.New(id, cargo.RouteSpecification{
+			Origin:          location.SESTO,
+			Destination:     location.AUMEL,
+			ArrivalDeadline: deadline.Add(time.Duration(i) * time.Hour),
+		})
+		if i%2 == 1 {
+			c.Delivery.RoutingStatus = cargo.Routed
+			c.Delivery.TransportStatus = cargo.Claimed
+		}
+		if err := cargos.Store(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewService(cargos, inmem.NewTaskRepository(), 72*time.Hour)
+
+	var (
+		got  []string
+		next string
+	)
+	for {
+		items, cursor, err := s.Queue(Query{Cursor: next, Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, it := range items {
+			got = append(got, it.TrackingID)
+		}
+		if cursor == "" {
+			break
+		}
+		next = cursor
+	}
+
+	if want := []string{"AAA", "CCC", "EEE"}; !equal(got, want) {
+		t.Errorf("got = %v; want = %v", got, want)
+	}
+
+	if _, _, err := s.Queue(Query{Cursor: "invalid"}); err != ErrInvalidArgument {
+		t.Errorf("err = %v; want = %v", err, ErrInvalidArgument)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	cargos := inmem.NewCargoRepository()
+
+	c := cargo.New("AAA", cargo.RouteSpecification{
+		Origin:          location.SESTO,
+		Destination:     location.AUMEL,
+		ArrivalDeadline: time.Now().Add(30 * 24 * time.Hour),
+	})
+	if err := cargos.Store(c); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks := inmem.NewTaskRepository()
+
+	s := NewService(cargos, tasks, 72*time.Hour)
+
+	if err := s.Resolve("AAA", "alice", "routed manually"); err != nil {
+		t.Fatal(err)
+	}
+
+	if items, _, _ := s.Queue(Query{}); len(items) != 0 {
+		t.Errorf("len(items) = %d; want = %d", len(items), 0)
+	}
+	if items, _, _ := s.Queue(Query{IncludeResolved: true}); len(items) != 1 {
+		t.Errorf("len(items) = %d; want = %d", len(items), 1)
+	}
+
+	// The cargo reappears once it needs attention for a new reason.
+	c.Delivery.IsMisdirected = true
+
+	items, _, err := s.Queue(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("len(items) = %d; want = %d", len(items), 1)
+	}
+	if items[0].Status != "Open" {
+		t.Errorf("items[0].Status = %s; want = %s", items[0].Status, "Open")
+	}
+
+	// Listing the queue does not change the task.
+	if tk, _ := tasks.Find("AAA"); tk.Status != task.Resolved || tk.Assignee != "alice" {
+		t.Errorf("Status = %s, Assignee = %s; want = Resolved, alice", tk.Status, tk.Assignee)
+	}
+}
+
+func TestQueueArrived(t *testing.T) {
+	cargos := inmem.NewCargoRepository()
+
+	deadline := time.Now().Add(24 * time.Hour)
+
+	onTime := cargo.New("AAA", cargo.RouteSpecification{
+		Origin:          location.SESTO,
+		Destination:     location.AUMEL,
+		ArrivalDeadline: deadline,
+	})
+	tooLate := cargo.New("BBB", cargo.RouteSpecification{
+		Origin:          location.SESTO,
+		Destination:     location.AUMEL,
+		ArrivalDeadline: deadline,
+	})
+	for i, c := range []*cargo.Cargo{onTime, tooLate} {
+		c.Delivery.RoutingStatus = cargo.Routed
+		c.Delivery.IsUnloadedAtDestination = true
+		c.Delivery.LastEvent = cargo.HandlingEvent{
+			TrackingID: c.TrackingID,
+			Activity: cargo.HandlingActivity{
+				Type:     cargo.Unload,
+				Location: location.AUMEL,
+			},
+			Completed: deadline.Add(time.Duration(2*i-1) * time.Hour),
+		}
+		if err := cargos.Store(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewService(cargos, inmem.NewTaskRepository(), 72*time.Hour)
+
+	items, _, err := s.Queue(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("len(items) = %d; want = %d", len(items), 1)
+	}
+	if items[0].TrackingID != "BBB" {
+		t.Errorf("items[0].TrackingID = %s; want = %s", items[0].TrackingID, "BBB")
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
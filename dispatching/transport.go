@@ -0,0 +1,223 @@
+package dispatching
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	kitlog "github.com/go-kit/kit/log"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"golang.org/x/net/context"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/task"
+)
+
+// MakeHandler returns a handler for the dispatching service.
+func MakeHandler(ctx context.Context, ds Service, logger kitlog.Logger) http.Handler {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorLogger(logger),
+		kithttp.ServerErrorEncoder(encodeError),
+	}
+
+	queueHandler := kithttp.NewServer(
+		ctx,
+		makeQueueEndpoint(ds),
+		decodeQueueRequest,
+		encodeResponse,
+		opts...,
+	)
+	claimHandler := kithttp.NewServer(
+		ctx,
+		makeClaimEndpoint(ds),
+		decodeClaimRequest,
+		encodeResponse,
+		opts...,
+	)
+	assignHandler := kithttp.NewServer(
+		ctx,
+		makeAssignEndpoint(ds),
+		decodeAssignRequest,
+		encodeResponse,
+		opts...,
+	)
+	addNoteHandler := kithttp.NewServer(
+		ctx,
+		makeAddNoteEndpoint(ds),
+		decodeAddNoteRequest,
+		encodeResponse,
+		opts...,
+	)
+	resolveHandler := kithttp.NewServer(
+		ctx,
+		makeResolveEndpoint(ds),
+		decodeResolveRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	r := mux.NewRouter()
+
+	r.Handle("/dispatching/v1/queue", queueHandler).Methods("GET")
+	r.Handle("/dispatching/v1/queue/{id}/claim", claimHandler).Methods("POST")
+	r.Handle("/dispatching/v1/queue/{id}/assign", assignHandler).Methods("POST")
+	r.Handle("/dispatching/v1/queue/{id}/notes", addNoteHandler).Methods("POST")
+	r.Handle("/dispatching/v1/queue/{id}/resolve", resolveHandler).Methods("POST")
+	r.Handle("/dispatching/v1/docs", http.StripPrefix("/dispatching/v1/docs", http.FileServer(http.Dir("dispatching/docs"))))
+
+	return r
+}
+
+var errBadRoute = errors.New("bad route")
+
+func decodeQueueRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vals := r.URL.Query()
+
+	q := Query{
+		Assignee: vals.Get("assignee"),
+		Cursor:   vals.Get("cursor"),
+	}
+
+	if v := vals.Get("include_resolved"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, ErrInvalidArgument
+		}
+		q.IncludeResolved = b
+	}
+
+	if v := vals.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, ErrInvalidArgument
+		}
+		q.Limit = n
+	}
+
+	return queueRequest{Query: q}, nil
+}
+
+func decodeClaimRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+
+	var body struct {
+		Dispatcher string `json:"dispatcher"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	return claimRequest{
+		ID:         cargo.TrackingID(id),
+		Dispatcher: body.Dispatcher,
+	}, nil
+}
+
+func decodeAssignRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+
+	var body struct {
+		Dispatcher string `json:"dispatcher"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	return assignRequest{
+		ID:         cargo.TrackingID(id),
+		Dispatcher: body.Dispatcher,
+	}, nil
+}
+
+func decodeAddNoteRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+
+	var body struct {
+		Author string `json:"author"`
+		Text   string `json:"text"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	return addNoteRequest{
+		ID:     cargo.TrackingID(id),
+		Author: body.Author,
+		Text:   body.Text,
+	}, nil
+}
+
+func decodeResolveRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+
+	var body struct {
+		Dispatcher string `json:"dispatcher"`
+		Resolution string `json:"resolution"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	return resolveRequest{
+		ID:         cargo.TrackingID(id),
+		Dispatcher: body.Dispatcher,
+		Resolution: body.Resolution,
+	}, nil
+}
+
+func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if e, ok := response.(errorer); ok && e.error() != nil {
+		encodeError(ctx, e.error(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(w).Encode(response)
+}
+
+type errorer interface {
+	error() error
+}
+
+// encode errors from business-logic
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+	if e, ok := err.(kithttp.Error); ok && e.Domain == kithttp.DomainDecode {
+		err = e.Err
+	}
+
+	switch err {
+	case cargo.ErrUnknown:
+		w.WriteHeader(http.StatusNotFound)
+	case ErrInvalidArgument:
+		w.WriteHeader(http.StatusBadRequest)
+	case task.ErrClaimed:
+		w.WriteHeader(http.StatusConflict)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,108 @@
+// Package document provides the BillOfLading aggregate, i.e. the transport
+// document issued to the shipper once a cargo has been routed.
+package document
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/pborman/uuid"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/voyage"
+)
+
+// Number uniquely identifies the bill of lading of a cargo. The number stays
+// the same across versions.
+type Number string
+
+// BillOfLading is a snapshot of the contract of carriage of a cargo. A new
+// version is issued every time the cargo is assigned to a new route.
+type BillOfLading struct {
+	Number     Number
+	Version    int
+	TrackingID cargo.TrackingID
+	Issued     time.Time
+
+	Shipper       Party
+	Consignee     Party
+	NotifyParties []Party
+
+	Origin          Place
+	Destination     Place
+	ArrivalDeadline time.Time
+	Measurement     cargo.Measurement
+	Legs            []Leg
+}
+
+// Party is a customer named on a bill of lading.
+type Party struct {
+	ID      customer.ID
+	Name    string
+	Address string
+}
+
+// Place is a location named on a bill of lading.
+type Place struct {
+	UNLocode location.UNLocode
+	Name     string
+}
+
+// Leg is a voyage named on a bill of lading.
+type Leg struct {
+	VoyageNumber voyage.Number
+	Carrier      string
+	Mode         voyage.Mode
+	From         Place
+	To           Place
+	Departure    time.Time
+	Arrival      time.Time
+}
+
+// Itinerary returns the itinerary the bill of lading was issued for.
+func (b *BillOfLading) Itinerary() cargo.Itinerary {
+	var legs []cargo.Leg
+	for _, l := range b.Legs {
+		legs = append(legs, cargo.Leg{
+			VoyageNumber:   l.VoyageNumber,
+			LoadLocation:   l.From.UNLocode,
+			UnloadLocation: l.To.UNLocode,
+			LoadTime:       l.Departure,
+			UnloadTime:     l.Arrival,
+		})
+	}
+	return cargo.Itinerary{Legs: legs}
+}
+
+// ErrUnknown is used when a bill of lading could not be found.
+var ErrUnknown = errors.New("unknown document")
+
+// ErrNotRouted is used when issuing a bill of lading for a cargo that has not
+// been routed.
+var ErrNotRouted = errors.New("cargo has not been routed")
+
+// ErrConflict is used when storing a version of a bill of lading that has
+// already been issued.
+var ErrConflict = errors.New("document version already issued")
+
+// Repository provides access a bill of lading store.
+type Repository interface {
+	// Store adds a new version of a bill of lading. Versions are never
+	// replaced, and storing one that already exists fails with ErrConflict.
+	Store(b *BillOfLading) error
+
+	// Find returns a version of the bill of lading of a cargo.
+	Find(id cargo.TrackingID, version int) (*BillOfLading, error)
+
+	// FindByCargo returns all versions of the bill of lading of a cargo,
+	// oldest first.
+	FindByCargo(id cargo.TrackingID) []*BillOfLading
+}
+
+// NextNumber generates a new bill of lading number.
+func NextNumber() Number {
+	return Number("BL" + strings.Split(strings.ToUpper(uuid.New()), "-")[0])
+}
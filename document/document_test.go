@@ -0,0 +1,225 @@
+package document
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/voyage"
+)
+
+func TestIssue(t *testing.T) {
+	i := Issuer{
+		DocumentRepository: &stubDocumentRepository{},
+		LocationRepository: stubLocationRepository{},
+		VoyageRepository:   stubVoyageRepository{},
+		CustomerRepository: stubCustomerRepository{},
+	}
+
+	c := cargo.New("ABC", cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.AUMEL,
+	})
+	c.AttachParty(cargo.Shipper, "ACME")
+
+	if _, err := i.Issue(c); err != ErrNotRouted {
+		t.Errorf("err = %v; want = %v", err, ErrNotRouted)
+	}
+
+	departure := time.Date(2015, time.November, 1, 12, 0, 0, 0, time.UTC)
+
+	c.AssignToRoute(cargo.Itinerary{Legs: []cargo.Leg{
+		cargo.NewLeg("V100", location.SESTO, location.AUMEL, departure, departure.Add(72*time.Hour)),
+	}})
+
+	first, err := i.Issue(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Version != 1 {
+		t.Errorf("first.Version = %d; want = %d", first.Version, 1)
+	}
+	if first.Shipper.Name != "Acme Corp" {
+		t.Errorf("first.Shipper.Name = %s; want = %s", first.Shipper.Name, "Acme Corp")
+	}
+	if l := first.Legs[0]; l.Carrier != "Baltic Line" || l.Mode != voyage.Barge {
+		t.Errorf("Carrier = %s, Mode = %s; want = Baltic Line, Barge", l.Carrier, l.Mode)
+	}
+
+	same, err := i.Issue(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same != first {
+		t.Errorf("unchanged route should not issue a new version")
+	}
+
+	c.AssignToRoute(cargo.Itinerary{Legs: []cargo.Leg{
+		cargo.NewLeg("V300", location.SESTO, location.AUMEL, departure, departure.Add(96*time.Hour)),
+	}})
+
+	second, err := i.Issue(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Version != 2 {
+		t.Errorf("second.Version = %d; want = %d", second.Version, 2)
+	}
+	if second.Number != first.Number {
+		t.Errorf("second.Number = %s; want = %s", second.Number, first.Number)
+	}
+}
+
+func TestIssueConcurrently(t *testing.T) {
+	departure := time.Date(2015, time.November, 1, 12, 0, 0, 0, time.UTC)
+
+	docs := &stubDocumentRepository{
+		concurrent: &BillOfLading{
+			Number:     "BL1234",
+			Version:    1,
+			TrackingID: "ABC",
+			Legs:       []Leg{{VoyageNumber: "V300"}},
+		},
+	}
+
+	i := Issuer{
+		DocumentRepository: docs,
+		LocationRepository: stubLocationRepository{},
+		VoyageRepository:   stubVoyageRepository{},
+		CustomerRepository: stubCustomerRepository{},
+	}
+
+	c := cargo.New("ABC", cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.AUMEL,
+	})
+	c.AssignToRoute(cargo.Itinerary{Legs: []cargo.Leg{
+		cargo.NewLeg("V100", location.SESTO, location.AUMEL, departure, departure.Add(72*time.Hour)),
+	}})
+
+	b, err := i.Issue(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Version != 2 || b.Number != "BL1234" {
+		t.Errorf("b.Version = %d, b.Number = %s; want = 2, BL1234", b.Version, b.Number)
+	}
+	if len(docs.docs) != 2 {
+		t.Errorf("len(docs) = %d; want = 2", len(docs.docs))
+	}
+}
+
+func TestRenderText(t *testing.T) {
+	b := &BillOfLading{
+		Number:     "BL1234",
+		Version:    2,
+		TrackingID: "ABC",
+		Shipper:    Party{ID: "ACME", Name: "Acme Corp"},
+		Origin:     Place{UNLocode: location.SESTO, Name: "Stockholm"},
+		Legs: []Leg{
+			{VoyageNumber: "V100", Carrier: "Baltic Line", Mode: voyage.Barge, From: Place{UNLocode: location.SESTO}, To: Place{UNLocode: location.AUMEL}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := RenderText(&buf, b); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"BL1234", "Acme Corp (ACME)", "Stockholm (SESTO)", "V100", "Barge", "Baltic Line"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("rendered text is missing %q", want)
+		}
+	}
+
+	buf.Reset()
+	if err := RenderHTML(&buf, b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "<title>Bill of lading BL1234</title>") {
+		t.Errorf("rendered HTML is missing title")
+	}
+}
+
+type stubDocumentRepository struct {
+	docs []*BillOfLading
+
+	// concurrent is stored just before the next version, as if issued at
+	// the same time by someone else.
+	concurrent *BillOfLading
+}
+
+func (r *stubDocumentRepository) Store(b *BillOfLading) error {
+	if r.concurrent != nil {
+		r.docs, r.concurrent = append(r.docs, r.concurrent), nil
+	}
+	for _, d := range r.docs {
+		if d.Version == b.Version {
+			return ErrConflict
+		}
+	}
+	r.docs = append(r.docs, b)
+	return nil
+}
+
+func (r *stubDocumentRepository) Find(id cargo.TrackingID, version int) (*BillOfLading, error) {
+	for _, b := range r.docs {
+		if b.Version == version {
+			return b, nil
+		}
+	}
+	return nil, ErrUnknown
+}
+
+func (r *stubDocumentRepository) FindByCargo(id cargo.TrackingID) []*BillOfLading {
+	return r.docs
+}
+
+type stubLocationRepository struct{}
+
+func (r stubLocationRepository) Find(code location.UNLocode) (*location.Location, error) {
+	return &location.Location{UNLocode: code, Name: "Stockholm"}, nil
+}
+
+func (r stubLocationRepository) FindAll() []*location.Location {
+	return nil
+}
+
+type stubVoyageRepository struct{}
+
+func (r stubVoyageRepository) Find(n voyage.Number) (*voyage.Voyage, error) {
+	v := voyage.New(n, voyage.Schedule{})
+	v.Carrier = "Baltic Line"
+	v.Mode = voyage.Barge
+	return v, nil
+}
+
+func (r stubVoyageRepository) FindMany(numbers []voyage.Number) ([]*voyage.Voyage, error) {
+	var voyages []*voyage.Voyage
+	for _, n := range numbers {
+		voyages = append(voyages, voyage.New(n, voyage.Schedule{}))
+	}
+	return voyages, nil
+}
+
+func (r stubVoyageRepository) FindAll() []*voyage.Voyage {
+	return nil
+}
+
+type stubCustomerRepository struct{}
+
+func (r stubCustomerRepository) Store(c *customer.Customer) error {
+	return nil
+}
+
+func (r stubCustomerRepository) Find(id customer.ID) (*customer.Customer, error) {
+	return customer.New(id, "Acme Corp", "Storgatan 1", ""), nil
+}
+
+func (r stubCustomerRepository) FindAll() []*customer.Customer {
+	return nil
+}
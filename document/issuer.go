@@ -0,0 +1,151 @@
+package document
+
+import (
+	"time"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/voyage"
+)
+
+// Issuer issues bills of lading, filling in the names of the locations and
+// parties involved, and the carrier and mode of each voyage.
+type Issuer struct {
+	DocumentRepository Repository
+	LocationRepository location.Repository
+	VoyageRepository   voyage.Repository
+	CustomerRepository customer.Repository
+}
+
+// maxIssueAttempts is how many times issuing is tried when another version is
+// issued concurrently.
+const maxIssueAttempts = 3
+
+// Issue returns the current bill of lading of a routed cargo. A new version
+// is issued if the cargo has been assigned to a different route since the
+// previous one.
+func (i *Issuer) Issue(c *cargo.Cargo) (*BillOfLading, error) {
+	if c.Itinerary.IsEmpty() {
+		return nil, ErrNotRouted
+	}
+
+	var (
+		b   *BillOfLading
+		err error
+	)
+	for n := 0; n < maxIssueAttempts; n++ {
+		// Another issuer may just have issued the same version, in which
+		// case it is read again before deciding on the next one.
+		if b, err = i.tryIssue(c); err != ErrConflict {
+			break
+		}
+	}
+	return b, err
+}
+
+func (i *Issuer) tryIssue(c *cargo.Cargo) (*BillOfLading, error) {
+	var (
+		number  = NextNumber()
+		version = 1
+	)
+
+	if prev := i.DocumentRepository.FindByCargo(c.TrackingID); len(prev) > 0 {
+		latest := prev[len(prev)-1]
+		if sameItinerary(latest.Itinerary(), c.Itinerary) {
+			return latest, nil
+		}
+		number = latest.Number
+		version = latest.Version + 1
+	}
+
+	var legs []Leg
+	for _, l := range c.Itinerary.Legs {
+		v, err := i.VoyageRepository.Find(l.VoyageNumber)
+		if err != nil {
+			return nil, err
+		}
+		legs = append(legs, Leg{
+			VoyageNumber: l.VoyageNumber,
+			Carrier:      v.Carrier,
+			Mode:         v.Mode,
+			From:         i.place(l.LoadLocation),
+			To:           i.place(l.UnloadLocation),
+			Departure:    l.LoadTime,
+			Arrival:      l.UnloadTime,
+		})
+	}
+
+	var notify []Party
+	for _, id := range c.Parties.NotifyParties {
+		notify = append(notify, i.party(id))
+	}
+
+	b := &BillOfLading{
+		Number:          number,
+		Version:         version,
+		TrackingID:      c.TrackingID,
+		Issued:          time.Now(),
+		Shipper:         i.party(c.Parties.Shipper),
+		Consignee:       i.party(c.Parties.Consignee),
+		NotifyParties:   notify,
+		Origin:          i.place(c.Origin),
+		Destination:     i.place(c.RouteSpecification.Destination),
+		ArrivalDeadline: c.RouteSpecification.ArrivalDeadline,
+		Measurement:     c.Measurement,
+		Legs:            legs,
+	}
+
+	if err := i.DocumentRepository.Store(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// place looks up the name of a location. Unknown locations are printed by
+// their UN locode only.
+func (i *Issuer) place(code location.UNLocode) Place {
+	p := Place{UNLocode: code}
+	if l, err := i.LocationRepository.Find(code); err == nil {
+		p.Name = l.Name
+	}
+	return p
+}
+
+// party looks up the name and address of a customer.
+func (i *Issuer) party(id customer.ID) Party {
+	p := Party{ID: id}
+	if id == "" {
+		return p
+	}
+	if c, err := i.CustomerRepository.Find(id); err == nil {
+		p.Name = c.Name
+		p.Address = c.Address
+	}
+	return p
+}
+
+// sameItinerary compares itineraries, allowing for times having lost
+// precision in storage.
+func sameItinerary(a, b cargo.Itinerary) bool {
+	if len(a.Legs) != len(b.Legs) {
+		return false
+	}
+	for n := range a.Legs {
+		x, y := a.Legs[n], b.Legs[n]
+		if x.VoyageNumber != y.VoyageNumber ||
+			x.LoadLocation != y.LoadLocation ||
+			x.UnloadLocation != y.UnloadLocation ||
+			!sameTime(x.LoadTime, y.LoadTime) ||
+			!sameTime(x.UnloadTime, y.UnloadTime) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameTime(a, b time.Time) bool {
+	d := a.Sub(b)
+	return d > -time.Millisecond && d < time.Millisecond
+}
@@ -0,0 +1,96 @@
+package document
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"text/template"
+	"time"
+)
+
+// RenderHTML writes the bill of lading as a printable HTML page.
+func RenderHTML(w io.Writer, b *BillOfLading) error {
+	return htmlTemplate.Execute(w, b)
+}
+
+// RenderText writes the bill of lading as fixed-width plain text, suitable
+// for printing.
+func RenderText(w io.Writer, b *BillOfLading) error {
+	return textTemplate.Execute(w, b)
+}
+
+var funcs = map[string]interface{}{
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.UTC().Format("2006-01-02 15:04 MST")
+	},
+	"place": func(p Place) string {
+		if p.Name == "" {
+			return string(p.UNLocode)
+		}
+		return fmt.Sprintf("%s (%s)", p.Name, p.UNLocode)
+	},
+	"party": func(p Party) string {
+		switch {
+		case p.ID == "":
+			return "-"
+		case p.Address == "":
+			return fmt.Sprintf("%s (%s)", p.Name, p.ID)
+		}
+		return fmt.Sprintf("%s, %s (%s)", p.Name, p.Address, p.ID)
+	},
+}
+
+var textTemplate = template.Must(template.New("text").Funcs(funcs).Parse(`BILL OF LADING
+==============
+
+B/L number:  {{.Number}}
+Version:     {{.Version}}
+Issued:      {{date .Issued}}
+Tracking ID: {{.TrackingID}}
+
+Shipper:     {{party .Shipper}}
+Consignee:   {{party .Consignee}}
+{{range .NotifyParties}}Notify:      {{party .}}
+{{end}}
+Origin:      {{place .Origin}}
+Destination: {{place .Destination}}
+Deadline:    {{date .ArrivalDeadline}}
+Measurement: {{.Measurement.TEU}} TEU, {{printf "%.0f" .Measurement.Weight}} kg
+
+{{printf "%-8s %-6s %-16s %-24s %-24s %-20s %-20s" "Voyage" "Mode" "Carrier" "From" "To" "Departure" "Arrival"}}
+{{range .Legs}}{{printf "%-8s" .VoyageNumber}} {{printf "%-6s" .Mode}} {{printf "%-16s" (or .Carrier "-")}} {{printf "%-24s" (place .From)}} {{printf "%-24s" (place .To)}} {{printf "%-20s" (date .Departure)}} {{printf "%-20s" (date .Arrival)}}
+{{end}}`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Bill of lading {{.Number}}</title>
+<style>
+body { font-family: serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #000; padding: 0.3em; text-align: left; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>Bill of lading</h1>
+<table>
+<tr><th>B/L number</th><td>{{.Number}}</td><th>Version</th><td>{{.Version}}</td></tr>
+<tr><th>Tracking ID</th><td>{{.TrackingID}}</td><th>Issued</th><td>{{date .Issued}}</td></tr>
+<tr><th>Shipper</th><td colspan="3">{{party .Shipper}}</td></tr>
+<tr><th>Consignee</th><td colspan="3">{{party .Consignee}}</td></tr>
+{{range .NotifyParties}}<tr><th>Notify party</th><td colspan="3">{{party .}}</td></tr>
+{{end}}<tr><th>Origin</th><td>{{place .Origin}}</td><th>Destination</th><td>{{place .Destination}}</td></tr>
+<tr><th>Arrival deadline</th><td>{{date .ArrivalDeadline}}</td><th>Measurement</th><td>{{.Measurement.TEU}} TEU, {{printf "%.0f" .Measurement.Weight}} kg</td></tr>
+</table>
+<h2>Route</h2>
+<table>
+<tr><th>Voyage</th><th>Mode</th><th>Carrier</th><th>From</th><th>To</th><th>Departure</th><th>Arrival</th></tr>
+{{range .Legs}}<tr><td>{{.VoyageNumber}}</td><td>{{.Mode}}</td><td>{{or .Carrier "-"}}</td><td>{{place .From}}</td><td>{{place .To}}</td><td>{{date .Departure}}</td><td>{{date .Arrival}}</td></tr>
+{{end}}</table>
+</body>
+</html>`))
@@ -0,0 +1,105 @@
+// Package eventbus provides an in-process bus for telling interested parties
+// that a cargo has changed, e.g. to push updates to end-users.
+package eventbus
+
+import (
+	"sync"
+
+	"github.com/marcusolsson/goddd/cargo"
+)
+
+// Publisher is the interface that wraps the Publish method.
+type Publisher interface {
+	// Publish tells subscribers that the cargo has changed.
+	Publish(id cargo.TrackingID)
+}
+
+// Bus tells subscribers of a cargo whenever the cargo has been published.
+// Each publication is given a sequence number, which subscribers may use to
+// find out whether they have missed anything.
+type Bus struct {
+	mtx  sync.Mutex
+	seq  uint64
+	last map[cargo.TrackingID]uint64
+	subs map[cargo.TrackingID]map[*Subscription]struct{}
+}
+
+// New returns a new bus.
+func New() *Bus {
+	return &Bus{
+		last: make(map[cargo.TrackingID]uint64),
+		subs: make(map[cargo.TrackingID]map[*Subscription]struct{}),
+	}
+}
+
+// Publish tells the subscribers of the cargo that it has changed. It never
+// blocks on slow subscribers; publications that happen before a subscriber
+// has caught up are coalesced into one.
+func (b *Bus) Publish(id cargo.TrackingID) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.seq++
+	b.last[id] = b.seq
+
+	for s := range b.subs[id] {
+		select {
+		case s.c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Last returns the sequence number of the latest publication of the cargo,
+// or zero if it has never been published.
+func (b *Bus) Last(id cargo.TrackingID) uint64 {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.last[id]
+}
+
+// Subscribe returns a subscription to changes of the cargo. The subscription
+// must be closed once it is no longer needed.
+func (b *Bus) Subscribe(id cargo.TrackingID) *Subscription {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	c := make(chan struct{}, 1)
+	s := &Subscription{
+		C:   c,
+		c:   c,
+		id:  id,
+		bus: b,
+	}
+
+	if _, ok := b.subs[id]; !ok {
+		b.subs[id] = make(map[*Subscription]struct{})
+	}
+	b.subs[id][s] = struct{}{}
+
+	return s
+}
+
+func (b *Bus) unsubscribe(s *Subscription) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	delete(b.subs[s.id], s)
+	if len(b.subs[s.id]) == 0 {
+		delete(b.subs, s.id)
+	}
+}
+
+// Subscription receives a value on C whenever the cargo has changed.
+type Subscription struct {
+	C <-chan struct{}
+
+	c   chan struct{}
+	id  cargo.TrackingID
+	bus *Bus
+}
+
+// Close stops the subscription.
+func (s *Subscription) Close() {
+	s.bus.unsubscribe(s)
+}
@@ -0,0 +1,60 @@
+package eventbus
+
+import "testing"
+
+func TestPublish(t *testing.T) {
+	b := New()
+
+	s := b.Subscribe("ABC123")
+	defer s.Close()
+
+	b.Publish("XYZ789")
+
+	select {
+	case <-s.C:
+		t.Fatal("should not be notified of other cargos")
+	default:
+	}
+
+	// Publications are coalesced until the subscriber catches up.
+	b.Publish("ABC123")
+	b.Publish("ABC123")
+
+	select {
+	case <-s.C:
+	default:
+		t.Fatal("should be notified")
+	}
+
+	select {
+	case <-s.C:
+		t.Fatal("should only be notified once")
+	default:
+	}
+
+	if got := b.Last("ABC123"); got != 3 {
+		t.Errorf("b.Last() = %d; want = %d", got, 3)
+	}
+	if got := b.Last("FTL456"); got != 0 {
+		t.Errorf("b.Last() = %d; want = %d", got, 0)
+	}
+}
+
+func TestClose(t *testing.T) {
+	b := New()
+
+	s := b.Subscribe("ABC123")
+	s.Close()
+
+	b.Publish("ABC123")
+
+	select {
+	case <-s.C:
+		t.Fatal("closed subscription should not be notified")
+	default:
+	}
+
+	if len(b.subs) != 0 {
+		t.Errorf("len(b.subs) = %d; want = %d", len(b.subs), 0)
+	}
+}
@@ -0,0 +1,18 @@
+package graph
+
+import (
+	"github.com/go-kit/kit/endpoint"
+	"golang.org/x/net/context"
+)
+
+type executeRequest struct {
+	Request Request
+}
+
+func makeExecuteEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(executeRequest)
+		// The result carries its own errors, as specified by GraphQL.
+		return s.Execute(req.Request), nil
+	}
+}
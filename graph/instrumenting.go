@@ -0,0 +1,33 @@
+package graph
+
+import (
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/graphql-go/graphql"
+)
+
+type instrumentingService struct {
+	requestCount   metrics.Counter
+	requestLatency metrics.TimeHistogram
+	Service
+}
+
+// NewInstrumentingService returns an instance of an instrumenting Service.
+func NewInstrumentingService(counter metrics.Counter, latency metrics.TimeHistogram, s Service) Service {
+	return &instrumentingService{
+		requestCount:   counter,
+		requestLatency: latency,
+		Service:        s,
+	}
+}
+
+func (s *instrumentingService) Execute(req Request) *graphql.Result {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "execute"}
+		s.requestCount.With(methodField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.Execute(req)
+}
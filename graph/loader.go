@@ -0,0 +1,214 @@
+package graph
+
+import (
+	"sort"
+
+	"github.com/marcusolsson/goddd/access"
+	"github.com/marcusolsson/goddd/booking"
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/handling"
+	"github.com/marcusolsson/goddd/tracking"
+	"github.com/marcusolsson/goddd/voyage"
+)
+
+// resolver holds the state of a single request.
+//
+// Items in a list are resolved one after another, so loading the handling
+// history of each cargo on the spot would mean one repository call per
+// cargo. Instead, the resolvers enqueue the key and return a thunk. The
+// executor calls the thunks level by level, so the first of them finds the
+// keys of all its siblings enqueued and loads them in one go.
+//
+// A request is executed by a single goroutine, so no locking is needed.
+type resolver struct {
+	bs      booking.Service
+	hs      handling.Service
+	ts      tracking.Service
+	events  cargo.HandlingEventRepository
+	voyages voyage.Repository
+
+	token access.Token
+	lang  tracking.Language
+
+	locations map[string]booking.Location
+
+	pendingHistories map[cargo.TrackingID]bool
+	histories        map[cargo.TrackingID]cargo.HandlingHistory
+
+	pendingVoyages map[voyage.Number]bool
+	loadedVoyages  map[voyage.Number]*voyage.Voyage
+
+	pendingTracking map[string]bool
+	tracked         map[string]tracking.Result
+	trackingErr     error
+}
+
+func newResolver(s *service, token access.Token, lang tracking.Language) *resolver {
+	return &resolver{
+		bs:               s.bs,
+		hs:               s.hs,
+		ts:               s.ts,
+		events:           s.events,
+		voyages:          s.voyages,
+		token:            token,
+		lang:             lang,
+		pendingHistories: make(map[cargo.TrackingID]bool),
+		histories:        make(map[cargo.TrackingID]cargo.HandlingHistory),
+		pendingVoyages:   make(map[voyage.Number]bool),
+		loadedVoyages:    make(map[voyage.Number]*voyage.Voyage),
+		pendingTracking:  make(map[string]bool),
+		tracked:          make(map[string]tracking.Result),
+	}
+}
+
+// location returns a registered location, or nil if unknown. All locations
+// are loaded the first time one is asked for.
+func (r *resolver) location(code string) interface{} {
+	if r.locations == nil {
+		r.locations = make(map[string]booking.Location)
+		for _, l := range r.bs.Locations() {
+			r.locations[l.UNLocode] = l
+		}
+	}
+	if l, ok := r.locations[code]; ok {
+		return l
+	}
+	return nil
+}
+
+// history returns a thunk, i.e. a function the executor calls once every
+// sibling field has been resolved, resolving to the handling events of a
+// cargo.
+func (r *resolver) history(id cargo.TrackingID) func() (interface{}, error) {
+	if _, ok := r.histories[id]; !ok {
+		r.pendingHistories[id] = true
+	}
+	return func() (interface{}, error) {
+		if len(r.pendingHistories) > 0 {
+			var ids []cargo.TrackingID
+			for id := range r.pendingHistories {
+				ids = append(ids, id)
+			}
+			sort.Sort(trackingIDs(ids))
+
+			for id, h := range r.events.QueryHandlingHistories(ids) {
+				r.histories[id] = h
+			}
+			for _, id := range ids {
+				delete(r.pendingHistories, id)
+			}
+		}
+		if h, ok := r.histories[id]; ok && h.HandlingEvents != nil {
+			return h.HandlingEvents, nil
+		}
+		return []cargo.HandlingEvent{}, nil
+	}
+}
+
+// voyage returns a thunk resolving to a voyage, or to nil if unknown.
+func (r *resolver) voyage(n voyage.Number) func() (interface{}, error) {
+	if _, ok := r.loadedVoyages[n]; !ok {
+		r.pendingVoyages[n] = true
+	}
+	return func() (interface{}, error) {
+		if len(r.pendingVoyages) > 0 {
+			var numbers []voyage.Number
+			for n := range r.pendingVoyages {
+				numbers = append(numbers, n)
+			}
+			sort.Sort(voyageNumbers(numbers))
+
+			voyages, err := r.voyages.FindMany(numbers)
+			if err != nil {
+				return nil, err
+			}
+
+			// Unknown voyages are remembered as well, so that they are
+			// not asked for again.
+			for _, n := range numbers {
+				r.loadedVoyages[n] = nil
+				delete(r.pendingVoyages, n)
+			}
+			for _, v := range voyages {
+				r.loadedVoyages[v.Number] = v
+			}
+		}
+		if v := r.loadedVoyages[n]; v != nil {
+			return v, nil
+		}
+		return nil, nil
+	}
+}
+
+// tracking returns a thunk resolving to the tracking view of a cargo, as
+// seen by the holder of the access token given with the request.
+func (r *resolver) tracking(id string) func() (interface{}, error) {
+	if _, ok := r.tracked[id]; !ok {
+		r.pendingTracking[id] = true
+	}
+	return func() (interface{}, error) {
+		if len(r.pendingTracking) > 0 {
+			var ids []string
+			for id := range r.pendingTracking {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+
+			res, err := r.ts.TrackMany(ids, r.token, r.lang)
+			if err != nil {
+				r.trackingErr = err
+			}
+			for id, t := range res {
+				r.tracked[id] = t
+			}
+			for _, id := range ids {
+				delete(r.pendingTracking, id)
+			}
+		}
+		if r.trackingErr != nil {
+			return nil, r.trackingErr
+		}
+		res, ok := r.tracked[id]
+		if !ok {
+			return nil, nil
+		}
+		if res.Error != "" {
+			return nil, trackingError(res.Error)
+		}
+		return *res.Cargo, nil
+	}
+}
+
+// fullView returns a thunk resolving to what the given thunk resolves to, as
+// long as the access token given with the request gives the full view of the
+// cargo. The route and handling history are left out of the public view.
+func (r *resolver) fullView(id string, then func() (interface{}, error)) func() (interface{}, error) {
+	track := r.tracking(id)
+	return func() (interface{}, error) {
+		v, err := track()
+		if err != nil {
+			return nil, err
+		}
+		if c, ok := v.(tracking.Cargo); !ok || c.View != tracking.FullView {
+			return nil, tracking.ErrUnauthorized
+		}
+		return then()
+	}
+}
+
+// trackingError is the error given for a single cargo in a batch.
+type trackingError string
+
+func (e trackingError) Error() string { return string(e) }
+
+type trackingIDs []cargo.TrackingID
+
+func (s trackingIDs) Len() int           { return len(s) }
+func (s trackingIDs) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s trackingIDs) Less(i, j int) bool { return s[i] < s[j] }
+
+type voyageNumbers []voyage.Number
+
+func (s voyageNumbers) Len() int           { return len(s) }
+func (s voyageNumbers) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s voyageNumbers) Less(i, j int) bool { return s[i] < s[j] }
@@ -0,0 +1,30 @@
+package graph
+
+import (
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/graphql-go/graphql"
+)
+
+type loggingService struct {
+	logger log.Logger
+	Service
+}
+
+// NewLoggingService returns a new instance of a logging Service.
+func NewLoggingService(logger log.Logger, s Service) Service {
+	return &loggingService{logger, s}
+}
+
+func (s *loggingService) Execute(req Request) (res *graphql.Result) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "execute",
+			"operation", req.OperationName,
+			"errors", len(res.Errors),
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+	return s.Service.Execute(req)
+}
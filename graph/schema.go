@@ -0,0 +1,748 @@
+package graph
+
+import (
+	"time"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/marcusolsson/goddd/booking"
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/tracking"
+	"github.com/marcusolsson/goddd/voyage"
+)
+
+var locationType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Location",
+	Fields: graphql.Fields{
+		"locode": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(booking.Location).UNLocode, nil
+			},
+		},
+		"name": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(booking.Location).Name, nil
+			},
+		},
+	},
+})
+
+var carrierMovementType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "CarrierMovement",
+	Fields: graphql.Fields{
+		"from": &graphql.Field{
+			Type: locationType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				m := p.Source.(voyage.CarrierMovement)
+				return resolverFrom(p.Context).location(string(m.DepartureLocation)), nil
+			},
+		},
+		"to": &graphql.Field{
+			Type: locationType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				m := p.Source.(voyage.CarrierMovement)
+				return resolverFrom(p.Context).location(string(m.ArrivalLocation)), nil
+			},
+		},
+		"departureTime": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.DateTime),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(voyage.CarrierMovement).DepartureTime, nil
+			},
+		},
+		"arrivalTime": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.DateTime),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(voyage.CarrierMovement).ArrivalTime, nil
+			},
+		},
+	},
+})
+
+var voyageType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Voyage",
+	Fields: graphql.Fields{
+		"number": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return string(p.Source.(*voyage.Voyage).Number), nil
+			},
+		},
+		"mode": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*voyage.Voyage).Mode.String(), nil
+			},
+		},
+		"carrier": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if c := p.Source.(*voyage.Voyage).Carrier; c != "" {
+					return c, nil
+				}
+				return nil, nil
+			},
+		},
+		"movements": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(carrierMovementType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				movements := p.Source.(*voyage.Voyage).Schedule.CarrierMovements
+				if movements == nil {
+					return []voyage.CarrierMovement{}, nil
+				}
+				return movements, nil
+			},
+		},
+	},
+})
+
+var legType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Leg",
+	Fields: graphql.Fields{
+		"voyageNumber": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return string(p.Source.(cargo.Leg).VoyageNumber), nil
+			},
+		},
+		"voyage": &graphql.Field{
+			Type: voyageType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return resolverFrom(p.Context).voyage(p.Source.(cargo.Leg).VoyageNumber), nil
+			},
+		},
+		"mode": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(cargo.Leg).Mode.String(), nil
+			},
+		},
+		"from": &graphql.Field{
+			Type: locationType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return resolverFrom(p.Context).location(string(p.Source.(cargo.Leg).LoadLocation)), nil
+			},
+		},
+		"to": &graphql.Field{
+			Type: locationType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return resolverFrom(p.Context).location(string(p.Source.(cargo.Leg).UnloadLocation)), nil
+			},
+		},
+		"loadTime": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.DateTime),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(cargo.Leg).LoadTime, nil
+			},
+		},
+		"unloadTime": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.DateTime),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(cargo.Leg).UnloadTime, nil
+			},
+		},
+	},
+})
+
+var handlingEventType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "HandlingEvent",
+	Fields: graphql.Fields{
+		"type": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(cargo.HandlingEvent).Activity.Type.String(), nil
+			},
+		},
+		"location": &graphql.Field{
+			Type: locationType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				e := p.Source.(cargo.HandlingEvent)
+				return resolverFrom(p.Context).location(string(e.Activity.Location)), nil
+			},
+		},
+		"voyageNumber": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if n := p.Source.(cargo.HandlingEvent).Activity.VoyageNumber; n != "" {
+					return string(n), nil
+				}
+				return nil, nil
+			},
+		},
+		"voyage": &graphql.Field{
+			Type: voyageType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if n := p.Source.(cargo.HandlingEvent).Activity.VoyageNumber; n != "" {
+					return resolverFrom(p.Context).voyage(n), nil
+				}
+				return nil, nil
+			},
+		},
+		"completed": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.DateTime),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(cargo.HandlingEvent).Completed, nil
+			},
+		},
+		"registered": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.DateTime),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(cargo.HandlingEvent).Registered, nil
+			},
+		},
+	},
+})
+
+var trackingEventType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TrackingEvent",
+	Fields: graphql.Fields{
+		"description": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(tracking.Event).Description, nil
+			},
+		},
+		"expected": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Boolean),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(tracking.Event).Expected, nil
+			},
+		},
+	},
+})
+
+var trackingType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Tracking",
+	Description: "The cargo as shown to customers, in the language of the request.",
+	Fields: graphql.Fields{
+		"view": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return string(p.Source.(tracking.Cargo).View), nil
+			},
+		},
+		"statusText": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(tracking.Cargo).StatusText, nil
+			},
+		},
+		"nextExpectedActivity": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(tracking.Cargo).NextExpectedActivity, nil
+			},
+		},
+		"progress": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(tracking.Cargo).Progress, nil
+			},
+		},
+		"events": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(trackingEventType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if events := p.Source.(tracking.Cargo).Events; events != nil {
+					return events, nil
+				}
+				return []tracking.Event{}, nil
+			},
+		},
+	},
+})
+
+var referenceType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Reference",
+	Fields: graphql.Fields{
+		"type": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(booking.Reference).Type, nil
+			},
+		},
+		"value": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(booking.Reference).Value, nil
+			},
+		},
+	},
+})
+
+var cargoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Cargo",
+	Fields: graphql.Fields{
+		"trackingId": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(booking.Cargo).TrackingID, nil
+			},
+		},
+		"origin": &graphql.Field{
+			Type: locationType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return resolverFrom(p.Context).location(p.Source.(booking.Cargo).Origin), nil
+			},
+		},
+		"destination": &graphql.Field{
+			Type: locationType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return resolverFrom(p.Context).location(p.Source.(booking.Cargo).Destination), nil
+			},
+		},
+		"arrivalDeadline": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.DateTime),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(booking.Cargo).ArrivalDeadline, nil
+			},
+		},
+		"routed": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Boolean),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(booking.Cargo).Routed, nil
+			},
+		},
+		"misrouted": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Boolean),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(booking.Cargo).Misrouted, nil
+			},
+		},
+		"misdirected": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Boolean),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(booking.Cargo).Misdirected, nil
+			},
+		},
+		"transportStatus": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(booking.Cargo).TransportStatus, nil
+			},
+		},
+		"lastKnownLocation": &graphql.Field{
+			Type: locationType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return resolverFrom(p.Context).location(p.Source.(booking.Cargo).LastKnownLocation), nil
+			},
+		},
+		"currentVoyage": &graphql.Field{
+			Type: voyageType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if n := p.Source.(booking.Cargo).CurrentVoyage; n != "" {
+					return resolverFrom(p.Context).voyage(voyage.Number(n)), nil
+				}
+				return nil, nil
+			},
+		},
+		"eta": &graphql.Field{
+			Type: graphql.DateTime,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if eta := p.Source.(booking.Cargo).ETA; !eta.IsZero() {
+					return eta, nil
+				}
+				return nil, nil
+			},
+		},
+		"shipper": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return optional(p.Source.(booking.Cargo).Shipper), nil
+			},
+		},
+		"consignee": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return optional(p.Source.(booking.Cargo).Consignee), nil
+			},
+		},
+		"references": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(referenceType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if refs := p.Source.(booking.Cargo).References; refs != nil {
+					return refs, nil
+				}
+				return []booking.Reference{}, nil
+			},
+		},
+		"legs": &graphql.Field{
+			Type:        graphql.NewList(graphql.NewNonNull(legType)),
+			Description: "The legs of the itinerary of the cargo. Requires a customer session for the cargo.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				c := p.Source.(booking.Cargo)
+				return resolverFrom(p.Context).fullView(c.TrackingID, func() (interface{}, error) {
+					if c.Legs != nil {
+						return c.Legs, nil
+					}
+					return []cargo.Leg{}, nil
+				}), nil
+			},
+		},
+		"events": &graphql.Field{
+			Type:        graphql.NewList(graphql.NewNonNull(handlingEventType)),
+			Description: "The handling events registered for the cargo. Requires a customer session for the cargo.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				r := resolverFrom(p.Context)
+				id := p.Source.(booking.Cargo).TrackingID
+				return r.fullView(id, r.history(cargo.TrackingID(id))), nil
+			},
+		},
+		"tracking": &graphql.Field{
+			Type:        trackingType,
+			Description: "The tracking view of the cargo. Requires an access token for the cargo.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return resolverFrom(p.Context).tracking(p.Source.(booking.Cargo).TrackingID), nil
+			},
+		},
+	},
+})
+
+// cargoPage is the source of the CargoPage type.
+type cargoPage struct {
+	cargos     []booking.Cargo
+	nextCursor string
+}
+
+var cargoPageType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "CargoPage",
+	Fields: graphql.Fields{
+		"cargos": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(cargoType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if cargos := p.Source.(cargoPage).cargos; cargos != nil {
+					return cargos, nil
+				}
+				return []booking.Cargo{}, nil
+			},
+		},
+		"nextCursor": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return optional(p.Source.(cargoPage).nextCursor), nil
+			},
+		},
+	},
+})
+
+var bookingType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Booking",
+	Fields: graphql.Fields{
+		"cargo": &graphql.Field{
+			Type: cargoType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				b := p.Source.(booking.Booking)
+				return resolverFrom(p.Context).bs.LoadCargo(b.TrackingID)
+			},
+		},
+		"shareToken": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return string(p.Source.(booking.Booking).ShareToken), nil
+			},
+		},
+		"notRoutedReason": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return optional(p.Source.(booking.Booking).NotRoutedReason), nil
+			},
+		},
+	},
+})
+
+var legInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "LegInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"voyageNumber": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"from":         &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"to":           &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"loadTime":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.DateTime)},
+		"unloadTime":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.DateTime)},
+		"mode":         &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Sea, Rail, Road or Barge. Defaults to Sea."},
+	},
+})
+
+var queryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Query",
+	Fields: graphql.Fields{
+		"cargo": &graphql.Field{
+			Type: cargoType,
+			Args: graphql.FieldConfigArgument{
+				"trackingId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				id, _ := p.Args["trackingId"].(string)
+				return resolverFrom(p.Context).bs.LoadCargo(cargo.TrackingID(id))
+			},
+		},
+		"cargos": &graphql.Field{
+			Type:        graphql.NewNonNull(cargoPageType),
+			Description: "A page of booked cargos, filtered and sorted as in the booking API.",
+			Args: graphql.FieldConfigArgument{
+				"origin":      &graphql.ArgumentConfig{Type: graphql.String},
+				"destination": &graphql.ArgumentConfig{Type: graphql.String},
+				"voyage":      &graphql.ArgumentConfig{Type: graphql.String},
+				"customer":    &graphql.ArgumentConfig{Type: graphql.String},
+				"sort":        &graphql.ArgumentConfig{Type: graphql.String},
+				"descending":  &graphql.ArgumentConfig{Type: graphql.Boolean},
+				"limit":       &graphql.ArgumentConfig{Type: graphql.Int},
+				"cursor":      &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				q := cargo.Query{
+					Origin:      location.UNLocode(stringArg(p, "origin")),
+					Destination: location.UNLocode(stringArg(p, "destination")),
+					Voyage:      voyage.Number(stringArg(p, "voyage")),
+					Customer:    customer.ID(stringArg(p, "customer")),
+					SortBy:      cargo.SortField(stringArg(p, "sort")),
+					Cursor:      stringArg(p, "cursor"),
+				}
+				q.Descending, _ = p.Args["descending"].(bool)
+				if limit, ok := p.Args["limit"].(int); ok {
+					if limit < 0 {
+						return nil, booking.ErrInvalidArgument
+					}
+					q.Limit = limit
+				}
+
+				cargos, next, err := resolverFrom(p.Context).bs.Cargos(q)
+				if err != nil {
+					return nil, err
+				}
+				return cargoPage{cargos: cargos, nextCursor: next}, nil
+			},
+		},
+		"location": &graphql.Field{
+			Type: locationType,
+			Args: graphql.FieldConfigArgument{
+				"locode": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return resolverFrom(p.Context).location(stringArg(p, "locode")), nil
+			},
+		},
+		"locations": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(locationType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if locations := resolverFrom(p.Context).bs.Locations(); locations != nil {
+					return locations, nil
+				}
+				return []booking.Location{}, nil
+			},
+		},
+		"voyage": &graphql.Field{
+			Type: voyageType,
+			Args: graphql.FieldConfigArgument{
+				"number": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return resolverFrom(p.Context).voyage(voyage.Number(stringArg(p, "number"))), nil
+			},
+		},
+	},
+})
+
+var mutationType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Mutation",
+	Fields: graphql.Fields{
+		"bookCargo": &graphql.Field{
+			Type: graphql.NewNonNull(bookingType),
+			Args: graphql.FieldConfigArgument{
+				"origin":          &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"destination":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"arrivalDeadline": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.DateTime)},
+				"autoRoute":       &graphql.ArgumentConfig{Type: graphql.Boolean},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				deadline, ok := p.Args["arrivalDeadline"].(time.Time)
+				if !ok {
+					return nil, booking.ErrInvalidArgument
+				}
+
+				mode := booking.AutoRouteDefault
+				if auto, ok := p.Args["autoRoute"].(bool); ok {
+					mode = booking.AutoRouteOff
+					if auto {
+						mode = booking.AutoRouteOn
+					}
+				}
+
+				return resolverFrom(p.Context).bs.BookNewCargo(
+					location.UNLocode(stringArg(p, "origin")),
+					location.UNLocode(stringArg(p, "destination")),
+					deadline,
+					mode,
+				)
+			},
+		},
+		"assignCargoToRoute": &graphql.Field{
+			Type: graphql.NewNonNull(cargoType),
+			Args: graphql.FieldConfigArgument{
+				"trackingId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"legs":       &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(legInputType)))},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				id := cargo.TrackingID(stringArg(p, "trackingId"))
+
+				var itinerary cargo.Itinerary
+				legs, _ := p.Args["legs"].([]interface{})
+				for _, v := range legs {
+					l, _ := v.(map[string]interface{})
+					load, ok := l["loadTime"].(time.Time)
+					if !ok {
+						return nil, booking.ErrInvalidArgument
+					}
+					unload, ok := l["unloadTime"].(time.Time)
+					if !ok {
+						return nil, booking.ErrInvalidArgument
+					}
+					number, _ := l["voyageNumber"].(string)
+					from, _ := l["from"].(string)
+					to, _ := l["to"].(string)
+
+					leg := cargo.NewLeg(
+						voyage.Number(number),
+						location.UNLocode(from),
+						location.UNLocode(to),
+						load,
+						unload,
+					)
+
+					if m, ok := l["mode"].(string); ok {
+						mode, err := voyage.ParseMode(m)
+						if err != nil {
+							return nil, booking.ErrInvalidArgument
+						}
+						leg.Mode = mode
+					}
+
+					itinerary.Legs = append(itinerary.Legs, leg)
+				}
+
+				r := resolverFrom(p.Context)
+				if err := r.bs.AssignCargoToRoute(id, itinerary); err != nil {
+					return nil, err
+				}
+				return r.bs.LoadCargo(id)
+			},
+		},
+		"changeDestination": &graphql.Field{
+			Type: graphql.NewNonNull(cargoType),
+			Args: graphql.FieldConfigArgument{
+				"trackingId":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"destination": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				id := cargo.TrackingID(stringArg(p, "trackingId"))
+
+				r := resolverFrom(p.Context)
+				if err := r.bs.ChangeDestination(id, location.UNLocode(stringArg(p, "destination"))); err != nil {
+					return nil, err
+				}
+				return r.bs.LoadCargo(id)
+			},
+		},
+		"changeArrivalDeadline": &graphql.Field{
+			Type: graphql.NewNonNull(cargoType),
+			Args: graphql.FieldConfigArgument{
+				"trackingId":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"arrivalDeadline": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.DateTime)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				id := cargo.TrackingID(stringArg(p, "trackingId"))
+
+				deadline, ok := p.Args["arrivalDeadline"].(time.Time)
+				if !ok {
+					return nil, booking.ErrInvalidArgument
+				}
+
+				r := resolverFrom(p.Context)
+				if err := r.bs.ChangeArrivalDeadline(id, deadline); err != nil {
+					return nil, err
+				}
+				return r.bs.LoadCargo(id)
+			},
+		},
+		"cancelBooking": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Boolean),
+			Args: graphql.FieldConfigArgument{
+				"trackingId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				id := cargo.TrackingID(stringArg(p, "trackingId"))
+				if err := resolverFrom(p.Context).bs.CancelBooking(id); err != nil {
+					return nil, err
+				}
+				return true, nil
+			},
+		},
+		"registerHandlingEvent": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Boolean),
+			Args: graphql.FieldConfigArgument{
+				"trackingId":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"type":         &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"location":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"voyageNumber": &graphql.ArgumentConfig{Type: graphql.String},
+				"completed":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.DateTime)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				// An unparsable time is left zero, and rejected by the
+				// handling service.
+				completed, _ := p.Args["completed"].(time.Time)
+
+				err := resolverFrom(p.Context).hs.RegisterHandlingEvent(
+					completed,
+					cargo.TrackingID(stringArg(p, "trackingId")),
+					voyage.Number(stringArg(p, "voyageNumber")),
+					location.UNLocode(stringArg(p, "location")),
+					eventTypes[stringArg(p, "type")],
+				)
+				if err != nil {
+					return nil, err
+				}
+				return true, nil
+			},
+		},
+	},
+})
+
+var eventTypes = map[string]cargo.HandlingEventType{
+	cargo.Receive.String(): cargo.Receive,
+	cargo.Load.String():    cargo.Load,
+	cargo.Unload.String():  cargo.Unload,
+	cargo.Customs.String(): cargo.Customs,
+	cargo.Claim.String():   cargo.Claim,
+	cargo.GateIn.String():  cargo.GateIn,
+	cargo.GateOut.String(): cargo.GateOut,
+}
+
+var schema = mustSchema(graphql.SchemaConfig{
+	Query:    queryType,
+	Mutation: mutationType,
+})
+
+func mustSchema(c graphql.SchemaConfig) graphql.Schema {
+	s, err := graphql.NewSchema(c)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// stringArg returns an optional string argument, or the empty string if not
+// given.
+func stringArg(p graphql.ResolveParams, name string) string {
+	s, _ := p.Args[name].(string)
+	return s
+}
+
+// optional returns nil for empty strings, so that they are resolved to null.
+func optional(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
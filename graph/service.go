@@ -0,0 +1,81 @@
+// Package graph provides a GraphQL API over the booking, tracking and
+// handling services, exposing cargos, legs, handling events, locations and
+// voyages as one connected graph. Used by views that would otherwise need
+// several round trips to the other APIs.
+package graph
+
+import (
+	"github.com/graphql-go/graphql"
+	"golang.org/x/net/context"
+
+	"github.com/marcusolsson/goddd/access"
+	"github.com/marcusolsson/goddd/booking"
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/handling"
+	"github.com/marcusolsson/goddd/tracking"
+	"github.com/marcusolsson/goddd/voyage"
+)
+
+// Request is a query or mutation, along with the credentials of the client.
+type Request struct {
+	Query         string
+	Variables     map[string]interface{}
+	OperationName string
+
+	// Token and Language are passed to the tracking service when resolving
+	// the tracking view of cargos.
+	Token    access.Token
+	Language tracking.Language
+}
+
+// Service is the interface that provides the Execute method.
+type Service interface {
+	// Execute runs a request against the schema. Errors in resolving single
+	// fields are reported in the result, along with the data that could be
+	// resolved.
+	Execute(req Request) *graphql.Result
+}
+
+type service struct {
+	schema graphql.Schema
+
+	bs      booking.Service
+	hs      handling.Service
+	ts      tracking.Service
+	events  cargo.HandlingEventRepository
+	voyages voyage.Repository
+}
+
+func (s *service) Execute(req Request) *graphql.Result {
+	ctx := context.WithValue(context.Background(), resolverKey, newResolver(s, req.Token, req.Language))
+
+	return graphql.Do(graphql.Params{
+		Schema:         s.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        ctx,
+	})
+}
+
+// NewService returns a new instance of the GraphQL service. The handling
+// service has no read side, so handling events and voyages are read from
+// the repositories.
+func NewService(bs booking.Service, hs handling.Service, ts tracking.Service, events cargo.HandlingEventRepository, voyages voyage.Repository) Service {
+	return &service{
+		schema:  schema,
+		bs:      bs,
+		hs:      hs,
+		ts:      ts,
+		events:  events,
+		voyages: voyages,
+	}
+}
+
+type contextKey int
+
+const resolverKey contextKey = 0
+
+func resolverFrom(ctx context.Context) *resolver {
+	return ctx.Value(resolverKey).(*resolver)
+}
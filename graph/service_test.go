@@ -0,0 +1,201 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/marcusolsson/goddd/access"
+	"github.com/marcusolsson/goddd/booking"
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/document"
+	"github.com/marcusolsson/goddd/inmem"
+	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/mock"
+	"github.com/marcusolsson/goddd/tracking"
+	"github.com/marcusolsson/goddd/voyage"
+)
+
+func TestCargosBatchesLoads(t *testing.T) {
+	cargos := inmem.NewCargoRepository()
+
+	for _, id := range []cargo.TrackingID{"ABC", "DEF", "GHI"} {
+		c := cargo.New(id, cargo.RouteSpecification{
+			Origin:      location.SESTO,
+			Destination: location.CNHKG,
+		})
+		c.AssignToRoute(cargo.Itinerary{Legs: []cargo.Leg{
+			cargo.NewLeg(voyage.V100.Number, location.SESTO, location.JNTKO, time.Time{}, time.Time{}),
+			cargo.NewLeg(voyage.V300.Number, location.JNTKO, location.CNHKG, time.Time{}, time.Time{}),
+		}})
+		c.AttachParty(cargo.Shipper, "ACME")
+		cargos.Store(c)
+	}
+
+	grants := inmem.NewGrantRepository()
+
+	session := access.NewSession("ACME", time.Now())
+	grants.Store(session)
+
+	var historiesCalls int
+
+	var events mock.HandlingEventRepository
+	events.QueryHandlingHistoriesFn = func(ids []cargo.TrackingID) map[cargo.TrackingID]cargo.HandlingHistory {
+		historiesCalls++
+
+		h := make(map[cargo.TrackingID]cargo.HandlingHistory)
+		for _, id := range ids {
+			h[id] = cargo.HandlingHistory{HandlingEvents: []cargo.HandlingEvent{
+				{
+					TrackingID: id,
+					Activity:   cargo.HandlingActivity{Type: cargo.Load, Location: location.SESTO, VoyageNumber: voyage.V100.Number},
+				},
+			}}
+		}
+		return h
+	}
+
+	var voyagesCalls int
+
+	voyages := inmem.NewVoyageRepository()
+
+	var vr mock.VoyageRepository
+	vr.FindManyFn = func(numbers []voyage.Number) ([]*voyage.Voyage, error) {
+		voyagesCalls++
+		return voyages.FindMany(numbers)
+	}
+
+	bs := booking.NewService(cargos, inmem.NewLocationRepository(), inmem.NewVoyageRepository(), document.Issuer{}, grants, booking.Options{})
+	ts := tracking.NewService(cargos, inmem.NewHandlingEventRepository(), inmem.NewLocationRepository(), grants)
+
+	s := NewService(bs, nil, ts, &events, &vr)
+
+	res := s.Execute(Request{Token: session.Token, Query: `{
+		cargos {
+			cargos {
+				trackingId
+				origin { name }
+				legs { voyage { number } }
+				events { type voyage { number } }
+			}
+		}
+	}`})
+
+	if len(res.Errors) > 0 {
+		t.Fatal(res.Errors)
+	}
+
+	if historiesCalls != 1 {
+		t.Errorf("QueryHandlingHistories called %d times; want = 1", historiesCalls)
+	}
+	if voyagesCalls != 1 {
+		t.Errorf("FindMany called %d times; want = 1", voyagesCalls)
+	}
+	if vr.FindInvoked {
+		t.Errorf("Find should not be invoked")
+	}
+
+	page := res.Data.(map[string]interface{})["cargos"].(map[string]interface{})
+	got := page["cargos"].([]interface{})
+
+	if len(got) != 3 {
+		t.Fatalf("len(cargos) = %d; want = 3", len(got))
+	}
+
+	want := map[string]interface{}{
+		"trackingId": "ABC",
+		"origin":     map[string]interface{}{"name": "Stockholm"},
+		"legs": []interface{}{
+			map[string]interface{}{"voyage": map[string]interface{}{"number": "V100"}},
+			map[string]interface{}{"voyage": map[string]interface{}{"number": "V300"}},
+		},
+		"events": []interface{}{
+			map[string]interface{}{"type": "Load", "voyage": map[string]interface{}{"number": "V100"}},
+		},
+	}
+
+	if !reflect.DeepEqual(got[0], want) {
+		t.Errorf("cargos[0] = %#v; want = %#v", got[0], want)
+	}
+}
+
+func TestChangeDestination(t *testing.T) {
+	cargos := inmem.NewCargoRepository()
+	cargos.Store(cargo.New("ABC", cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.CNHKG,
+	}))
+
+	bs := booking.NewService(cargos, inmem.NewLocationRepository(), inmem.NewVoyageRepository(), document.Issuer{}, inmem.NewGrantRepository(), booking.Options{})
+
+	s := NewService(bs, nil, nil, nil, nil)
+
+	res := s.Execute(Request{
+		Query: `mutation ($id: String!) {
+			changeDestination(trackingId: $id, destination: "AUMEL") {
+				destination { locode name }
+			}
+		}`,
+		Variables: map[string]interface{}{"id": "ABC"},
+	})
+
+	if len(res.Errors) > 0 {
+		t.Fatal(res.Errors)
+	}
+
+	want := map[string]interface{}{
+		"changeDestination": map[string]interface{}{
+			"destination": map[string]interface{}{"locode": "AUMEL", "name": "Melbourne"},
+		},
+	}
+
+	if !reflect.DeepEqual(res.Data, want) {
+		t.Errorf("res.Data = %#v; want = %#v", res.Data, want)
+	}
+
+	c, err := cargos.Find("ABC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.RouteSpecification.Destination != location.AUMEL {
+		t.Errorf("Destination = %s; want = %s", c.RouteSpecification.Destination, location.AUMEL)
+	}
+}
+
+func TestTrackingUnauthorized(t *testing.T) {
+	cargos := inmem.NewCargoRepository()
+	cargos.Store(cargo.New("ABC", cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.CNHKG,
+	}))
+
+	events := inmem.NewHandlingEventRepository()
+	locations := inmem.NewLocationRepository()
+	grants := inmem.NewGrantRepository()
+
+	bs := booking.NewService(cargos, locations, inmem.NewVoyageRepository(), document.Issuer{}, grants, booking.Options{})
+	ts := tracking.NewService(cargos, events, locations, grants)
+
+	s := NewService(bs, nil, ts, events, nil)
+
+	res := s.Execute(Request{Query: `{ cargo(trackingId: "ABC") { trackingId tracking { statusText } legs { from { name } } events { type } } }`})
+
+	// The booking view is still resolved, with the tracking view, the route
+	// and the handling history left out.
+	if len(res.Errors) != 3 {
+		t.Fatalf("res.Errors = %v; want 3 errors", res.Errors)
+	}
+	for _, e := range res.Errors {
+		if e.Message != tracking.ErrUnauthorized.Error() {
+			t.Errorf("e.Message = %q; want = %q", e.Message, tracking.ErrUnauthorized.Error())
+		}
+	}
+
+	want := map[string]interface{}{
+		"cargo": map[string]interface{}{"trackingId": "ABC", "tracking": nil, "legs": nil, "events": nil},
+	}
+
+	if !reflect.DeepEqual(res.Data, want) {
+		t.Errorf("res.Data = %#v; want = %#v", res.Data, want)
+	}
+}
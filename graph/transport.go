@@ -0,0 +1,102 @@
+package graph
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	kitlog "github.com/go-kit/kit/log"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"golang.org/x/net/context"
+
+	"github.com/marcusolsson/goddd/access"
+	"github.com/marcusolsson/goddd/tracking"
+)
+
+// ErrInvalidRequest is returned when a request body could not be decoded.
+var ErrInvalidRequest = errors.New("invalid request")
+
+// MakeHandler returns a handler for the GraphQL service.
+func MakeHandler(ctx context.Context, s Service, logger kitlog.Logger) http.Handler {
+	r := mux.NewRouter()
+
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorLogger(logger),
+		kithttp.ServerErrorEncoder(encodeError),
+	}
+
+	executeHandler := kithttp.NewServer(
+		ctx,
+		makeExecuteEndpoint(s),
+		decodeExecuteRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	r.Handle("/graph/v1/query", executeHandler).Methods("POST")
+	r.Handle("/graph/v1/docs", http.StripPrefix("/graph/v1/docs", http.FileServer(http.Dir("graph/docs"))))
+
+	return r
+}
+
+func decodeExecuteRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var body struct {
+		Query         string                 `json:"query"`
+		Variables     map[string]interface{} `json:"variables"`
+		OperationName string                 `json:"operationName"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, ErrInvalidRequest
+	}
+
+	if body.Query == "" {
+		return nil, ErrInvalidRequest
+	}
+
+	return executeRequest{
+		Request: Request{
+			Query:         body.Query,
+			Variables:     body.Variables,
+			OperationName: body.OperationName,
+			Token:         accessToken(r),
+			Language:      tracking.ParseAcceptLanguage(r.Header.Get("Accept-Language")),
+		},
+	}, nil
+}
+
+func accessToken(r *http.Request) access.Token {
+	const prefix = "Bearer "
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, prefix) {
+		return access.Token(strings.TrimSpace(h[len(prefix):]))
+	}
+	return ""
+}
+
+// encodeResponse writes the result of a request. Errors in resolving fields
+// are part of the result, so the status is always 200 OK.
+func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Vary", "Accept-Language")
+	return json.NewEncoder(w).Encode(response)
+}
+
+// encode errors from decoding requests
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+	if e, ok := err.(kithttp.Error); ok && e.Domain == kithttp.DomainDecode {
+		err = e.Err
+	}
+
+	switch err {
+	case ErrInvalidRequest:
+		w.WriteHeader(http.StatusBadRequest)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,63 @@
+package graph
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"golang.org/x/net/context"
+
+	"github.com/marcusolsson/goddd/booking"
+	"github.com/marcusolsson/goddd/document"
+	"github.com/marcusolsson/goddd/inmem"
+)
+
+func TestQuery(t *testing.T) {
+	bs := booking.NewService(inmem.NewCargoRepository(), inmem.NewLocationRepository(), inmem.NewVoyageRepository(), document.Issuer{}, inmem.NewGrantRepository(), booking.Options{})
+
+	h := MakeHandler(context.Background(), NewService(bs, nil, nil, nil, nil), log.NewNopLogger())
+
+	tests := []struct {
+		body     string
+		wantCode int
+	}{
+		{`{"query": "{ location(locode: \"SESTO\") { name } }"}`, http.StatusOK},
+		{`{"query": "{ cargo(trackingId: \"unknown\") { trackingId } }"}`, http.StatusOK},
+		{`{"query": ""}`, http.StatusBadRequest},
+		{`not json`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("POST", "http://example.com/graph/v1/query", strings.NewReader(tt.body))
+		rec := httptest.NewRecorder()
+
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s: rec.Code = %d; want = %d", tt.body, rec.Code, tt.wantCode)
+		}
+	}
+
+	req, _ := http.NewRequest("POST", "http://example.com/graph/v1/query", strings.NewReader(`{"query": "{ location(locode: \"SESTO\") { name } }"}`))
+	rec := httptest.NewRecorder()
+
+	h.ServeHTTP(rec, req)
+
+	var response struct {
+		Data struct {
+			Location struct {
+				Name string `json:"name"`
+			} `json:"location"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Data.Location.Name != "Stockholm" {
+		t.Errorf("location.name = %q; want = %q", response.Data.Location.Name, "Stockholm")
+	}
+}
@@ -0,0 +1,30 @@
+package handling
+
+import (
+	"time"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/eventbus"
+	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/voyage"
+)
+
+type publishingService struct {
+	publisher eventbus.Publisher
+	Service
+}
+
+// NewPublishingService returns a Service that publishes the cargo whenever a
+// handling event has been registered for it.
+func NewPublishingService(p eventbus.Publisher, s Service) Service {
+	return &publishingService{p, s}
+}
+
+func (s *publishingService) RegisterHandlingEvent(completed time.Time, id cargo.TrackingID, voyageNumber voyage.Number,
+	unLocode location.UNLocode, eventType cargo.HandlingEventType) error {
+	if err := s.Service.RegisterHandlingEvent(completed, id, voyageNumber, unLocode, eventType); err != nil {
+		return err
+	}
+	s.publisher.Publish(id)
+	return nil
+}
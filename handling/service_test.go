@@ -79,3 +79,49 @@ func TestRegisterHandlingEvent(t *testing.T) {
 		t.Errorf("len(eh.events) = %d; want = %d", len(eh.events), 1)
 	}
 }
+
+func TestRegisterHandlingEventByTruck(t *testing.T) {
+	var cargos mock.CargoRepository
+	cargos.FindFn = func(id cargo.TrackingID) (*cargo.Cargo, error) {
+		return new(cargo.Cargo), nil
+	}
+
+	var voyages mock.VoyageRepository
+	voyages.FindFn = func(n voyage.Number) (*voyage.Voyage, error) {
+		return &voyage.Voyage{Number: n, Mode: voyage.Road}, nil
+	}
+
+	var locations mock.LocationRepository
+	locations.FindFn = func(l location.UNLocode) (*location.Location, error) {
+		return nil, nil
+	}
+
+	var events mock.HandlingEventRepository
+	events.StoreFn = func(e cargo.HandlingEvent) {}
+
+	ef := cargo.HandlingEventFactory{
+		CargoRepository:    &cargos,
+		VoyageRepository:   &voyages,
+		LocationRepository: &locations,
+	}
+
+	s := NewService(&events, ef, &stubEventHandler{})
+
+	completed := time.Date(2015, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		typ  cargo.HandlingEventType
+		want error
+	}{
+		{cargo.GateOut, nil},
+		{cargo.GateIn, nil},
+		{cargo.Load, cargo.ErrModeMismatch},
+		{cargo.Unload, cargo.ErrModeMismatch},
+	}
+
+	for _, tt := range tests {
+		if err := s.RegisterHandlingEvent(completed, "ABC123", "T100", location.DEHAM, tt.typ); err != tt.want {
+			t.Errorf("%s: err = %v; want = %v", tt.typ, err, tt.want)
+		}
+	}
+}
@@ -67,6 +67,8 @@ func stringToEventType(s string) cargo.HandlingEventType {
 		cargo.Unload.String():  cargo.Unload,
 		cargo.Customs.String(): cargo.Customs,
 		cargo.Claim.String():   cargo.Claim,
+		cargo.GateIn.String():  cargo.GateIn,
+		cargo.GateOut.String(): cargo.GateOut,
 	}
 	return types[s]
 }
@@ -89,7 +91,7 @@ func encodeError(_ context.Context, err error, w http.ResponseWriter) {
 	switch err {
 	case cargo.ErrUnknown:
 		w.WriteHeader(http.StatusNotFound)
-	case ErrInvalidArgument:
+	case ErrInvalidArgument, cargo.ErrModeMismatch:
 		w.WriteHeader(http.StatusBadRequest)
 	default:
 		w.WriteHeader(http.StatusInternalServerError)
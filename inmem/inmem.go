@@ -2,25 +2,69 @@
 package inmem
 
 import (
+	"sort"
 	"sync"
+	"time"
 
+	"github.com/marcusolsson/goddd/access"
+	"github.com/marcusolsson/goddd/allotment"
 	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/document"
 	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/task"
 	"github.com/marcusolsson/goddd/voyage"
+	"github.com/marcusolsson/goddd/webhook"
 )
 
 type cargoRepository struct {
 	mtx    sync.RWMutex
 	cargos map[cargo.TrackingID]*cargo.Cargo
+
+	// references maps reference values to the cargos carrying them, as of
+	// when the cargos were last stored.
+	references map[string]map[cargo.TrackingID]bool
+	indexed    map[cargo.TrackingID][]string
 }
 
 func (r *cargoRepository) Store(c *cargo.Cargo) error {
 	r.mtx.Lock()
 	defer r.mtx.Unlock()
 	r.cargos[c.TrackingID] = c
+	r.unindex(c.TrackingID)
+	for _, ref := range c.References {
+		v := cargo.NormalizeReference(ref.Value)
+		if _, ok := r.references[v]; !ok {
+			r.references[v] = make(map[cargo.TrackingID]bool)
+		}
+		r.references[v][c.TrackingID] = true
+		r.indexed[c.TrackingID] = append(r.indexed[c.TrackingID], v)
+	}
 	return nil
 }
 
+func (r *cargoRepository) unindex(id cargo.TrackingID) {
+	for _, v := range r.indexed[id] {
+		delete(r.references[v], id)
+		if len(r.references[v]) == 0 {
+			delete(r.references, v)
+		}
+	}
+	delete(r.indexed, id)
+}
+
+func (r *cargoRepository) FindByReference(value string) ([]*cargo.Cargo, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	ids := r.references[cargo.NormalizeReference(value)]
+	c := make([]*cargo.Cargo, 0, len(ids))
+	for id := range ids {
+		c = append(c, r.cargos[id])
+	}
+	sort.Sort(byField{c, cargo.SortByTrackingID, false})
+	return c, nil
+}
+
 func (r *cargoRepository) Find(id cargo.TrackingID) (*cargo.Cargo, error) {
 	r.mtx.RLock()
 	defer r.mtx.RUnlock()
@@ -30,6 +74,29 @@ func (r *cargoRepository) Find(id cargo.TrackingID) (*cargo.Cargo, error) {
 	return nil, cargo.ErrUnknown
 }
 
+func (r *cargoRepository) FindMany(ids []cargo.TrackingID) ([]*cargo.Cargo, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	c := make([]*cargo.Cargo, 0, len(ids))
+	for _, id := range ids {
+		if val, ok := r.cargos[id]; ok {
+			c = append(c, val)
+		}
+	}
+	return c, nil
+}
+
+func (r *cargoRepository) Remove(id cargo.TrackingID) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if _, ok := r.cargos[id]; !ok {
+		return cargo.ErrUnknown
+	}
+	delete(r.cargos, id)
+	r.unindex(id)
+	return nil
+}
+
 func (r *cargoRepository) FindAll() []*cargo.Cargo {
 	r.mtx.RLock()
 	defer r.mtx.RUnlock()
@@ -40,10 +107,71 @@ func (r *cargoRepository) FindAll() []*cargo.Cargo {
 	return c
 }
 
+func (r *cargoRepository) Query(q cargo.Query) (cargo.Page, error) {
+	q = q.Normalize()
+
+	var (
+		cur       cargo.Cursor
+		hasCursor = q.Cursor != ""
+	)
+	if hasCursor {
+		var err error
+		if cur, err = cargo.DecodeCursor(q.Cursor); err != nil {
+			return cargo.Page{}, err
+		}
+	}
+
+	r.mtx.RLock()
+	matches := make([]*cargo.Cargo, 0)
+	for _, c := range r.cargos {
+		if !q.Matches(c) {
+			continue
+		}
+		if hasCursor && !cur.After(c, q.SortBy, q.Descending) {
+			continue
+		}
+		matches = append(matches, c)
+	}
+	r.mtx.RUnlock()
+
+	sort.Sort(byField{matches, q.SortBy, q.Descending})
+
+	var page cargo.Page
+	if len(matches) > q.Limit {
+		matches = matches[:q.Limit]
+		page.NextCursor = cargo.NewCursor(matches[q.Limit-1], q.SortBy)
+	}
+	page.Cargos = matches
+
+	return page, nil
+}
+
+type byField struct {
+	cargos []*cargo.Cargo
+	field  cargo.SortField
+	desc   bool
+}
+
+func (s byField) Len() int      { return len(s.cargos) }
+func (s byField) Swap(i, j int) { s.cargos[i], s.cargos[j] = s.cargos[j], s.cargos[i] }
+func (s byField) Less(i, j int) bool {
+	a, b := s.cargos[i], s.cargos[j]
+	ka, kb := s.field.Key(a), s.field.Key(b)
+	if ka == kb {
+		ka, kb = string(a.TrackingID), string(b.TrackingID)
+	}
+	if s.desc {
+		return ka > kb
+	}
+	return ka < kb
+}
+
 // NewCargoRepository returns a new instance of a in-memory cargo repository.
 func NewCargoRepository() cargo.Repository {
 	return &cargoRepository{
-		cargos: make(map[cargo.TrackingID]*cargo.Cargo),
+		cargos:     make(map[cargo.TrackingID]*cargo.Cargo),
+		references: make(map[string]map[cargo.TrackingID]bool),
+		indexed:    make(map[cargo.TrackingID][]string),
 	}
 }
 
@@ -94,6 +222,24 @@ func (r *voyageRepository) Find(voyageNumber voyage.Number) (*voyage.Voyage, err
 	return nil, voyage.ErrUnknown
 }
 
+func (r *voyageRepository) FindMany(numbers []voyage.Number) ([]*voyage.Voyage, error) {
+	v := make([]*voyage.Voyage, 0, len(numbers))
+	for _, n := range numbers {
+		if val, ok := r.voyages[n]; ok {
+			v = append(v, val)
+		}
+	}
+	return v, nil
+}
+
+func (r *voyageRepository) FindAll() []*voyage.Voyage {
+	v := make([]*voyage.Voyage, 0, len(r.voyages))
+	for _, val := range r.voyages {
+		v = append(v, val)
+	}
+	return v
+}
+
 // NewVoyageRepository returns a new instance of a in-memory voyage repository.
 func NewVoyageRepository() voyage.Repository {
 	r := &voyageRepository{
@@ -103,6 +249,8 @@ func NewVoyageRepository() voyage.Repository {
 	r.voyages[voyage.V100.Number] = voyage.V100
 	r.voyages[voyage.V300.Number] = voyage.V300
 	r.voyages[voyage.V400.Number] = voyage.V400
+	r.voyages[voyage.R100.Number] = voyage.R100
+	r.voyages[voyage.T100.Number] = voyage.T100
 
 	r.voyages[voyage.V0100S.Number] = voyage.V0100S
 	r.voyages[voyage.V0200T.Number] = voyage.V0200T
@@ -113,6 +261,332 @@ func NewVoyageRepository() voyage.Repository {
 	return r
 }
 
+type customerRepository struct {
+	mtx       sync.RWMutex
+	customers map[customer.ID]*customer.Customer
+}
+
+func (r *customerRepository) Store(c *customer.Customer) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.customers[c.ID] = c
+	return nil
+}
+
+func (r *customerRepository) Find(id customer.ID) (*customer.Customer, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	if val, ok := r.customers[id]; ok {
+		return val, nil
+	}
+	return nil, customer.ErrUnknown
+}
+
+func (r *customerRepository) FindAll() []*customer.Customer {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	c := make([]*customer.Customer, 0, len(r.customers))
+	for _, val := range r.customers {
+		c = append(c, val)
+	}
+	return c
+}
+
+// NewCustomerRepository returns a new instance of a in-memory customer repository.
+func NewCustomerRepository() customer.Repository {
+	return &customerRepository{
+		customers: make(map[customer.ID]*customer.Customer),
+	}
+}
+
+type allotmentRepository struct {
+	mtx        sync.RWMutex
+	allotments map[allotment.ID]*allotment.Allotment
+}
+
+func (r *allotmentRepository) Store(a *allotment.Allotment) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if prev, ok := r.allotments[a.ID]; ok && prev.Version != a.Version || !ok && a.Version != 0 {
+		return allotment.ErrConflict
+	}
+	a.Version++
+	r.allotments[a.ID] = a.Copy()
+	return nil
+}
+
+func (r *allotmentRepository) Find(id allotment.ID) (*allotment.Allotment, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	if val, ok := r.allotments[id]; ok {
+		return val.Copy(), nil
+	}
+	return nil, allotment.ErrUnknown
+}
+
+func (r *allotmentRepository) FindByCustomer(c customer.ID) []*allotment.Allotment {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	a := make([]*allotment.Allotment, 0)
+	for _, val := range r.allotments {
+		if val.Customer == c {
+			a = append(a, val.Copy())
+		}
+	}
+	return a
+}
+
+func (r *allotmentRepository) FindAll() []*allotment.Allotment {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	a := make([]*allotment.Allotment, 0, len(r.allotments))
+	for _, val := range r.allotments {
+		a = append(a, val.Copy())
+	}
+	return a
+}
+
+// NewAllotmentRepository returns a new instance of a in-memory allotment repository.
+func NewAllotmentRepository() allotment.Repository {
+	return &allotmentRepository{
+		allotments: make(map[allotment.ID]*allotment.Allotment),
+	}
+}
+
+type taskRepository struct {
+	mtx   sync.RWMutex
+	tasks map[cargo.TrackingID]*task.Task
+}
+
+func (r *taskRepository) Store(t *task.Task) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.tasks[t.TrackingID] = t
+	return nil
+}
+
+func (r *taskRepository) Find(id cargo.TrackingID) (*task.Task, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	if val, ok := r.tasks[id]; ok {
+		return val, nil
+	}
+	return nil, task.ErrUnknown
+}
+
+func (r *taskRepository) FindMany(ids []cargo.TrackingID) ([]*task.Task, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	t := make([]*task.Task, 0, len(ids))
+	for _, id := range ids {
+		if val, ok := r.tasks[id]; ok {
+			t = append(t, val)
+		}
+	}
+	return t, nil
+}
+
+// NewTaskRepository returns a new instance of a in-memory task repository.
+func NewTaskRepository() task.Repository {
+	return &taskRepository{
+		tasks: make(map[cargo.TrackingID]*task.Task),
+	}
+}
+
+type documentRepository struct {
+	mtx       sync.RWMutex
+	documents map[cargo.TrackingID][]*document.BillOfLading
+}
+
+func (r *documentRepository) Store(b *document.BillOfLading) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	docs := r.documents[b.TrackingID]
+	for _, d := range docs {
+		if d.Version == b.Version {
+			return document.ErrConflict
+		}
+	}
+	r.documents[b.TrackingID] = append(docs, b)
+	return nil
+}
+
+func (r *documentRepository) Find(id cargo.TrackingID, version int) (*document.BillOfLading, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	for _, d := range r.documents[id] {
+		if d.Version == version {
+			return d, nil
+		}
+	}
+	return nil, document.ErrUnknown
+}
+
+func (r *documentRepository) FindByCargo(id cargo.TrackingID) []*document.BillOfLading {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return append([]*document.BillOfLading{}, r.documents[id]...)
+}
+
+// NewDocumentRepository returns a new instance of a in-memory document repository.
+func NewDocumentRepository() document.Repository {
+	return &documentRepository{
+		documents: make(map[cargo.TrackingID][]*document.BillOfLading),
+	}
+}
+
+type subscriptionRepository struct {
+	mtx           sync.RWMutex
+	subscriptions map[webhook.SubscriptionID]*webhook.Subscription
+}
+
+func (r *subscriptionRepository) Store(s *webhook.Subscription) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.subscriptions[s.ID] = s
+	return nil
+}
+
+func (r *subscriptionRepository) Find(id webhook.SubscriptionID) (*webhook.Subscription, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	if val, ok := r.subscriptions[id]; ok {
+		return val, nil
+	}
+	return nil, webhook.ErrUnknownSubscription
+}
+
+func (r *subscriptionRepository) FindAll() []*webhook.Subscription {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	s := make([]*webhook.Subscription, 0, len(r.subscriptions))
+	for _, val := range r.subscriptions {
+		s = append(s, val)
+	}
+	return s
+}
+
+func (r *subscriptionRepository) Remove(id webhook.SubscriptionID) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if _, ok := r.subscriptions[id]; !ok {
+		return webhook.ErrUnknownSubscription
+	}
+	delete(r.subscriptions, id)
+	return nil
+}
+
+// NewSubscriptionRepository returns a new instance of a in-memory subscription repository.
+func NewSubscriptionRepository() webhook.SubscriptionRepository {
+	return &subscriptionRepository{
+		subscriptions: make(map[webhook.SubscriptionID]*webhook.Subscription),
+	}
+}
+
+type deliveryRepository struct {
+	mtx        sync.RWMutex
+	deliveries []*webhook.Delivery
+}
+
+func (r *deliveryRepository) Store(d *webhook.Delivery) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for i, val := range r.deliveries {
+		if val.ID == d.ID {
+			r.deliveries[i] = d
+			return nil
+		}
+	}
+	r.deliveries = append(r.deliveries, d)
+	return nil
+}
+
+func (r *deliveryRepository) Find(id webhook.DeliveryID) (*webhook.Delivery, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	for _, val := range r.deliveries {
+		if val.ID == id {
+			return val, nil
+		}
+	}
+	return nil, webhook.ErrUnknownDelivery
+}
+
+func (r *deliveryRepository) FindBySubscription(id webhook.SubscriptionID) []*webhook.Delivery {
+	return r.filter(func(d *webhook.Delivery) bool {
+		return d.SubscriptionID == id
+	})
+}
+
+func (r *deliveryRepository) FindDue(t time.Time) []*webhook.Delivery {
+	return r.filter(func(d *webhook.Delivery) bool {
+		return d.State == webhook.Pending && !d.NextAttempt.After(t)
+	})
+}
+
+func (r *deliveryRepository) FindByState(s webhook.DeliveryState) []*webhook.Delivery {
+	return r.filter(func(d *webhook.Delivery) bool {
+		return d.State == s
+	})
+}
+
+func (r *deliveryRepository) filter(fn func(*webhook.Delivery) bool) []*webhook.Delivery {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	d := make([]*webhook.Delivery, 0)
+	for _, val := range r.deliveries {
+		if fn(val) {
+			d = append(d, val)
+		}
+	}
+	return d
+}
+
+// NewDeliveryRepository returns a new instance of a in-memory webhook delivery repository.
+func NewDeliveryRepository() webhook.DeliveryRepository {
+	return &deliveryRepository{}
+}
+
+type grantRepository struct {
+	mtx    sync.RWMutex
+	grants map[access.Token]*access.Grant
+}
+
+func (r *grantRepository) Store(g *access.Grant) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.grants[g.Token] = g
+	return nil
+}
+
+func (r *grantRepository) Find(t access.Token) (*access.Grant, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	if val, ok := r.grants[t]; ok {
+		return val, nil
+	}
+	return nil, access.ErrUnknown
+}
+
+func (r *grantRepository) FindByCargo(id cargo.TrackingID) []*access.Grant {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	g := make([]*access.Grant, 0)
+	for _, val := range r.grants {
+		if val.TrackingID == id {
+			g = append(g, val)
+		}
+	}
+	return g
+}
+
+// NewGrantRepository returns a new instance of a in-memory access grant repository.
+func NewGrantRepository() access.Repository {
+	return &grantRepository{
+		grants: make(map[access.Token]*access.Grant),
+	}
+}
+
 type handlingEventRepository struct {
 	mtx    sync.RWMutex
 	events map[cargo.TrackingID][]cargo.HandlingEvent
@@ -134,6 +608,16 @@ func (r *handlingEventRepository) QueryHandlingHistory(id cargo.TrackingID) carg
 	return cargo.HandlingHistory{HandlingEvents: r.events[id]}
 }
 
+func (r *handlingEventRepository) QueryHandlingHistories(ids []cargo.TrackingID) map[cargo.TrackingID]cargo.HandlingHistory {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	h := make(map[cargo.TrackingID]cargo.HandlingHistory, len(ids))
+	for _, id := range ids {
+		h[id] = cargo.HandlingHistory{HandlingEvents: r.events[id]}
+	}
+	return h
+}
+
 // NewHandlingEventRepository returns a new instance of a in-memory handling event repository.
 func NewHandlingEventRepository() cargo.HandlingEventRepository {
 	return &handlingEventRepository{
@@ -7,6 +7,35 @@ import "github.com/marcusolsson/goddd/cargo"
 type EventHandler interface {
 	CargoWasMisdirected(*cargo.Cargo)
 	CargoHasArrived(*cargo.Cargo)
+
+	// DeliveryWasUpdated is called after a changed delivery has been
+	// derived and stored for the cargo.
+	DeliveryWasUpdated(*cargo.Cargo)
+}
+
+// Handlers is an event handler that passes each event on to all of its
+// handlers, in order.
+type Handlers []EventHandler
+
+// CargoWasMisdirected implements the EventHandler interface.
+func (hs Handlers) CargoWasMisdirected(c *cargo.Cargo) {
+	for _, h := range hs {
+		h.CargoWasMisdirected(c)
+	}
+}
+
+// CargoHasArrived implements the EventHandler interface.
+func (hs Handlers) CargoHasArrived(c *cargo.Cargo) {
+	for _, h := range hs {
+		h.CargoHasArrived(c)
+	}
+}
+
+// DeliveryWasUpdated implements the EventHandler interface.
+func (hs Handlers) DeliveryWasUpdated(c *cargo.Cargo) {
+	for _, h := range hs {
+		h.DeliveryWasUpdated(c)
+	}
 }
 
 // Service provides cargo inspection operations.
@@ -32,17 +61,40 @@ func (s *service) InspectCargo(id cargo.TrackingID) {
 
 	h := s.events.QueryHandlingHistory(id)
 
+	prev := c.Delivery
+
 	c.DeriveDeliveryProgress(h)
 
-	if c.Delivery.IsMisdirected {
+	if err := s.cargos.Store(c); err != nil {
+		return
+	}
+
+	// Only tell about the cargo becoming misdirected or arriving, rather than
+	// every time it is inspected while it is.
+	if c.Delivery.IsMisdirected && !prev.IsMisdirected {
 		s.handler.CargoWasMisdirected(c)
 	}
 
-	if c.Delivery.IsUnloadedAtDestination {
+	if c.Delivery.IsUnloadedAtDestination && !prev.IsUnloadedAtDestination {
 		s.handler.CargoHasArrived(c)
 	}
 
-	s.cargos.Store(c)
+	if !sameDelivery(prev, c.Delivery) {
+		s.handler.DeliveryWasUpdated(c)
+	}
+}
+
+// sameDelivery compares the parts of a delivery that are visible to the
+// end-user.
+func sameDelivery(a, b cargo.Delivery) bool {
+	return a.TransportStatus == b.TransportStatus &&
+		a.RoutingStatus == b.RoutingStatus &&
+		a.LastKnownLocation == b.LastKnownLocation &&
+		a.CurrentVoyage == b.CurrentVoyage &&
+		a.IsMisdirected == b.IsMisdirected &&
+		a.IsUnloadedAtDestination == b.IsUnloadedAtDestination &&
+		a.NextExpectedActivity == b.NextExpectedActivity &&
+		a.ETA.Equal(b.ETA)
 }
 
 // NewService creates a inspection service with necessary dependencies.
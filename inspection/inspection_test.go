@@ -9,7 +9,8 @@ import (
 )
 
 type stubEventHandler struct {
-	events []interface{}
+	events  []interface{}
+	updates []*cargo.Cargo
 }
 
 func (h *stubEventHandler) CargoWasMisdirected(c *cargo.Cargo) {
@@ -20,6 +21,10 @@ func (h *stubEventHandler) CargoHasArrived(c *cargo.Cargo) {
 	h.events = append(h.events, c)
 }
 
+func (h *stubEventHandler) DeliveryWasUpdated(c *cargo.Cargo) {
+	h.updates = append(h.updates, c)
+}
+
 func TestInspectMisdirectedCargo(t *testing.T) {
 	var cargos mockCargoRepository
 
@@ -27,7 +32,7 @@ func TestInspectMisdirectedCargo(t *testing.T) {
 		events: make(map[cargo.TrackingID][]cargo.HandlingEvent),
 	}
 
-	handler := stubEventHandler{make([]interface{}, 0)}
+	handler := stubEventHandler{events: make([]interface{}, 0)}
 
 	s := NewService(&cargos, &events, &handler)
 
@@ -62,6 +67,13 @@ func TestInspectMisdirectedCargo(t *testing.T) {
 		t.Errorf("1 event should be handled")
 	}
 
+	// The cargo is still misdirected, which has already been handled.
+	s.InspectCargo(id)
+
+	if len(handler.events) != 1 {
+		t.Errorf("len(handler.events) = %d; want = %d", len(handler.events), 1)
+	}
+
 	s.InspectCargo("no_such_id")
 
 	// no events was published
@@ -77,7 +89,7 @@ func TestInspectUnloadedCargo(t *testing.T) {
 		events: make(map[cargo.TrackingID][]cargo.HandlingEvent),
 	}
 
-	handler := stubEventHandler{make([]interface{}, 0)}
+	handler := stubEventHandler{events: make([]interface{}, 0)}
 
 	s := &service{
 		cargos:  &cargos,
@@ -115,6 +127,53 @@ func TestInspectUnloadedCargo(t *testing.T) {
 	if len(handler.events) != 1 {
 		t.Errorf("len(handler.events) = %d; want = %d", len(handler.events), 1)
 	}
+
+	s.InspectCargo(id)
+
+	if len(handler.events) != 1 {
+		t.Errorf("len(handler.events) = %d; want = %d", len(handler.events), 1)
+	}
+}
+
+func TestInspectUpdatedDelivery(t *testing.T) {
+	var cargos mockCargoRepository
+
+	events := mockHandlingEventRepository{
+		events: make(map[cargo.TrackingID][]cargo.HandlingEvent),
+	}
+
+	var handler stubEventHandler
+
+	s := NewService(&cargos, &events, &handler)
+
+	id := cargo.TrackingID("ABC123")
+	c := cargo.New(id, cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.CNHKG,
+	})
+
+	cargos.Store(c)
+
+	s.InspectCargo(id)
+
+	if len(handler.updates) != 0 {
+		t.Errorf("len(handler.updates) = %d; want = %d", len(handler.updates), 0)
+	}
+
+	storeEvent(&events, id, "", cargo.Receive, location.SESTO)
+
+	s.InspectCargo(id)
+
+	if len(handler.updates) != 1 {
+		t.Errorf("len(handler.updates) = %d; want = %d", len(handler.updates), 1)
+	}
+
+	// Inspecting again without new events should not notify anyone.
+	s.InspectCargo(id)
+
+	if len(handler.updates) != 1 {
+		t.Errorf("len(handler.updates) = %d; want = %d", len(handler.updates), 1)
+	}
 }
 
 func storeEvent(r cargo.HandlingEventRepository, id cargo.TrackingID, voyageNumber voyage.Number, typ cargo.HandlingEventType, loc location.UNLocode) {
@@ -146,10 +205,45 @@ func (r *mockCargoRepository) Find(id cargo.TrackingID) (*cargo.Cargo, error) {
 	return nil, cargo.ErrUnknown
 }
 
+func (r *mockCargoRepository) FindMany(ids []cargo.TrackingID) ([]*cargo.Cargo, error) {
+	var cargos []*cargo.Cargo
+	for _, id := range ids {
+		if r.cargo != nil && r.cargo.TrackingID == id {
+			cargos = append(cargos, r.cargo)
+		}
+	}
+	return cargos, nil
+}
+
+func (r *mockCargoRepository) FindByReference(value string) ([]*cargo.Cargo, error) {
+	var cargos []*cargo.Cargo
+	if r.cargo != nil {
+		for _, ref := range r.cargo.References {
+			if ref.Value == cargo.NormalizeReference(value) {
+				cargos = append(cargos, r.cargo)
+				break
+			}
+		}
+	}
+	return cargos, nil
+}
+
 func (r *mockCargoRepository) FindAll() []*cargo.Cargo {
 	return []*cargo.Cargo{r.cargo}
 }
 
+func (r *mockCargoRepository) Query(q cargo.Query) (cargo.Page, error) {
+	return cargo.Page{Cargos: r.FindAll()}, nil
+}
+
+func (r *mockCargoRepository) Remove(id cargo.TrackingID) error {
+	if r.cargo == nil {
+		return cargo.ErrUnknown
+	}
+	r.cargo = nil
+	return nil
+}
+
 type mockHandlingEventRepository struct {
 	events map[cargo.TrackingID][]cargo.HandlingEvent
 }
@@ -164,3 +258,29 @@ func (r *mockHandlingEventRepository) Store(e cargo.HandlingEvent) {
 func (r *mockHandlingEventRepository) QueryHandlingHistory(id cargo.TrackingID) cargo.HandlingHistory {
 	return cargo.HandlingHistory{HandlingEvents: r.events[id]}
 }
+
+func (r *mockHandlingEventRepository) QueryHandlingHistories(ids []cargo.TrackingID) map[cargo.TrackingID]cargo.HandlingHistory {
+	h := make(map[cargo.TrackingID]cargo.HandlingHistory)
+	for _, id := range ids {
+		h[id] = r.QueryHandlingHistory(id)
+	}
+	return h
+}
+
+func TestHandlers(t *testing.T) {
+	var a, b stubEventHandler
+
+	h := Handlers{&a, &b}
+
+	c := cargo.New("ABC123", cargo.RouteSpecification{})
+
+	h.CargoWasMisdirected(c)
+	h.CargoHasArrived(c)
+	h.DeliveryWasUpdated(c)
+
+	for _, s := range []stubEventHandler{a, b} {
+		if len(s.events) != 2 || len(s.updates) != 1 {
+			t.Errorf("events = %d, updates = %d; want = 2, 1", len(s.events), len(s.updates))
+		}
+	}
+}
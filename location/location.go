@@ -1,7 +1,11 @@
 // Package location provides the Location aggregate.
 package location
 
-import "errors"
+import (
+	"errors"
+	"sync"
+	"time"
+)
 
 // UNLocode is the United Nations location code that uniquely identifies a
 // particular location.
@@ -15,8 +19,45 @@ type UNLocode string
 type Location struct {
 	UNLocode UNLocode
 	Name     string
+
+	// TimeZone is the IANA time zone name of the location, such as
+	// "Europe/Stockholm".
+	TimeZone string
+}
+
+// Zone returns the time zone of the location. Locations with an unknown time
+// zone use UTC.
+func (l *Location) Zone() *time.Location {
+	if l.TimeZone == "" {
+		return time.UTC
+	}
+
+	zonesMtx.RLock()
+	z, ok := zones[l.TimeZone]
+	zonesMtx.RUnlock()
+	if ok {
+		return z
+	}
+
+	z, err := time.LoadLocation(l.TimeZone)
+	if err != nil {
+		z = time.UTC
+	}
+
+	zonesMtx.Lock()
+	zones[l.TimeZone] = z
+	zonesMtx.Unlock()
+
+	return z
 }
 
+// zones caches the time zones by name, since loading one reads the time zone
+// database.
+var (
+	zones    = make(map[string]*time.Location)
+	zonesMtx sync.RWMutex
+)
+
 // ErrUnknown is used when a location could not be found.
 var ErrUnknown = errors.New("unknown location")
 
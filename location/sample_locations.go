@@ -15,13 +15,13 @@ var (
 
 // Sample locations.
 var (
-	Stockholm = &Location{SESTO, "Stockholm"}
-	Melbourne = &Location{AUMEL, "Melbourne"}
-	Hongkong  = &Location{CNHKG, "Hongkong"}
-	NewYork   = &Location{USNYC, "New York"}
-	Chicago   = &Location{USCHI, "Chicago"}
-	Tokyo     = &Location{JNTKO, "Tokyo"}
-	Hamburg   = &Location{DEHAM, "Hamburg"}
-	Rotterdam = &Location{NLRTM, "Rotterdam"}
-	Helsinki  = &Location{FIHEL, "Helsinki"}
+	Stockholm = &Location{SESTO, "Stockholm", "Europe/Stockholm"}
+	Melbourne = &Location{AUMEL, "Melbourne", "Australia/Melbourne"}
+	Hongkong  = &Location{CNHKG, "Hongkong", "Asia/Hong_Kong"}
+	NewYork   = &Location{USNYC, "New York", "America/New_York"}
+	Chicago   = &Location{USCHI, "Chicago", "America/Chicago"}
+	Tokyo     = &Location{JNTKO, "Tokyo", "Asia/Tokyo"}
+	Hamburg   = &Location{DEHAM, "Hamburg", "Europe/Berlin"}
+	Rotterdam = &Location{NLRTM, "Rotterdam", "Europe/Amsterdam"}
+	Helsinki  = &Location{FIHEL, "Helsinki", "Europe/Helsinki"}
 )
@@ -3,9 +3,12 @@ package main
 import (
 	"flag"
 	"fmt"
+	"net"
 	"net/http"
+	"net/smtp"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -15,20 +18,34 @@ import (
 	stdprometheus "github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/net/context"
 
+	"github.com/go-kit/kit/loadbalancer"
+	"github.com/go-kit/kit/loadbalancer/dnssrv"
+	"github.com/go-kit/kit/loadbalancer/static"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/metrics"
 	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
 
+	"github.com/marcusolsson/goddd/access"
+	"github.com/marcusolsson/goddd/allotment"
 	"github.com/marcusolsson/goddd/booking"
 	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/dispatching"
+	"github.com/marcusolsson/goddd/document"
+	"github.com/marcusolsson/goddd/eventbus"
+	"github.com/marcusolsson/goddd/graph"
 	"github.com/marcusolsson/goddd/handling"
 	"github.com/marcusolsson/goddd/inmem"
 	"github.com/marcusolsson/goddd/inspection"
 	"github.com/marcusolsson/goddd/location"
 	"github.com/marcusolsson/goddd/mongo"
+	"github.com/marcusolsson/goddd/notification"
 	"github.com/marcusolsson/goddd/routing"
+	"github.com/marcusolsson/goddd/subscribing"
+	"github.com/marcusolsson/goddd/task"
 	"github.com/marcusolsson/goddd/tracking"
 	"github.com/marcusolsson/goddd/voyage"
+	"github.com/marcusolsson/goddd/webhook"
 )
 
 const (
@@ -46,11 +63,34 @@ func main() {
 		dbname = envString("DB_NAME", defaultDBName)
 
 		httpAddr          = flag.String("http.addr", ":"+addr, "HTTP listen address")
-		routingServiceURL = flag.String("service.routing", rsurl, "routing service URL")
+		operatorAddr      = flag.String("operator.addr", "localhost:8081", "HTTP listen address for operators, issuing share tokens and sessions and listing subscriptions")
+		routingServiceURL = flag.String("service.routing", rsurl, "routing service URL, or a comma-separated list of URLs")
 		mongoDBURL        = flag.String("db.url", dburl, "MongoDB URL")
 		databaseName      = flag.String("db.name", dbname, "MongoDB database name")
 		inmemory          = flag.Bool("inmem", false, "use in-memory repositories")
 
+		routingEngine       = flag.String("routing.engine", "pathfinder", "routing engine, either pathfinder or builtin")
+		routingSRV          = flag.String("routing.srv", "", "DNS SRV record of the routing service instances, instead of their URLs")
+		routingFile         = flag.String("routing.file", "", "file listing the routing service instances, one per line, instead of their URLs")
+		routingRetries      = flag.Int("routing.retries", 3, "attempts at fetching routes, each from the next routing service instance")
+		routingTimeout      = flag.Duration("routing.timeout", 3*time.Second, "time to fetch routes, including retries")
+		routingPaths        = flag.Int("routing.paths", routing.DefaultPaths, "number of routes found by the builtin routing engine")
+		connectionTime      = flag.Duration("routing.connection", routing.DefaultConnectionTime, "least time to transship cargo between voyages in the builtin routing engine")
+		routeCacheTTL       = flag.Duration("routing.cache.ttl", 5*time.Minute, "time routes are cached before fetched again, or zero to disable caching")
+		routeCacheSize      = flag.Int("routing.cache.size", 1000, "number of route specifications to cache routes for")
+		transitWeight       = flag.Float64("routing.weight.transit", routing.DefaultWeights.TransitTime, "route score penalty per day of transit")
+		transshipmentWeight = flag.Float64("routing.weight.transshipment", routing.DefaultWeights.Transshipments, "route score penalty per transshipment")
+		slackWeight         = flag.Float64("routing.weight.slack", routing.DefaultWeights.Slack, "route score bonus per day of slack before deadline")
+		autoRoute           = flag.Bool("booking.autoroute", false, "route new cargos when booked unless requested otherwise")
+		deadlineHorizon     = flag.Duration("dispatching.horizon", 72*time.Hour, "time before arrival deadline at which cargos need attention")
+		webhookAttempts     = flag.Int("subscribing.attempts", webhook.DefaultBackoff.MaxAttempts, "attempts at delivering a webhook before dead-lettering it")
+		webhookBackoff      = flag.Duration("subscribing.backoff", webhook.DefaultBackoff.Initial, "wait after the first failed webhook delivery, doubled for each attempt")
+		smtpAddr            = flag.String("notification.smtp", "", "SMTP relay to email notifications through, as host:port")
+		smtpFrom            = flag.String("notification.from", "noreply@goddd.example", "sender address of emailed notifications")
+		notificationWebhook = flag.String("notification.webhook", "", "URL to post notifications to, for customers preferring webhooks")
+		notificationDir     = flag.String("notification.templates", "", "directory of notification templates overriding the default texts")
+		notificationDefault = flag.String("notification.channels", "log", "comma-separated channels for customers without a preference")
+
 		ctx = context.Background()
 	)
 
@@ -67,6 +107,13 @@ func main() {
 		locations      location.Repository
 		voyages        voyage.Repository
 		handlingEvents cargo.HandlingEventRepository
+		customers      customer.Repository
+		allotments     allotment.Repository
+		tasks          task.Repository
+		documents      document.Repository
+		subscriptions  webhook.SubscriptionRepository
+		deliveries     webhook.DeliveryRepository
+		grants         access.Repository
 	)
 
 	if *inmemory {
@@ -74,6 +121,13 @@ func main() {
 		locations = inmem.NewLocationRepository()
 		voyages = inmem.NewVoyageRepository()
 		handlingEvents = inmem.NewHandlingEventRepository()
+		customers = inmem.NewCustomerRepository()
+		allotments = inmem.NewAllotmentRepository()
+		tasks = inmem.NewTaskRepository()
+		documents = inmem.NewDocumentRepository()
+		subscriptions = inmem.NewSubscriptionRepository()
+		deliveries = inmem.NewDeliveryRepository()
+		grants = inmem.NewGrantRepository()
 	} else {
 		session, err := mgo.Dial(*mongoDBURL)
 		if err != nil {
@@ -87,6 +141,13 @@ func main() {
 		locations, _ = mongo.NewLocationRepository(*databaseName, session)
 		voyages, _ = mongo.NewVoyageRepository(*databaseName, session)
 		handlingEvents = mongo.NewHandlingEventRepository(*databaseName, session)
+		customers, _ = mongo.NewCustomerRepository(*databaseName, session)
+		allotments, _ = mongo.NewAllotmentRepository(*databaseName, session)
+		tasks, _ = mongo.NewTaskRepository(*databaseName, session)
+		documents, _ = mongo.NewDocumentRepository(*databaseName, session)
+		subscriptions, _ = mongo.NewSubscriptionRepository(*databaseName, session)
+		deliveries, _ = mongo.NewDeliveryRepository(*databaseName, session)
+		grants, _ = mongo.NewGrantRepository(*databaseName, session)
 	}
 
 	// Configure some questionable dependencies.
@@ -96,8 +157,27 @@ func main() {
 			VoyageRepository:   voyages,
 			LocationRepository: locations,
 		}
+		billOfLadingIssuer = document.Issuer{
+			DocumentRepository: documents,
+			LocationRepository: locations,
+			VoyageRepository:   voyages,
+			CustomerRepository: customers,
+		}
+		webhookDeliverer = subscribing.NewDeliverer(
+			subscriptions,
+			deliveries,
+			subscribing.NewCallbackClient(10*time.Second),
+			webhook.Backoff{
+				Initial:     *webhookBackoff,
+				Max:         webhook.DefaultBackoff.Max,
+				MaxAttempts: *webhookAttempts,
+			},
+			log.NewContext(logger).With("component", "webhook"),
+		)
+		notifier             = newNotifier(customers, *smtpAddr, *smtpFrom, *notificationWebhook, *notificationDir, *notificationDefault, log.NewContext(logger).With("component", "notification"))
+		cargoEvents          = eventbus.New()
 		handlingEventHandler = handling.NewEventHandler(
-			inspection.NewService(cargos, handlingEvents, nil),
+			inspection.NewService(cargos, handlingEvents, inspection.Handlers{webhookDeliverer, notifier}),
 		)
 	)
 
@@ -107,10 +187,56 @@ func main() {
 	fieldKeys := []string{"method"}
 
 	var rs routing.Service
-	rs = routing.NewProxyingMiddleware(*routingServiceURL, ctx)(rs)
+	switch *routingEngine {
+	case "pathfinder":
+		routingLogger := log.NewContext(logger).With("component", "routing")
+
+		// The circuit breakers are given the whole time, so that a slow
+		// pathfinder is reported as timing out rather than unavailable.
+		factory := routing.NewFactory(*routingTimeout)
+
+		var instances loadbalancer.Publisher
+		switch {
+		case *routingFile != "":
+			instances = routing.NewFilePublisher(*routingFile, 10*time.Second, factory, routingLogger)
+		case *routingSRV != "":
+			instances = dnssrv.NewPublisher(*routingSRV, 30*time.Second, factory, routingLogger)
+		default:
+			instances = static.NewPublisher(strings.Split(*routingServiceURL, ","), factory, routingLogger)
+		}
+
+		rs = routing.NewProxyingMiddleware(instances, *routingRetries, *routingTimeout, ctx)(rs)
+	case "builtin":
+		rs = routing.NewEngine(voyages, *routingPaths, *connectionTime)
+	default:
+		logger.Log("routing_engine", *routingEngine, "err", "unknown routing engine")
+		os.Exit(1)
+	}
+
+	var routeCache *routing.Cache
+	if *routeCacheTTL > 0 {
+		routeCache = routing.NewCache(*routeCacheTTL, *routeCacheSize)
+		rs = routing.NewCachingMiddleware(routeCache)(rs)
+	}
+
+	rs = routing.NewConstrainingMiddleware(voyages)(rs)
+
+	rk := routing.NewRanker(routing.Weights{
+		TransitTime:    *transitWeight,
+		Transshipments: *transshipmentWeight,
+		Slack:          *slackWeight,
+	})
 
 	var bs booking.Service
-	bs = booking.NewService(cargos, locations, handlingEvents, rs)
+	bs = booking.NewService(cargos, locations, voyages, billOfLadingIssuer, grants, booking.Options{
+		Customers:  customers,
+		Allotments: allotments,
+		Routing:    rs,
+		Ranker:     rk,
+		Policy:     routing.HighestRanked,
+		AutoRoute:  *autoRoute,
+	})
+	bs = booking.NewPublishingService(cargoEvents, bs)
 	bs = booking.NewLoggingService(log.NewContext(logger).With("component", "booking"), bs)
 	bs = booking.NewInstrumentingService(
 		kitprometheus.NewCounter(stdprometheus.CounterOpts{
@@ -118,7 +244,7 @@ func main() {
 			Subsystem: "booking_service",
 			Name:      "request_count",
 			Help:      "Number of requests received.",
-		}, fieldKeys),
+		}, []string{"method", "error"}),
 		metrics.NewTimeHistogram(time.Microsecond, kitprometheus.NewSummary(stdprometheus.SummaryOpts{
 			Namespace: "api",
 			Subsystem: "booking_service",
@@ -127,7 +253,7 @@ func main() {
 		}, fieldKeys)), bs)
 
 	var ts tracking.Service
-	ts = tracking.NewService(cargos, handlingEvents)
+	ts = tracking.NewService(cargos, handlingEvents, locations, grants)
 	ts = tracking.NewLoggingService(log.NewContext(logger).With("component", "tracking"), ts)
 	ts = tracking.NewInstrumentingService(
 		kitprometheus.NewCounter(stdprometheus.CounterOpts{
@@ -145,6 +271,7 @@ func main() {
 
 	var hs handling.Service
 	hs = handling.NewService(handlingEvents, handlingEventFactory, handlingEventHandler)
+	hs = handling.NewPublishingService(cargoEvents, hs)
 	hs = handling.NewLoggingService(log.NewContext(logger).With("component", "handling"), hs)
 	hs = handling.NewInstrumentingService(
 		kitprometheus.NewCounter(stdprometheus.CounterOpts{
@@ -160,22 +287,98 @@ func main() {
 			Help:      "Total duration of requests in microseconds.",
 		}, fieldKeys)), hs)
 
+	var ds dispatching.Service
+	ds = dispatching.NewService(cargos, tasks, *deadlineHorizon)
+	ds = dispatching.NewLoggingService(log.NewContext(logger).With("component", "dispatching"), ds)
+	ds = dispatching.NewInstrumentingService(
+		kitprometheus.NewCounter(stdprometheus.CounterOpts{
+			Namespace: "api",
+			Subsystem: "dispatching_service",
+			Name:      "request_count",
+			Help:      "Number of requests received.",
+		}, fieldKeys),
+		metrics.NewTimeHistogram(time.Microsecond, kitprometheus.NewSummary(stdprometheus.SummaryOpts{
+			Namespace: "api",
+			Subsystem: "dispatching_service",
+			Name:      "request_latency_microseconds",
+			Help:      "Total duration of requests in microseconds.",
+		}, fieldKeys)), ds)
+
+	var ss subscribing.Service
+	ss = subscribing.NewService(cargos, customers, subscriptions, deliveries, grants)
+	ss = subscribing.NewLoggingService(log.NewContext(logger).With("component", "subscribing"), ss)
+	ss = subscribing.NewInstrumentingService(
+		kitprometheus.NewCounter(stdprometheus.CounterOpts{
+			Namespace: "api",
+			Subsystem: "subscribing_service",
+			Name:      "request_count",
+			Help:      "Number of requests received.",
+		}, fieldKeys),
+		metrics.NewTimeHistogram(time.Microsecond, kitprometheus.NewSummary(stdprometheus.SummaryOpts{
+			Namespace: "api",
+			Subsystem: "subscribing_service",
+			Name:      "request_latency_microseconds",
+			Help:      "Total duration of requests in microseconds.",
+		}, fieldKeys)), ss)
+
+	var gs graph.Service
+	gs = graph.NewService(bs, hs, ts, handlingEvents, voyages)
+	gs = graph.NewLoggingService(log.NewContext(logger).With("component", "graph"), gs)
+	gs = graph.NewInstrumentingService(
+		kitprometheus.NewCounter(stdprometheus.CounterOpts{
+			Namespace: "api",
+			Subsystem: "graph_service",
+			Name:      "request_count",
+			Help:      "Number of requests received.",
+		}, fieldKeys),
+		metrics.NewTimeHistogram(time.Microsecond, kitprometheus.NewSummary(stdprometheus.SummaryOpts{
+			Namespace: "api",
+			Subsystem: "graph_service",
+			Name:      "request_latency_microseconds",
+			Help:      "Total duration of requests in microseconds.",
+		}, fieldKeys)), gs)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go webhookDeliverer.Run(5*time.Second, done)
+	go notifier.Run(done)
+
+	if routeCache != nil {
+		go routeCache.Watch(voyages, time.Minute, done)
+	}
+
 	httpLogger := log.NewContext(logger).With("component", "http")
 
 	mux := http.NewServeMux()
 
 	mux.Handle("/booking/v1/", booking.MakeHandler(ctx, bs, httpLogger))
-	mux.Handle("/tracking/v1/", tracking.MakeHandler(ctx, ts, httpLogger))
+	mux.Handle("/tracking/v1/", tracking.MakeHandler(ctx, ts, cargoEvents, httpLogger))
 	mux.Handle("/handling/v1/", handling.MakeHandler(ctx, hs, httpLogger))
+	mux.Handle("/dispatching/v1/", dispatching.MakeHandler(ctx, ds, httpLogger))
+	mux.Handle("/subscribing/v1/", subscribing.MakeHandler(ctx, ss, httpLogger))
+	mux.Handle("/graph/v1/", graph.MakeHandler(ctx, gs, httpLogger))
 
 	http.Handle("/", accessControl(mux))
 	http.Handle("/metrics", stdprometheus.Handler())
 
-	errs := make(chan error, 2)
+	// Share tokens and sessions give access to tracking information, and
+	// listings span every customer, so they are served on a listener of
+	// their own, kept from the public.
+	operatorMux := http.NewServeMux()
+
+	operatorMux.Handle("/booking/v1/", booking.MakeOperatorHandler(ctx, bs, httpLogger))
+	operatorMux.Handle("/subscribing/v1/", subscribing.MakeOperatorHandler(ctx, ss, httpLogger))
+
+	errs := make(chan error, 3)
 	go func() {
 		logger.Log("transport", "http", "address", *httpAddr, "msg", "listening")
 		errs <- http.ListenAndServe(*httpAddr, nil)
 	}()
+	go func() {
+		logger.Log("transport", "http", "address", *operatorAddr, "msg", "listening for operators")
+		errs <- http.ListenAndServe(*operatorAddr, operatorMux)
+	}()
 	go func() {
 		c := make(chan os.Signal)
 		signal.Notify(c, syscall.SIGINT)
@@ -188,8 +391,8 @@ func main() {
 func accessControl(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization")
 
 		if r.Method == "OPTIONS" {
 			return
@@ -199,6 +402,49 @@ func accessControl(h http.Handler) http.Handler {
 	})
 }
 
+// newNotifier returns a notifier sending over the log and whichever of the
+// email and webhook channels have been configured. Relay credentials are read
+// from SMTP_USERNAME and SMTP_PASSWORD, if set.
+func newNotifier(customers customer.Repository, smtpAddr, from, webhookURL, dir, defaults string, logger log.Logger) *notification.Notifier {
+	channels := map[customer.Channel]notification.Channel{
+		customer.Log: notification.NewLogChannel(logger),
+	}
+
+	if smtpAddr != "" {
+		var auth smtp.Auth
+		if user := os.Getenv("SMTP_USERNAME"); user != "" {
+			host, _, _ := net.SplitHostPort(smtpAddr)
+			auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)
+		}
+		channels[customer.Email] = notification.NewSMTPChannel(smtpAddr, from, auth)
+	}
+
+	if webhookURL != "" {
+		channels[customer.Webhook] = notification.NewWebhookChannel(webhookURL, &http.Client{Timeout: 10 * time.Second})
+	}
+
+	var defaultChannels []customer.Channel
+	for _, s := range strings.Split(defaults, ",") {
+		c, err := customer.ParseChannel(strings.TrimSpace(s))
+		if err != nil {
+			logger.Log("channel", s, "err", err)
+			os.Exit(1)
+		}
+		defaultChannels = append(defaultChannels, c)
+	}
+
+	templates, err := notification.NewTemplates(nil)
+	if dir != "" {
+		templates, err = notification.LoadTemplates(dir)
+	}
+	if err != nil {
+		logger.Log("templates", dir, "err", err)
+		os.Exit(1)
+	}
+
+	return notification.NewNotifier(customers, templates, channels, defaultChannels, logger)
+}
+
 func envString(env, fallback string) string {
 	e := os.Getenv(env)
 	if e == "" {
@@ -208,10 +454,11 @@ func envString(env, fallback string) string {
 }
 
 func storeTestData(r cargo.Repository) {
+	// The sample cargos can be routed on the sample voyages in time.
 	test1 := cargo.New("FTL456", cargo.RouteSpecification{
-		Origin:          location.AUMEL,
+		Origin:          location.CNHKG,
 		Destination:     location.SESTO,
-		ArrivalDeadline: time.Now().AddDate(0, 0, 7),
+		ArrivalDeadline: time.Now().AddDate(0, 0, 45),
 	})
 	if err := r.Store(test1); err != nil {
 		panic(err)
@@ -219,8 +466,8 @@ func storeTestData(r cargo.Repository) {
 
 	test2 := cargo.New("ABC123", cargo.RouteSpecification{
 		Origin:          location.SESTO,
-		Destination:     location.CNHKG,
-		ArrivalDeadline: time.Now().AddDate(0, 0, 14),
+		Destination:     location.AUMEL,
+		ArrivalDeadline: time.Now().AddDate(0, 0, 60),
 	})
 	if err := r.Store(test2); err != nil {
 		panic(err)
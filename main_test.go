@@ -8,10 +8,12 @@ import (
 
 	"github.com/marcusolsson/goddd/booking"
 	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/document"
 	"github.com/marcusolsson/goddd/handling"
 	"github.com/marcusolsson/goddd/inmem"
 	"github.com/marcusolsson/goddd/inspection"
 	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/routing"
 	"github.com/marcusolsson/goddd/voyage"
 )
 
@@ -43,8 +45,15 @@ func (s *S) TestCargoFromHongkongToStockholm(chk *C) {
 	cargoInspectionService := inspection.NewService(cargoRepository, handlingEventRepository, cargoEventHandler)
 	handlingEventHandler := &stubHandlingEventHandler{cargoInspectionService}
 
+	billOfLadingIssuer := document.Issuer{
+		DocumentRepository: inmem.NewDocumentRepository(),
+		LocationRepository: locationRepository,
+		VoyageRepository:   voyageRepository,
+		CustomerRepository: inmem.NewCustomerRepository(),
+	}
+
 	var (
-		bookingService       = booking.NewService(cargoRepository, locationRepository, handlingEventRepository, routingService)
+		bookingService       = booking.NewService(cargoRepository, locationRepository, voyageRepository, billOfLadingIssuer, inmem.NewGrantRepository(), booking.Options{Customers: inmem.NewCustomerRepository(), Allotments: inmem.NewAllotmentRepository(), Routing: routingService})
 		handlingEventService = handling.NewService(handlingEventRepository, handlingEventFactory, handlingEventHandler)
 	)
 
@@ -58,10 +67,12 @@ func (s *S) TestCargoFromHongkongToStockholm(chk *C) {
 	// Use case 1: booking
 	//
 
-	id, err := bookingService.BookNewCargo(origin, destination, deadline)
+	b, err := bookingService.BookNewCargo(origin, destination, deadline, booking.AutoRouteDefault)
 
 	chk.Assert(err, IsNil)
 
+	id := b.TrackingID
+
 	c, err := cargoRepository.Find(id)
 
 	chk.Assert(err, IsNil)
@@ -76,7 +87,8 @@ func (s *S) TestCargoFromHongkongToStockholm(chk *C) {
 	// Use case 2: routing
 	//
 
-	itineraries := bookingService.RequestPossibleRoutesForCargo(id)
+	itineraries, err := bookingService.RequestPossibleRoutesForCargo(id, routing.Constraints{})
+	chk.Assert(err, IsNil)
 	itinerary := selectPreferredItinerary(itineraries)
 
 	c.AssignToRoute(itinerary)
@@ -164,7 +176,8 @@ func (s *S) TestCargoFromHongkongToStockholm(chk *C) {
 	chk.Check(c.Delivery.NextExpectedActivity, Equals, cargo.HandlingActivity{})
 
 	// Repeat procedure of selecting one out of a number of possible routes satisfying the route spec
-	newItineraries := bookingService.RequestPossibleRoutesForCargo(id)
+	newItineraries, err := bookingService.RequestPossibleRoutesForCargo(id, routing.Constraints{})
+	chk.Assert(err, IsNil)
 	newItinerary := selectPreferredItinerary(newItineraries)
 
 	c.AssignToRoute(newItinerary)
@@ -238,8 +251,8 @@ func (s *S) TestCargoFromHongkongToStockholm(chk *C) {
 	chk.Check(c.Delivery.NextExpectedActivity, Equals, cargo.HandlingActivity{})
 }
 
-func selectPreferredItinerary(itineraries []cargo.Itinerary) cargo.Itinerary {
-	return itineraries[0]
+func selectPreferredItinerary(candidates []routing.Candidate) cargo.Itinerary {
+	return candidates[0].Itinerary
 }
 
 func toDate(year int, month time.Month, day int) time.Time {
@@ -249,7 +262,7 @@ func toDate(year int, month time.Month, day int) time.Time {
 // Stub RoutingService
 type stubRoutingService struct{}
 
-func (s *stubRoutingService) FetchRoutesForSpecification(rs cargo.RouteSpecification) []cargo.Itinerary {
+func (s *stubRoutingService) FetchRoutesForSpecification(rs cargo.RouteSpecification, c routing.Constraints) ([]cargo.Itinerary, error) {
 	if rs.Origin == location.CNHKG {
 		return []cargo.Itinerary{
 			{Legs: []cargo.Leg{
@@ -257,7 +270,7 @@ func (s *stubRoutingService) FetchRoutesForSpecification(rs cargo.RouteSpecifica
 				cargo.NewLeg("V200", location.USNYC, location.USCHI, toDate(2009, time.March, 10), toDate(2009, time.March, 14)),
 				cargo.NewLeg("V300", location.USCHI, location.SESTO, toDate(2009, time.March, 7), toDate(2009, time.March, 11)),
 			}},
-		}
+		}, nil
 	}
 
 	return []cargo.Itinerary{
@@ -265,7 +278,7 @@ func (s *stubRoutingService) FetchRoutesForSpecification(rs cargo.RouteSpecifica
 			cargo.NewLeg("V300", location.JNTKO, location.DEHAM, toDate(2009, time.March, 8), toDate(2009, time.March, 12)),
 			cargo.NewLeg("V400", location.DEHAM, location.SESTO, toDate(2009, time.March, 14), toDate(2009, time.March, 15)),
 		}},
-	}
+	}, nil
 }
 
 // Stub HandlingEventHandler
@@ -286,3 +299,6 @@ func (h *stubCargoEventHandler) CargoWasMisdirected(c *cargo.Cargo) {
 
 func (h *stubCargoEventHandler) CargoHasArrived(c *cargo.Cargo) {
 }
+
+func (h *stubCargoEventHandler) DeliveryWasUpdated(c *cargo.Cargo) {
+}
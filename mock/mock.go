@@ -1,9 +1,18 @@
 package mock
 
 import (
+	"time"
+
+	"github.com/marcusolsson/goddd/access"
+	"github.com/marcusolsson/goddd/allotment"
 	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/document"
 	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/routing"
+	"github.com/marcusolsson/goddd/task"
 	"github.com/marcusolsson/goddd/voyage"
+	"github.com/marcusolsson/goddd/webhook"
 )
 
 // CargoRepository is a mock cargo repository.
@@ -14,8 +23,20 @@ type CargoRepository struct {
 	FindFn      func(id cargo.TrackingID) (*cargo.Cargo, error)
 	FindInvoked bool
 
+	FindManyFn      func(ids []cargo.TrackingID) ([]*cargo.Cargo, error)
+	FindManyInvoked bool
+
+	FindByReferenceFn      func(value string) ([]*cargo.Cargo, error)
+	FindByReferenceInvoked bool
+
 	FindAllFn      func() []*cargo.Cargo
 	FindAllInvoked bool
+
+	QueryFn      func(q cargo.Query) (cargo.Page, error)
+	QueryInvoked bool
+
+	RemoveFn      func(id cargo.TrackingID) error
+	RemoveInvoked bool
 }
 
 // Store calls the StoreFn.
@@ -30,12 +51,36 @@ func (r *CargoRepository) Find(id cargo.TrackingID) (*cargo.Cargo, error) {
 	return r.FindFn(id)
 }
 
+// FindMany calls the FindManyFn.
+func (r *CargoRepository) FindMany(ids []cargo.TrackingID) ([]*cargo.Cargo, error) {
+	r.FindManyInvoked = true
+	return r.FindManyFn(ids)
+}
+
+// FindByReference calls the FindByReferenceFn.
+func (r *CargoRepository) FindByReference(value string) ([]*cargo.Cargo, error) {
+	r.FindByReferenceInvoked = true
+	return r.FindByReferenceFn(value)
+}
+
 // FindAll calls the FindAllFn.
 func (r *CargoRepository) FindAll() []*cargo.Cargo {
 	r.FindAllInvoked = true
 	return r.FindAllFn()
 }
 
+// Query calls the QueryFn.
+func (r *CargoRepository) Query(q cargo.Query) (cargo.Page, error) {
+	r.QueryInvoked = true
+	return r.QueryFn(q)
+}
+
+// Remove calls the RemoveFn.
+func (r *CargoRepository) Remove(id cargo.TrackingID) error {
+	r.RemoveInvoked = true
+	return r.RemoveFn(id)
+}
+
 // LocationRepository is a mock location repository.
 type LocationRepository struct {
 	FindFn      func(location.UNLocode) (*location.Location, error)
@@ -61,6 +106,12 @@ func (r *LocationRepository) FindAll() []*location.Location {
 type VoyageRepository struct {
 	FindFn      func(voyage.Number) (*voyage.Voyage, error)
 	FindInvoked bool
+
+	FindManyFn      func([]voyage.Number) ([]*voyage.Voyage, error)
+	FindManyInvoked bool
+
+	FindAllFn      func() []*voyage.Voyage
+	FindAllInvoked bool
 }
 
 // Find calls the FindFn.
@@ -69,6 +120,264 @@ func (r *VoyageRepository) Find(number voyage.Number) (*voyage.Voyage, error) {
 	return r.FindFn(number)
 }
 
+// FindMany calls the FindManyFn.
+func (r *VoyageRepository) FindMany(numbers []voyage.Number) ([]*voyage.Voyage, error) {
+	r.FindManyInvoked = true
+	return r.FindManyFn(numbers)
+}
+
+// FindAll calls the FindAllFn.
+func (r *VoyageRepository) FindAll() []*voyage.Voyage {
+	r.FindAllInvoked = true
+	return r.FindAllFn()
+}
+
+// CustomerRepository is a mock customer repository.
+type CustomerRepository struct {
+	StoreFn      func(c *customer.Customer) error
+	StoreInvoked bool
+
+	FindFn      func(id customer.ID) (*customer.Customer, error)
+	FindInvoked bool
+
+	FindAllFn      func() []*customer.Customer
+	FindAllInvoked bool
+}
+
+// Store calls the StoreFn.
+func (r *CustomerRepository) Store(c *customer.Customer) error {
+	r.StoreInvoked = true
+	return r.StoreFn(c)
+}
+
+// Find calls the FindFn.
+func (r *CustomerRepository) Find(id customer.ID) (*customer.Customer, error) {
+	r.FindInvoked = true
+	return r.FindFn(id)
+}
+
+// FindAll calls the FindAllFn.
+func (r *CustomerRepository) FindAll() []*customer.Customer {
+	r.FindAllInvoked = true
+	return r.FindAllFn()
+}
+
+// AllotmentRepository is a mock allotment repository.
+type AllotmentRepository struct {
+	StoreFn      func(a *allotment.Allotment) error
+	StoreInvoked bool
+
+	FindFn      func(id allotment.ID) (*allotment.Allotment, error)
+	FindInvoked bool
+
+	FindByCustomerFn      func(c customer.ID) []*allotment.Allotment
+	FindByCustomerInvoked bool
+
+	FindAllFn      func() []*allotment.Allotment
+	FindAllInvoked bool
+}
+
+// Store calls the StoreFn.
+func (r *AllotmentRepository) Store(a *allotment.Allotment) error {
+	r.StoreInvoked = true
+	return r.StoreFn(a)
+}
+
+// Find calls the FindFn.
+func (r *AllotmentRepository) Find(id allotment.ID) (*allotment.Allotment, error) {
+	r.FindInvoked = true
+	return r.FindFn(id)
+}
+
+// FindByCustomer calls the FindByCustomerFn.
+func (r *AllotmentRepository) FindByCustomer(c customer.ID) []*allotment.Allotment {
+	r.FindByCustomerInvoked = true
+	return r.FindByCustomerFn(c)
+}
+
+// FindAll calls the FindAllFn.
+func (r *AllotmentRepository) FindAll() []*allotment.Allotment {
+	r.FindAllInvoked = true
+	return r.FindAllFn()
+}
+
+// TaskRepository is a mock task repository.
+type TaskRepository struct {
+	StoreFn      func(t *task.Task) error
+	StoreInvoked bool
+
+	FindFn      func(id cargo.TrackingID) (*task.Task, error)
+	FindInvoked bool
+
+	FindAllFn      func() []*task.Task
+	FindAllInvoked bool
+}
+
+// Store calls the StoreFn.
+func (r *TaskRepository) Store(t *task.Task) error {
+	r.StoreInvoked = true
+	return r.StoreFn(t)
+}
+
+// Find calls the FindFn.
+func (r *TaskRepository) Find(id cargo.TrackingID) (*task.Task, error) {
+	r.FindInvoked = true
+	return r.FindFn(id)
+}
+
+// FindAll calls the FindAllFn.
+func (r *TaskRepository) FindAll() []*task.Task {
+	r.FindAllInvoked = true
+	return r.FindAllFn()
+}
+
+// DocumentRepository is a mock document repository.
+type DocumentRepository struct {
+	StoreFn      func(b *document.BillOfLading) error
+	StoreInvoked bool
+
+	FindFn      func(id cargo.TrackingID, version int) (*document.BillOfLading, error)
+	FindInvoked bool
+
+	FindByCargoFn      func(id cargo.TrackingID) []*document.BillOfLading
+	FindByCargoInvoked bool
+}
+
+// Store calls the StoreFn.
+func (r *DocumentRepository) Store(b *document.BillOfLading) error {
+	r.StoreInvoked = true
+	return r.StoreFn(b)
+}
+
+// Find calls the FindFn.
+func (r *DocumentRepository) Find(id cargo.TrackingID, version int) (*document.BillOfLading, error) {
+	r.FindInvoked = true
+	return r.FindFn(id, version)
+}
+
+// FindByCargo calls the FindByCargoFn.
+func (r *DocumentRepository) FindByCargo(id cargo.TrackingID) []*document.BillOfLading {
+	r.FindByCargoInvoked = true
+	return r.FindByCargoFn(id)
+}
+
+// GrantRepository is a mock access grant repository.
+type GrantRepository struct {
+	StoreFn      func(g *access.Grant) error
+	StoreInvoked bool
+
+	FindFn      func(t access.Token) (*access.Grant, error)
+	FindInvoked bool
+
+	FindByCargoFn      func(id cargo.TrackingID) []*access.Grant
+	FindByCargoInvoked bool
+}
+
+// Store calls the StoreFn.
+func (r *GrantRepository) Store(g *access.Grant) error {
+	r.StoreInvoked = true
+	return r.StoreFn(g)
+}
+
+// Find calls the FindFn.
+func (r *GrantRepository) Find(t access.Token) (*access.Grant, error) {
+	r.FindInvoked = true
+	return r.FindFn(t)
+}
+
+// FindByCargo calls the FindByCargoFn.
+func (r *GrantRepository) FindByCargo(id cargo.TrackingID) []*access.Grant {
+	r.FindByCargoInvoked = true
+	return r.FindByCargoFn(id)
+}
+
+// SubscriptionRepository is a mock subscription repository.
+type SubscriptionRepository struct {
+	StoreFn      func(s *webhook.Subscription) error
+	StoreInvoked bool
+
+	FindFn      func(id webhook.SubscriptionID) (*webhook.Subscription, error)
+	FindInvoked bool
+
+	FindAllFn      func() []*webhook.Subscription
+	FindAllInvoked bool
+
+	RemoveFn      func(id webhook.SubscriptionID) error
+	RemoveInvoked bool
+}
+
+// Store calls the StoreFn.
+func (r *SubscriptionRepository) Store(s *webhook.Subscription) error {
+	r.StoreInvoked = true
+	return r.StoreFn(s)
+}
+
+// Find calls the FindFn.
+func (r *SubscriptionRepository) Find(id webhook.SubscriptionID) (*webhook.Subscription, error) {
+	r.FindInvoked = true
+	return r.FindFn(id)
+}
+
+// FindAll calls the FindAllFn.
+func (r *SubscriptionRepository) FindAll() []*webhook.Subscription {
+	r.FindAllInvoked = true
+	return r.FindAllFn()
+}
+
+// Remove calls the RemoveFn.
+func (r *SubscriptionRepository) Remove(id webhook.SubscriptionID) error {
+	r.RemoveInvoked = true
+	return r.RemoveFn(id)
+}
+
+// DeliveryRepository is a mock webhook delivery repository.
+type DeliveryRepository struct {
+	StoreFn      func(d *webhook.Delivery) error
+	StoreInvoked bool
+
+	FindFn      func(id webhook.DeliveryID) (*webhook.Delivery, error)
+	FindInvoked bool
+
+	FindBySubscriptionFn      func(id webhook.SubscriptionID) []*webhook.Delivery
+	FindBySubscriptionInvoked bool
+
+	FindDueFn      func(t time.Time) []*webhook.Delivery
+	FindDueInvoked bool
+
+	FindByStateFn      func(s webhook.DeliveryState) []*webhook.Delivery
+	FindByStateInvoked bool
+}
+
+// Store calls the StoreFn.
+func (r *DeliveryRepository) Store(d *webhook.Delivery) error {
+	r.StoreInvoked = true
+	return r.StoreFn(d)
+}
+
+// Find calls the FindFn.
+func (r *DeliveryRepository) Find(id webhook.DeliveryID) (*webhook.Delivery, error) {
+	r.FindInvoked = true
+	return r.FindFn(id)
+}
+
+// FindBySubscription calls the FindBySubscriptionFn.
+func (r *DeliveryRepository) FindBySubscription(id webhook.SubscriptionID) []*webhook.Delivery {
+	r.FindBySubscriptionInvoked = true
+	return r.FindBySubscriptionFn(id)
+}
+
+// FindDue calls the FindDueFn.
+func (r *DeliveryRepository) FindDue(t time.Time) []*webhook.Delivery {
+	r.FindDueInvoked = true
+	return r.FindDueFn(t)
+}
+
+// FindByState calls the FindByStateFn.
+func (r *DeliveryRepository) FindByState(s webhook.DeliveryState) []*webhook.Delivery {
+	r.FindByStateInvoked = true
+	return r.FindByStateFn(s)
+}
+
 // HandlingEventRepository is a mock handling events repository.
 type HandlingEventRepository struct {
 	StoreFn      func(cargo.HandlingEvent)
@@ -76,6 +385,9 @@ type HandlingEventRepository struct {
 
 	QueryHandlingHistoryFn      func(cargo.TrackingID) cargo.HandlingHistory
 	QueryHandlingHistoryInvoked bool
+
+	QueryHandlingHistoriesFn      func([]cargo.TrackingID) map[cargo.TrackingID]cargo.HandlingHistory
+	QueryHandlingHistoriesInvoked bool
 }
 
 // Store calls the StoreFn.
@@ -90,14 +402,20 @@ func (r *HandlingEventRepository) QueryHandlingHistory(id cargo.TrackingID) carg
 	return r.QueryHandlingHistoryFn(id)
 }
 
+// QueryHandlingHistories calls the QueryHandlingHistoriesFn.
+func (r *HandlingEventRepository) QueryHandlingHistories(ids []cargo.TrackingID) map[cargo.TrackingID]cargo.HandlingHistory {
+	r.QueryHandlingHistoriesInvoked = true
+	return r.QueryHandlingHistoriesFn(ids)
+}
+
 // RoutingService provides a mock routing service.
 type RoutingService struct {
-	FetchRoutesFn      func(cargo.RouteSpecification) []cargo.Itinerary
+	FetchRoutesFn      func(cargo.RouteSpecification, routing.Constraints) ([]cargo.Itinerary, error)
 	FetchRoutesInvoked bool
 }
 
 // FetchRoutesForSpecification calls the FetchRoutesFn.
-func (s *RoutingService) FetchRoutesForSpecification(rs cargo.RouteSpecification) []cargo.Itinerary {
+func (s *RoutingService) FetchRoutesForSpecification(rs cargo.RouteSpecification, c routing.Constraints) ([]cargo.Itinerary, error) {
 	s.FetchRoutesInvoked = true
-	return s.FetchRoutesFn(rs)
+	return s.FetchRoutesFn(rs, c)
 }
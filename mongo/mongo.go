@@ -1,9 +1,17 @@
 package mongo
 
 import (
+	"time"
+
+	"github.com/marcusolsson/goddd/access"
+	"github.com/marcusolsson/goddd/allotment"
 	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/document"
 	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/task"
 	"github.com/marcusolsson/goddd/voyage"
+	"github.com/marcusolsson/goddd/webhook"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
@@ -19,11 +27,23 @@ func (r *cargoRepository) Store(cargo *cargo.Cargo) error {
 
 	c := sess.DB(r.db).C("cargo")
 
-	_, err := c.Upsert(bson.M{"trackingid": cargo.TrackingID}, bson.M{"$set": cargo})
+	_, err := c.Upsert(bson.M{"trackingid": cargo.TrackingID}, bson.M{"$set": newCargoDocument(cargo)})
 
 	return err
 }
 
+// cargoDocument is a cargo as it is stored. Whether the cargo is late is
+// derived from two of its fields, and is stored as well so that late cargos
+// can be found using an index.
+type cargoDocument struct {
+	cargo.Cargo `bson:",inline"`
+	Late        bool `bson:"late"`
+}
+
+func newCargoDocument(c *cargo.Cargo) cargoDocument {
+	return cargoDocument{Cargo: *c, Late: c.IsLate()}
+}
+
 func (r *cargoRepository) Find(id cargo.TrackingID) (*cargo.Cargo, error) {
 	sess := r.session.Copy()
 	defer sess.Close()
@@ -41,6 +61,50 @@ func (r *cargoRepository) Find(id cargo.TrackingID) (*cargo.Cargo, error) {
 	return &result, nil
 }
 
+func (r *cargoRepository) FindMany(ids []cargo.TrackingID) ([]*cargo.Cargo, error) {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("cargo")
+
+	var result []*cargo.Cargo
+	if err := c.Find(bson.M{"trackingid": bson.M{"$in": ids}}).All(&result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *cargoRepository) FindByReference(value string) ([]*cargo.Cargo, error) {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("cargo")
+
+	var result []*cargo.Cargo
+	if err := c.Find(bson.M{"references.value": cargo.NormalizeReference(value)}).Sort("trackingid").All(&result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *cargoRepository) Remove(id cargo.TrackingID) error {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("cargo")
+
+	if err := c.Remove(bson.M{"trackingid": id}); err != nil {
+		if err == mgo.ErrNotFound {
+			return cargo.ErrUnknown
+		}
+		return err
+	}
+
+	return nil
+}
+
 func (r *cargoRepository) FindAll() []*cargo.Cargo {
 	sess := r.session.Copy()
 	defer sess.Close()
@@ -55,6 +119,142 @@ func (r *cargoRepository) FindAll() []*cargo.Cargo {
 	return result
 }
 
+func (r *cargoRepository) Query(q cargo.Query) (cargo.Page, error) {
+	q = q.Normalize()
+
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("cargo")
+
+	filter, err := cargoFilter(q)
+	if err != nil {
+		return cargo.Page{}, err
+	}
+
+	field, order := sortKeys[q.SortBy], "+"
+	if q.Descending {
+		order = "-"
+	}
+
+	var result []*cargo.Cargo
+	if err := c.Find(filter).Sort(order+field, order+"trackingid").Limit(q.Limit + 1).All(&result); err != nil {
+		return cargo.Page{}, err
+	}
+
+	var page cargo.Page
+	if len(result) > q.Limit {
+		result = result[:q.Limit]
+		page.NextCursor = cargo.NewCursor(result[q.Limit-1], q.SortBy)
+	}
+	page.Cargos = result
+
+	return page, nil
+}
+
+// sortKeys maps sort fields to document keys.
+var sortKeys = map[cargo.SortField]string{
+	cargo.SortByTrackingID:      "trackingid",
+	cargo.SortByOrigin:          "origin",
+	cargo.SortByDestination:     "routespecification.destination",
+	cargo.SortByArrivalDeadline: "routespecification.arrivaldeadline",
+}
+
+func cargoFilter(q cargo.Query) (bson.M, error) {
+	and := []bson.M{}
+
+	if q.Origin != "" {
+		and = append(and, bson.M{"origin": q.Origin})
+	}
+	if q.Destination != "" {
+		and = append(and, bson.M{"routespecification.destination": q.Destination})
+	}
+	if q.RoutingStatus != nil {
+		and = append(and, bson.M{"delivery.routingstatus": *q.RoutingStatus})
+	}
+	if q.TransportStatus != nil {
+		and = append(and, bson.M{"delivery.transportstatus": *q.TransportStatus})
+	}
+	if q.Misdirected != nil {
+		and = append(and, bson.M{"delivery.ismisdirected": *q.Misdirected})
+	}
+	if q.Claimed != nil {
+		if *q.Claimed {
+			and = append(and, bson.M{"delivery.transportstatus": cargo.Claimed})
+		} else {
+			and = append(and, bson.M{"delivery.transportstatus": bson.M{"$ne": cargo.Claimed}})
+		}
+	}
+	if q.Late != nil {
+		if *q.Late {
+			and = append(and, bson.M{"late": true})
+		} else {
+			and = append(and, bson.M{"late": bson.M{"$ne": true}})
+		}
+	}
+	if !q.DeadlineFrom.IsZero() {
+		and = append(and, bson.M{"routespecification.arrivaldeadline": bson.M{"$gte": q.DeadlineFrom}})
+	}
+	if !q.DeadlineTo.IsZero() {
+		and = append(and, bson.M{"routespecification.arrivaldeadline": bson.M{"$lte": q.DeadlineTo}})
+	}
+	if q.Voyage != "" {
+		and = append(and, bson.M{"itinerary.legs.voyagenumber": q.Voyage})
+	}
+	if q.Customer != "" {
+		and = append(and, bson.M{"$or": []bson.M{
+			{"parties.shipper": q.Customer},
+			{"parties.consignee": q.Customer},
+			{"parties.notifyparties": q.Customer},
+		}})
+	}
+	if !q.NeedsAttentionBy.IsZero() {
+		unclaimed := bson.M{"$ne": cargo.Claimed}
+		and = append(and, bson.M{"$or": []bson.M{
+			{"delivery.routingstatus": bson.M{"$in": []cargo.RoutingStatus{cargo.NotRouted, cargo.Misrouted}}},
+			{"delivery.ismisdirected": true},
+			{"delivery.transportstatus": unclaimed, "late": true},
+			{"delivery.transportstatus": unclaimed, "routespecification.arrivaldeadline": bson.M{"$gt": time.Time{}, "$lte": q.NeedsAttentionBy}},
+		}})
+	}
+
+	if q.Cursor != "" {
+		cur, err := cargo.DecodeCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		var key interface{} = cur.Key
+		if q.SortBy == cargo.SortByArrivalDeadline {
+			t, err := cargo.ParseSortableTime(cur.Key)
+			if err != nil {
+				return nil, cargo.ErrInvalidCursor
+			}
+			key = t
+		}
+
+		op := "$gt"
+		if q.Descending {
+			op = "$lt"
+		}
+
+		field := sortKeys[q.SortBy]
+		if field == "trackingid" {
+			and = append(and, bson.M{"trackingid": bson.M{op: cur.ID}})
+		} else {
+			and = append(and, bson.M{"$or": []bson.M{
+				{field: bson.M{op: key}},
+				{field: key, "trackingid": bson.M{op: cur.ID}},
+			}})
+		}
+	}
+
+	if len(and) == 0 {
+		return bson.M{}, nil
+	}
+	return bson.M{"$and": and}, nil
+}
+
 // NewCargoRepository returns a new instance of a MongoDB cargo repository.
 func NewCargoRepository(db string, session *mgo.Session) (cargo.Repository, error) {
 	r := &cargoRepository{
@@ -79,6 +279,26 @@ func NewCargoRepository(db string, session *mgo.Session) (cargo.Repository, erro
 		return nil, err
 	}
 
+	// Secondary indexes backing the cargo listing queries.
+	for _, key := range [][]string{
+		{"origin", "trackingid"},
+		{"routespecification.destination", "trackingid"},
+		{"routespecification.arrivaldeadline", "trackingid"},
+		{"delivery.routingstatus"},
+		{"delivery.transportstatus"},
+		{"delivery.ismisdirected"},
+		{"late"},
+		{"itinerary.legs.voyagenumber"},
+		{"parties.shipper"},
+		{"parties.consignee"},
+		{"parties.notifyparties"},
+		{"references.value"},
+	} {
+		if err := c.EnsureIndex(mgo.Index{Key: key, Background: true}); err != nil {
+			return nil, err
+		}
+	}
+
 	return r, nil
 }
 
@@ -191,6 +411,34 @@ func (r *voyageRepository) Find(voyageNumber voyage.Number) (*voyage.Voyage, err
 	return &result, nil
 }
 
+func (r *voyageRepository) FindMany(numbers []voyage.Number) ([]*voyage.Voyage, error) {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("voyage")
+
+	var result []*voyage.Voyage
+	if err := c.Find(bson.M{"number": bson.M{"$in": numbers}}).All(&result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *voyageRepository) FindAll() []*voyage.Voyage {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("voyage")
+
+	var result []*voyage.Voyage
+	if err := c.Find(bson.M{}).All(&result); err != nil {
+		return []*voyage.Voyage{}
+	}
+
+	return result
+}
+
 func (r *voyageRepository) store(v *voyage.Voyage) error {
 	sess := r.session.Copy()
 	defer sess.Close()
@@ -230,6 +478,8 @@ func NewVoyageRepository(db string, session *mgo.Session) (voyage.Repository, er
 		voyage.V100,
 		voyage.V300,
 		voyage.V400,
+		voyage.R100,
+		voyage.T100,
 		voyage.V0100S,
 		voyage.V0200T,
 		voyage.V0300A,
@@ -244,6 +494,190 @@ func NewVoyageRepository(db string, session *mgo.Session) (voyage.Repository, er
 	return r, nil
 }
 
+type customerRepository struct {
+	db      string
+	session *mgo.Session
+}
+
+func (r *customerRepository) Store(cust *customer.Customer) error {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("customer")
+
+	_, err := c.Upsert(bson.M{"id": cust.ID}, bson.M{"$set": cust})
+
+	return err
+}
+
+func (r *customerRepository) Find(id customer.ID) (*customer.Customer, error) {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("customer")
+
+	var result customer.Customer
+	if err := c.Find(bson.M{"id": id}).One(&result); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, customer.ErrUnknown
+		}
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (r *customerRepository) FindAll() []*customer.Customer {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("customer")
+
+	var result []*customer.Customer
+	if err := c.Find(bson.M{}).All(&result); err != nil {
+		return []*customer.Customer{}
+	}
+
+	return result
+}
+
+// NewCustomerRepository returns a new instance of a MongoDB customer repository.
+func NewCustomerRepository(db string, session *mgo.Session) (customer.Repository, error) {
+	r := &customerRepository{
+		db:      db,
+		session: session,
+	}
+
+	index := mgo.Index{
+		Key:        []string{"id"},
+		Unique:     true,
+		DropDups:   true,
+		Background: true,
+		Sparse:     true,
+	}
+
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("customer")
+
+	if err := c.EnsureIndex(index); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+type allotmentRepository struct {
+	db      string
+	session *mgo.Session
+}
+
+func (r *allotmentRepository) Store(a *allotment.Allotment) error {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("allotment")
+
+	// Allotments stored before they were versioned have no version.
+	version := interface{}(a.Version)
+	if a.Version == 0 {
+		version = bson.M{"$in": []interface{}{0, nil}}
+	}
+
+	next := a.Copy()
+	next.Version++
+
+	// If the allotment exists with another version, the upsert tries to
+	// insert it again and violates the unique index on the ID.
+	if _, err := c.Upsert(bson.M{"id": a.ID, "version": version}, bson.M{"$set": next}); err != nil {
+		if mgo.IsDup(err) {
+			return allotment.ErrConflict
+		}
+		return err
+	}
+
+	a.Version = next.Version
+
+	return nil
+}
+
+func (r *allotmentRepository) Find(id allotment.ID) (*allotment.Allotment, error) {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("allotment")
+
+	var result allotment.Allotment
+	if err := c.Find(bson.M{"id": id}).One(&result); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, allotment.ErrUnknown
+		}
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (r *allotmentRepository) FindByCustomer(id customer.ID) []*allotment.Allotment {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("allotment")
+
+	var result []*allotment.Allotment
+	if err := c.Find(bson.M{"customer": id}).All(&result); err != nil {
+		return []*allotment.Allotment{}
+	}
+
+	return result
+}
+
+func (r *allotmentRepository) FindAll() []*allotment.Allotment {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("allotment")
+
+	var result []*allotment.Allotment
+	if err := c.Find(bson.M{}).All(&result); err != nil {
+		return []*allotment.Allotment{}
+	}
+
+	return result
+}
+
+// NewAllotmentRepository returns a new instance of a MongoDB allotment repository.
+func NewAllotmentRepository(db string, session *mgo.Session) (allotment.Repository, error) {
+	r := &allotmentRepository{
+		db:      db,
+		session: session,
+	}
+
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("allotment")
+
+	index := mgo.Index{
+		Key:        []string{"id"},
+		Unique:     true,
+		DropDups:   true,
+		Background: true,
+		Sparse:     true,
+	}
+
+	if err := c.EnsureIndex(index); err != nil {
+		return nil, err
+	}
+
+	if err := c.EnsureIndex(mgo.Index{Key: []string{"customer", "voyage"}, Background: true}); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
 type handlingEventRepository struct {
 	db      string
 	session *mgo.Session
@@ -270,6 +704,25 @@ func (r *handlingEventRepository) QueryHandlingHistory(id cargo.TrackingID) carg
 	return cargo.HandlingHistory{HandlingEvents: result}
 }
 
+func (r *handlingEventRepository) QueryHandlingHistories(ids []cargo.TrackingID) map[cargo.TrackingID]cargo.HandlingHistory {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("handling_event")
+
+	var result []cargo.HandlingEvent
+	_ = c.Find(bson.M{"trackingid": bson.M{"$in": ids}}).All(&result)
+
+	h := make(map[cargo.TrackingID]cargo.HandlingHistory, len(ids))
+	for _, e := range result {
+		hh := h[e.TrackingID]
+		hh.HandlingEvents = append(hh.HandlingEvents, e)
+		h[e.TrackingID] = hh
+	}
+
+	return h
+}
+
 // NewHandlingEventRepository returns a new instance of a MongoDB handling event repository.
 func NewHandlingEventRepository(db string, session *mgo.Session) cargo.HandlingEventRepository {
 	return &handlingEventRepository{
@@ -277,3 +730,406 @@ func NewHandlingEventRepository(db string, session *mgo.Session) cargo.HandlingE
 		session: session,
 	}
 }
+
+type taskRepository struct {
+	db      string
+	session *mgo.Session
+}
+
+func (r *taskRepository) Store(t *task.Task) error {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("task")
+
+	_, err := c.Upsert(bson.M{"trackingid": t.TrackingID}, bson.M{"$set": t})
+
+	return err
+}
+
+func (r *taskRepository) Find(id cargo.TrackingID) (*task.Task, error) {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("task")
+
+	var result task.Task
+	if err := c.Find(bson.M{"trackingid": id}).One(&result); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, task.ErrUnknown
+		}
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (r *taskRepository) FindMany(ids []cargo.TrackingID) ([]*task.Task, error) {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("task")
+
+	var result []*task.Task
+	if err := c.Find(bson.M{"trackingid": bson.M{"$in": ids}}).All(&result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// NewTaskRepository returns a new instance of a MongoDB task repository.
+func NewTaskRepository(db string, session *mgo.Session) (task.Repository, error) {
+	r := &taskRepository{
+		db:      db,
+		session: session,
+	}
+
+	index := mgo.Index{
+		Key:        []string{"trackingid"},
+		Unique:     true,
+		DropDups:   true,
+		Background: true,
+		Sparse:     true,
+	}
+
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("task")
+
+	if err := c.EnsureIndex(index); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+type documentRepository struct {
+	db      string
+	session *mgo.Session
+}
+
+func (r *documentRepository) Store(b *document.BillOfLading) error {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("document")
+
+	// The unique index on tracking ID and version keeps concurrent issuers
+	// from both storing the same version.
+	if err := c.Insert(b); err != nil {
+		if mgo.IsDup(err) {
+			return document.ErrConflict
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *documentRepository) Find(id cargo.TrackingID, version int) (*document.BillOfLading, error) {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("document")
+
+	var result document.BillOfLading
+	if err := c.Find(bson.M{"trackingid": id, "version": version}).One(&result); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, document.ErrUnknown
+		}
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (r *documentRepository) FindByCargo(id cargo.TrackingID) []*document.BillOfLading {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("document")
+
+	var result []*document.BillOfLading
+	if err := c.Find(bson.M{"trackingid": id}).Sort("version").All(&result); err != nil {
+		return []*document.BillOfLading{}
+	}
+
+	return result
+}
+
+// NewDocumentRepository returns a new instance of a MongoDB document repository.
+func NewDocumentRepository(db string, session *mgo.Session) (document.Repository, error) {
+	r := &documentRepository{
+		db:      db,
+		session: session,
+	}
+
+	index := mgo.Index{
+		Key:        []string{"trackingid", "version"},
+		Unique:     true,
+		DropDups:   true,
+		Background: true,
+	}
+
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("document")
+
+	if err := c.EnsureIndex(index); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+type subscriptionRepository struct {
+	db      string
+	session *mgo.Session
+}
+
+func (r *subscriptionRepository) Store(s *webhook.Subscription) error {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("subscription")
+
+	_, err := c.Upsert(bson.M{"id": s.ID}, bson.M{"$set": s})
+
+	return err
+}
+
+func (r *subscriptionRepository) Find(id webhook.SubscriptionID) (*webhook.Subscription, error) {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("subscription")
+
+	var result webhook.Subscription
+	if err := c.Find(bson.M{"id": id}).One(&result); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, webhook.ErrUnknownSubscription
+		}
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (r *subscriptionRepository) FindAll() []*webhook.Subscription {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("subscription")
+
+	var result []*webhook.Subscription
+	if err := c.Find(bson.M{}).Sort("created").All(&result); err != nil {
+		return []*webhook.Subscription{}
+	}
+
+	return result
+}
+
+func (r *subscriptionRepository) Remove(id webhook.SubscriptionID) error {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("subscription")
+
+	if err := c.Remove(bson.M{"id": id}); err != nil {
+		if err == mgo.ErrNotFound {
+			return webhook.ErrUnknownSubscription
+		}
+		return err
+	}
+
+	return nil
+}
+
+// NewSubscriptionRepository returns a new instance of a MongoDB subscription repository.
+func NewSubscriptionRepository(db string, session *mgo.Session) (webhook.SubscriptionRepository, error) {
+	r := &subscriptionRepository{
+		db:      db,
+		session: session,
+	}
+
+	index := mgo.Index{
+		Key:        []string{"id"},
+		Unique:     true,
+		DropDups:   true,
+		Background: true,
+		Sparse:     true,
+	}
+
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("subscription")
+
+	if err := c.EnsureIndex(index); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+type deliveryRepository struct {
+	db      string
+	session *mgo.Session
+}
+
+func (r *deliveryRepository) Store(d *webhook.Delivery) error {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("webhook_delivery")
+
+	_, err := c.Upsert(bson.M{"id": d.ID}, bson.M{"$set": d})
+
+	return err
+}
+
+func (r *deliveryRepository) Find(id webhook.DeliveryID) (*webhook.Delivery, error) {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("webhook_delivery")
+
+	var result webhook.Delivery
+	if err := c.Find(bson.M{"id": id}).One(&result); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, webhook.ErrUnknownDelivery
+		}
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (r *deliveryRepository) FindBySubscription(id webhook.SubscriptionID) []*webhook.Delivery {
+	return r.findAll(bson.M{"subscriptionid": id})
+}
+
+func (r *deliveryRepository) FindDue(t time.Time) []*webhook.Delivery {
+	return r.findAll(bson.M{"state": webhook.Pending, "nextattempt": bson.M{"$lte": t}})
+}
+
+func (r *deliveryRepository) FindByState(s webhook.DeliveryState) []*webhook.Delivery {
+	return r.findAll(bson.M{"state": s})
+}
+
+func (r *deliveryRepository) findAll(q bson.M) []*webhook.Delivery {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("webhook_delivery")
+
+	var result []*webhook.Delivery
+	if err := c.Find(q).Sort("created").All(&result); err != nil {
+		return []*webhook.Delivery{}
+	}
+
+	return result
+}
+
+// NewDeliveryRepository returns a new instance of a MongoDB webhook delivery repository.
+func NewDeliveryRepository(db string, session *mgo.Session) (webhook.DeliveryRepository, error) {
+	r := &deliveryRepository{
+		db:      db,
+		session: session,
+	}
+
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("webhook_delivery")
+
+	indexes := []mgo.Index{
+		{Key: []string{"id"}, Unique: true, DropDups: true, Background: true},
+		{Key: []string{"state", "nextattempt"}, Background: true},
+		{Key: []string{"subscriptionid"}, Background: true},
+	}
+
+	for _, index := range indexes {
+		if err := c.EnsureIndex(index); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+type grantRepository struct {
+	db      string
+	session *mgo.Session
+}
+
+func (r *grantRepository) Store(g *access.Grant) error {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("grant")
+
+	_, err := c.Upsert(bson.M{"token": g.Token}, bson.M{"$set": g})
+
+	return err
+}
+
+func (r *grantRepository) Find(t access.Token) (*access.Grant, error) {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("grant")
+
+	var result access.Grant
+	if err := c.Find(bson.M{"token": t}).One(&result); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, access.ErrUnknown
+		}
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (r *grantRepository) FindByCargo(id cargo.TrackingID) []*access.Grant {
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("grant")
+
+	var result []*access.Grant
+	if err := c.Find(bson.M{"trackingid": id}).Sort("issued").All(&result); err != nil {
+		return []*access.Grant{}
+	}
+
+	return result
+}
+
+// NewGrantRepository returns a new instance of a MongoDB access grant repository.
+func NewGrantRepository(db string, session *mgo.Session) (access.Repository, error) {
+	r := &grantRepository{
+		db:      db,
+		session: session,
+	}
+
+	sess := r.session.Copy()
+	defer sess.Close()
+
+	c := sess.DB(r.db).C("grant")
+
+	indexes := []mgo.Index{
+		{Key: []string{"token"}, Unique: true, DropDups: true, Background: true},
+		{Key: []string{"trackingid"}, Background: true},
+	}
+
+	for _, index := range indexes {
+		if err := c.EnsureIndex(index); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
@@ -0,0 +1,124 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/smtp"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/marcusolsson/goddd/customer"
+)
+
+// SMTPChannel sends messages by email through a relay.
+type SMTPChannel struct {
+	addr string
+	from string
+	auth smtp.Auth
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewSMTPChannel returns a channel sending email from the given address
+// through the relay at addr. The auth may be nil for relays that do not
+// require authentication.
+func NewSMTPChannel(addr, from string, auth smtp.Auth) *SMTPChannel {
+	return &SMTPChannel{
+		addr: addr,
+		from: from,
+		auth: auth,
+		send: smtp.SendMail,
+	}
+}
+
+// Send emails the message to the address of the recipient. Addresses that
+// cannot be parsed are treated as missing, rather than written to the
+// headers as they are.
+func (c *SMTPChannel) Send(m Message) error {
+	to, err := customer.ParseEmail(m.Recipient.Email)
+	if err != nil {
+		return ErrNoAddress
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", c.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", m.Subject)
+	fmt.Fprint(&msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprint(&msg, strings.Replace(m.Body, "\n", "\r\n", -1))
+
+	return c.send(c.addr, c.auth, c.from, []string{to}, msg.Bytes())
+}
+
+// WebhookChannel posts messages as JSON to a URL, e.g. of a gateway
+// forwarding them as text messages.
+type WebhookChannel struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookChannel returns a channel posting to the URL.
+func NewWebhookChannel(url string, client *http.Client) *WebhookChannel {
+	return &WebhookChannel{url: url, client: client}
+}
+
+// Send posts the message, failing unless the response is successful.
+func (c *WebhookChannel) Send(m Message) error {
+	payload, err := json.Marshal(struct {
+		Kind       string `json:"kind"`
+		TrackingID string `json:"tracking_id"`
+		CustomerID string `json:"customer_id"`
+		Email      string `json:"email,omitempty"`
+		Subject    string `json:"subject"`
+		Body       string `json:"body"`
+	}{
+		Kind:       m.Kind.String(),
+		TrackingID: string(m.TrackingID),
+		CustomerID: string(m.Recipient.ID),
+		Email:      m.Recipient.Email,
+		Subject:    m.Subject,
+		Body:       m.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Post(c.url, "application/json; charset=utf-8", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Drain the body so that the connection can be reused.
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+
+	return nil
+}
+
+// LogChannel writes messages to a logger, e.g. for development.
+type LogChannel struct {
+	logger log.Logger
+}
+
+// NewLogChannel returns a channel writing to the logger.
+func NewLogChannel(logger log.Logger) *LogChannel {
+	return &LogChannel{logger: logger}
+}
+
+// Send logs the message.
+func (c *LogChannel) Send(m Message) error {
+	return c.logger.Log(
+		"kind", m.Kind.String(),
+		"tracking_id", m.TrackingID,
+		"customer_id", m.Recipient.ID,
+		"subject", m.Subject,
+	)
+}
@@ -0,0 +1,158 @@
+// Package notification tells the customers involved in a cargo about
+// inspection events, such as the cargo having arrived, over the channels
+// they prefer.
+package notification
+
+import (
+	"errors"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+)
+
+// Kind describes what a notification is about.
+type Kind int
+
+// Kinds of notifications.
+const (
+	Misdirected Kind = iota
+	Arrived
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Misdirected:
+		return "Misdirected"
+	case Arrived:
+		return "Arrived"
+	}
+	return ""
+}
+
+// Message is a notification rendered for a recipient.
+type Message struct {
+	Kind       Kind
+	TrackingID cargo.TrackingID
+	Recipient  *customer.Customer
+	Subject    string
+	Body       string
+}
+
+// Channel is the interface that wraps the Send method.
+type Channel interface {
+	// Send delivers the message to its recipient.
+	Send(m Message) error
+}
+
+// ErrNoAddress is used when a recipient cannot be reached over a channel.
+var ErrNoAddress = errors.New("recipient has no address")
+
+// Notifier notifies the parties of a cargo when it has been misdirected or
+// has arrived. Messages are sent in the background, so that handling events
+// are not held up by slow channels.
+type Notifier struct {
+	customers customer.Repository
+	templates *Templates
+	channels  map[customer.Channel]Channel
+	defaults  []customer.Channel
+	logger    log.Logger
+	queue     chan Message
+}
+
+// NewNotifier returns a new notifier sending over the given channels. The
+// default channels are used for customers without a preference.
+func NewNotifier(customers customer.Repository, templates *Templates, channels map[customer.Channel]Channel, defaults []customer.Channel, logger log.Logger) *Notifier {
+	return &Notifier{
+		customers: customers,
+		templates: templates,
+		channels:  channels,
+		defaults:  defaults,
+		logger:    logger,
+		queue:     make(chan Message, 100),
+	}
+}
+
+// CargoWasMisdirected notifies the parties of the cargo that it needs to be
+// rerouted.
+func (n *Notifier) CargoWasMisdirected(c *cargo.Cargo) {
+	n.notify(c, Misdirected)
+}
+
+// CargoHasArrived notifies the parties of the cargo that it can be claimed.
+func (n *Notifier) CargoHasArrived(c *cargo.Cargo) {
+	n.notify(c, Arrived)
+}
+
+// DeliveryWasUpdated is left to webhook subscriptions.
+func (n *Notifier) DeliveryWasUpdated(c *cargo.Cargo) {}
+
+func (n *Notifier) notify(c *cargo.Cargo, k Kind) {
+	for _, id := range parties(c.Parties) {
+		cust, err := n.customers.Find(id)
+		if err != nil {
+			n.logger.Log("tracking_id", c.TrackingID, "customer_id", id, "err", err)
+			continue
+		}
+
+		m, err := n.templates.Render(k, c, cust)
+		if err != nil {
+			n.logger.Log("tracking_id", c.TrackingID, "kind", k.String(), "err", err)
+			return
+		}
+
+		select {
+		case n.queue <- m:
+		default:
+			n.logger.Log("tracking_id", c.TrackingID, "customer_id", id, "kind", k.String(), "msg", "queue full, dropped")
+		}
+	}
+}
+
+// Run sends queued messages over the channels preferred by their recipients,
+// until done is closed.
+func (n *Notifier) Run(done <-chan struct{}) {
+	for {
+		select {
+		case m := <-n.queue:
+			n.send(m)
+		case <-done:
+			return
+		}
+	}
+}
+
+func (n *Notifier) send(m Message) {
+	channels := m.Recipient.Channels
+	if len(channels) == 0 {
+		channels = n.defaults
+	}
+
+	for _, name := range channels {
+		ch, ok := n.channels[name]
+		if !ok {
+			n.logger.Log("customer_id", m.Recipient.ID, "channel", name, "msg", "channel not configured")
+			continue
+		}
+		if err := ch.Send(m); err != nil {
+			n.logger.Log("tracking_id", m.TrackingID, "customer_id", m.Recipient.ID, "channel", name, "err", err)
+		}
+	}
+}
+
+// parties returns the customers involved in a cargo, each of them once.
+func parties(p cargo.Parties) []customer.ID {
+	var (
+		ids  []customer.ID
+		seen = make(map[customer.ID]bool)
+	)
+	for _, id := range append([]customer.ID{p.Shipper, p.Consignee}, p.NotifyParties...) {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
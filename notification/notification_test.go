@@ -0,0 +1,212 @@
+package notification
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/inmem"
+	"github.com/marcusolsson/goddd/location"
+)
+
+type stubChannel struct {
+	sent []Message
+}
+
+func (c *stubChannel) Send(m Message) error {
+	c.sent = append(c.sent, m)
+	return nil
+}
+
+func TestNotifier(t *testing.T) {
+	customers := inmem.NewCustomerRepository()
+
+	acme := customer.New("ACME", "Acme Corp", "", "shipping@acme.example")
+	acme.Channels = []customer.Channel{customer.Email, customer.Webhook}
+
+	globex := customer.New("GLOBEX", "Globex", "", "")
+
+	for _, c := range []*customer.Customer{acme, globex} {
+		if err := customers.Store(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var email, webhook, logged stubChannel
+
+	templates, err := NewTemplates(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := NewNotifier(customers, templates, map[customer.Channel]Channel{
+		customer.Email:   &email,
+		customer.Webhook: &webhook,
+		customer.Log:     &logged,
+	}, []customer.Channel{customer.Log}, log.NewNopLogger())
+
+	c := cargo.New("ABC123", cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.AUMEL,
+	})
+	c.AttachParty(cargo.Shipper, "ACME")
+	c.AttachParty(cargo.Consignee, "GLOBEX")
+	c.AttachParty(cargo.NotifyParty, "ACME")
+	c.AttachParty(cargo.NotifyParty, "NOBODY")
+
+	n.CargoHasArrived(c)
+	n.DeliveryWasUpdated(c)
+
+	// Send the queued messages without running the notifier.
+	for len(n.queue) > 0 {
+		n.send(<-n.queue)
+	}
+
+	if len(email.sent) != 1 || email.sent[0].Recipient.ID != "ACME" {
+		t.Errorf("email.sent = %v; want one message to ACME", email.sent)
+	}
+	if len(webhook.sent) != 1 || webhook.sent[0].Recipient.ID != "ACME" {
+		t.Errorf("webhook.sent = %v; want one message to ACME", webhook.sent)
+	}
+	if len(logged.sent) != 1 || logged.sent[0].Recipient.ID != "GLOBEX" {
+		t.Errorf("logged.sent = %v; want one message to GLOBEX", logged.sent)
+	}
+
+	m := email.sent[0]
+	if m.Kind != Arrived || m.TrackingID != "ABC123" {
+		t.Errorf("m = %+v; want Arrived for ABC123", m)
+	}
+	if want := "Cargo ABC123 has arrived"; m.Subject != want {
+		t.Errorf("m.Subject = %q; want = %q", m.Subject, want)
+	}
+	if !strings.Contains(m.Body, "Dear Acme Corp") || !strings.Contains(m.Body, "AUMEL") {
+		t.Errorf("m.Body = %q", m.Body)
+	}
+}
+
+func TestLoadTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	text := "Misdirected: {{.Cargo.TrackingID}}\nCall {{.Customer.Name}}.\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "misdirected.tmpl"), []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := cargo.New("ABC123", cargo.RouteSpecification{Destination: location.AUMEL})
+	cust := customer.New("ACME", "Acme Corp", "", "")
+
+	m, err := templates.Render(Misdirected, c, cust)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Subject != "Misdirected: ABC123" || m.Body != "Call Acme Corp.\n" {
+		t.Errorf("m = %+v", m)
+	}
+
+	// Kinds without a file keep the default text.
+	m, err = templates.Render(Arrived, c, cust)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Subject != "Cargo ABC123 has arrived" {
+		t.Errorf("m.Subject = %q", m.Subject)
+	}
+
+	if _, err := NewTemplates(map[Kind]string{Arrived: "{{.Cargo"}); err == nil {
+		t.Errorf("expected error parsing template")
+	}
+}
+
+func TestSMTPChannel(t *testing.T) {
+	var (
+		gotAddr string
+		gotTo   []string
+		gotMsg  string
+	)
+
+	ch := NewSMTPChannel("relay:25", "noreply@goddd.example", nil)
+	ch.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotTo, gotMsg = addr, to, string(msg)
+		return nil
+	}
+
+	m := Message{
+		Recipient: customer.New("ACME", "Acme Corp", "", "shipping@acme.example"),
+		Subject:   "Cargo ABC123 has arrived",
+		Body:      "Dear Acme Corp,\n",
+	}
+
+	if err := ch.Send(m); err != nil {
+		t.Fatal(err)
+	}
+
+	if gotAddr != "relay:25" || len(gotTo) != 1 || gotTo[0] != "shipping@acme.example" {
+		t.Errorf("addr = %s, to = %v", gotAddr, gotTo)
+	}
+	if !strings.Contains(gotMsg, "Subject: Cargo ABC123 has arrived\r\n") || !strings.HasSuffix(gotMsg, "\r\n\r\nDear Acme Corp,\r\n") {
+		t.Errorf("msg = %q", gotMsg)
+	}
+
+	for _, email := range []string{"", "shipping@acme.example\r\nBcc: all@globex.example"} {
+		m.Recipient = customer.New("GLOBEX", "Globex", "", email)
+		if err := ch.Send(m); err != ErrNoAddress {
+			t.Errorf("err = %v; want = %v", err, ErrNoAddress)
+		}
+	}
+}
+
+func TestWebhookChannel(t *testing.T) {
+	var got map[string]string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	ch := NewWebhookChannel(srv.URL, http.DefaultClient)
+
+	m := Message{
+		Kind:       Misdirected,
+		TrackingID: "ABC123",
+		Recipient:  customer.New("ACME", "Acme Corp", "", ""),
+		Subject:    "Cargo ABC123 has been misdirected",
+	}
+
+	if err := ch.Send(m); err != nil {
+		t.Fatal(err)
+	}
+
+	if got["kind"] != "Misdirected" || got["tracking_id"] != "ABC123" || got["customer_id"] != "ACME" {
+		t.Errorf("got = %v", got)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	if err := NewWebhookChannel(failing.URL, http.DefaultClient).Send(m); err == nil {
+		t.Errorf("expected error on failed response")
+	}
+}
@@ -0,0 +1,103 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+)
+
+// DefaultTemplates are the texts of the notifications of each kind. The
+// first line of a text is the subject, and the rest is the body.
+var DefaultTemplates = map[Kind]string{
+	Misdirected: `Cargo {{.Cargo.TrackingID}} has been misdirected
+Dear {{.Customer.Name}},
+
+Cargo {{.Cargo.TrackingID}} was last handled in {{.Cargo.Delivery.LastKnownLocation}}, which is not on its route. The cargo will be rerouted to {{.Cargo.RouteSpecification.Destination}}.
+`,
+	Arrived: `Cargo {{.Cargo.TrackingID}} has arrived
+Dear {{.Customer.Name}},
+
+Cargo {{.Cargo.TrackingID}} has arrived in {{.Cargo.RouteSpecification.Destination}} and can be claimed.
+`,
+}
+
+// Templates render the notifications of each kind.
+type Templates struct {
+	templates map[Kind]*template.Template
+}
+
+// NewTemplates parses the texts of the notifications of each kind. Kinds
+// without a text get the default one.
+func NewTemplates(texts map[Kind]string) (*Templates, error) {
+	t := &Templates{templates: make(map[Kind]*template.Template)}
+
+	for k, text := range DefaultTemplates {
+		if s, ok := texts[k]; ok {
+			text = s
+		}
+		tmpl, err := template.New(k.String()).Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		t.templates[k] = tmpl
+	}
+
+	return t, nil
+}
+
+// LoadTemplates parses the templates in the directory, named after the kind
+// they are for in lowercase, e.g. arrived.tmpl. Kinds without a file get the
+// default text.
+func LoadTemplates(dir string) (*Templates, error) {
+	texts := make(map[Kind]string)
+
+	for k := range DefaultTemplates {
+		b, err := ioutil.ReadFile(filepath.Join(dir, strings.ToLower(k.String())+".tmpl"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		texts[k] = string(b)
+	}
+
+	return NewTemplates(texts)
+}
+
+// Render returns the notification of the given kind about the cargo, for
+// the customer.
+func (t *Templates) Render(k Kind, c *cargo.Cargo, cust *customer.Customer) (Message, error) {
+	tmpl, ok := t.templates[k]
+	if !ok {
+		return Message{}, fmt.Errorf("no template for %s", k)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, struct {
+		Cargo    *cargo.Cargo
+		Customer *customer.Customer
+	}{c, cust}); err != nil {
+		return Message{}, err
+	}
+
+	subject, body := buf.String(), ""
+	if i := strings.Index(subject, "\n"); i >= 0 {
+		subject, body = subject[:i], subject[i+1:]
+	}
+
+	return Message{
+		Kind:       k,
+		TrackingID: c.TrackingID,
+		Recipient:  cust,
+		Subject:    strings.TrimSpace(subject),
+		Body:       body,
+	}, nil
+}
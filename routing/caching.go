@@ -0,0 +1,222 @@
+package routing
+
+import (
+	"container/list"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/voyage"
+)
+
+// DeadlineWindow is the granularity of arrival deadlines in the cache. Route
+// specifications with deadlines in the same window share their routes, which
+// are fetched for the end of the window and left to the ranker to filter.
+const DeadlineWindow = 24 * time.Hour
+
+// Cache remembers the routes found for route specifications, so that
+// requesting routes for the same origin and destination does not reach the
+// routing service every time. Routes are fresh for the time to live, after
+// which they are only used while the routing service is unavailable. Once
+// the cache is full, the least recently used routes are evicted.
+type Cache struct {
+	ttl  time.Duration
+	size int
+	now  func() time.Time
+
+	mtx     sync.Mutex
+	gen     uint64
+	entries map[cacheKey]*list.Element
+	lru     *list.List
+}
+
+type cacheKey struct {
+	origin      location.UNLocode
+	destination location.UNLocode
+	deadline    int64
+	constraints string
+}
+
+type cacheEntry struct {
+	key         cacheKey
+	itineraries []cargo.Itinerary
+	expires     time.Time
+}
+
+// NewCache returns a cache holding the routes of at most size route
+// specifications.
+func NewCache(ttl time.Duration, size int) *Cache {
+	return &Cache{
+		ttl:     ttl,
+		size:    size,
+		now:     time.Now,
+		entries: make(map[cacheKey]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Invalidate removes all routes, e.g. because the schedules they were found
+// in have changed. Routes being fetched while invalidating are not cached.
+func (c *Cache) Invalidate() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.gen++
+	c.entries = make(map[cacheKey]*list.Element)
+	c.lru.Init()
+}
+
+// Watch invalidates the cache whenever the voyage schedules change, checking
+// the repository at every interval until done is closed.
+func (c *Cache) Watch(voyages voyage.Repository, interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := fingerprint(voyages.FindAll())
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+		if f := fingerprint(voyages.FindAll()); f != last {
+			c.Invalidate()
+			last = f
+		}
+	}
+}
+
+// get returns the routes cached for the key, whether they are still fresh,
+// and the generation of the cache they were looked up in.
+func (c *Cache) get(k cacheKey) (itineraries []cargo.Itinerary, fresh, ok bool, gen uint64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	el, ok := c.entries[k]
+	if !ok {
+		return nil, false, false, c.gen
+	}
+	c.lru.MoveToFront(el)
+
+	e := el.Value.(*cacheEntry)
+
+	return e.itineraries, c.now().Before(e.expires), true, c.gen
+}
+
+// put caches the routes for the key, unless the cache has been invalidated
+// since the generation they were fetched in.
+func (c *Cache) put(k cacheKey, itineraries []cargo.Itinerary, gen uint64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if gen != c.gen || c.size <= 0 {
+		return
+	}
+
+	e := &cacheEntry{key: k, itineraries: itineraries, expires: c.now().Add(c.ttl)}
+
+	if el, ok := c.entries[k]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.entries[k] = c.lru.PushFront(e)
+
+	for c.lru.Len() > c.size {
+		el := c.lru.Back()
+		c.lru.Remove(el)
+		delete(c.entries, el.Value.(*cacheEntry).key)
+	}
+}
+
+type cachingService struct {
+	cache *Cache
+	Service
+}
+
+func (s cachingService) FetchRoutesForSpecification(rs cargo.RouteSpecification, c Constraints) ([]cargo.Itinerary, error) {
+	rs.ArrivalDeadline = endOfWindow(rs.ArrivalDeadline)
+
+	k := cacheKey{
+		origin:      rs.Origin,
+		destination: rs.Destination,
+		deadline:    rs.ArrivalDeadline.Unix(),
+		constraints: c.key(),
+	}
+
+	cached, fresh, ok, gen := s.cache.get(k)
+	if ok && fresh {
+		return upcoming(cached, s.cache.now()), nil
+	}
+
+	itineraries, err := s.Service.FetchRoutesForSpecification(rs, c)
+	if err == ErrUnavailable && ok {
+		// Stale routes are better than none while the circuit is open, and
+		// are revalidated once it closes.
+		return upcoming(cached, s.cache.now()), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.put(k, itineraries, gen)
+
+	return itineraries, nil
+}
+
+// NewCachingMiddleware returns a new instance of a caching middleware,
+// keeping routes in the given cache.
+func NewCachingMiddleware(c *Cache) ServiceMiddleware {
+	return func(next Service) Service {
+		return cachingService{c, next}
+	}
+}
+
+// endOfWindow returns the end of the deadline window the deadline falls in.
+// A zero deadline has no window.
+func endOfWindow(deadline time.Time) time.Time {
+	if deadline.IsZero() {
+		return deadline
+	}
+	end := deadline.Truncate(DeadlineWindow)
+	if end.Before(deadline) {
+		end = end.Add(DeadlineWindow)
+	}
+	return end
+}
+
+// upcoming returns the itineraries that have yet to depart, leaving out those
+// that have departed since they were cached.
+func upcoming(itineraries []cargo.Itinerary, now time.Time) []cargo.Itinerary {
+	result := make([]cargo.Itinerary, 0, len(itineraries))
+	for _, it := range itineraries {
+		if !it.IsEmpty() && it.Legs[0].LoadTime.Before(now) {
+			continue
+		}
+		result = append(result, it)
+	}
+	return result
+}
+
+// fingerprint returns a hash of the schedules of the voyages, which changes
+// whenever any of them does.
+func fingerprint(voyages []*voyage.Voyage) uint64 {
+	sorted := make([]*voyage.Voyage, len(voyages))
+	copy(sorted, voyages)
+	sort.Sort(byNumber(sorted))
+
+	h := fnv.New64a()
+	for _, v := range sorted {
+		fmt.Fprintf(h, "%s;%s;%d;", v.Number, v.Carrier, v.Mode)
+		for _, m := range v.Schedule.CarrierMovements {
+			fmt.Fprintf(h, "%s,%s,%d,%d;", m.DepartureLocation, m.ArrivalLocation, m.DepartureTime.UnixNano(), m.ArrivalTime.UnixNano())
+		}
+	}
+	return h.Sum64()
+}
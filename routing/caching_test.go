@@ -0,0 +1,148 @@
+package routing
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/voyage"
+)
+
+func TestCachingService(t *testing.T) {
+	now := toDate(2009, time.March, 1)
+
+	routes := []cargo.Itinerary{
+		{Legs: []cargo.Leg{
+			cargo.NewLeg("V1", location.CNHKG, location.SESTO, toDate(2009, time.March, 2), toDate(2009, time.March, 10)),
+		}},
+	}
+
+	var (
+		calls int
+		err   error
+	)
+
+	rs := stubRoutingService(func(cargo.RouteSpecification, Constraints) ([]cargo.Itinerary, error) {
+		calls++
+		if err != nil {
+			return nil, err
+		}
+		return routes, nil
+	})
+
+	c := NewCache(time.Hour, 10)
+	c.now = func() time.Time { return now }
+
+	s := NewCachingMiddleware(c)(rs)
+
+	spec := cargo.RouteSpecification{
+		Origin:          location.CNHKG,
+		Destination:     location.SESTO,
+		ArrivalDeadline: toDate(2009, time.March, 20).Add(time.Hour),
+	}
+
+	fetch := func(spec cargo.RouteSpecification) []cargo.Itinerary {
+		got, err := s.FetchRoutesForSpecification(spec, Constraints{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	if got := fetch(spec); !reflect.DeepEqual(got, routes) {
+		t.Errorf("got = %v; want = %v", got, routes)
+	}
+
+	// Deadlines within the same window share routes.
+	spec.ArrivalDeadline = toDate(2009, time.March, 20).Add(10 * time.Hour)
+	if got := fetch(spec); !reflect.DeepEqual(got, routes) {
+		t.Errorf("got = %v; want = %v", got, routes)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d; want = 1", calls)
+	}
+
+	// Stale routes are served while the routing service is unavailable.
+	now = now.Add(2 * time.Hour)
+	err = ErrUnavailable
+	if got := fetch(spec); !reflect.DeepEqual(got, routes) {
+		t.Errorf("got = %v; want = %v", got, routes)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d; want = 2", calls)
+	}
+
+	// But not if it fails for other reasons.
+	err = ErrTimeout
+	if _, got := s.FetchRoutesForSpecification(spec, Constraints{}); got != ErrTimeout {
+		t.Errorf("err = %v; want = %v", got, ErrTimeout)
+	}
+
+	// Nor after the cache has been invalidated.
+	c.Invalidate()
+	err = ErrUnavailable
+	if _, got := s.FetchRoutesForSpecification(spec, Constraints{}); got != ErrUnavailable {
+		t.Errorf("err = %v; want = %v", got, ErrUnavailable)
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	var invoked bool
+
+	rs := stubRoutingService(func(cargo.RouteSpecification, Constraints) ([]cargo.Itinerary, error) {
+		invoked = true
+		return []cargo.Itinerary{}, nil
+	})
+
+	c := NewCache(time.Hour, 2)
+	s := NewCachingMiddleware(c)(rs)
+
+	fetch := func(dest location.UNLocode) bool {
+		invoked = false
+		if _, err := s.FetchRoutesForSpecification(cargo.RouteSpecification{Origin: location.SESTO, Destination: dest}, Constraints{}); err != nil {
+			t.Fatal(err)
+		}
+		return invoked
+	}
+
+	fetch(location.CNHKG)
+	fetch(location.AUMEL)
+	fetch(location.CNHKG)
+	fetch(location.USNYC)
+
+	if fetch(location.CNHKG) {
+		t.Errorf("recently used routes should be cached")
+	}
+	if !fetch(location.AUMEL) {
+		t.Errorf("least recently used routes should be evicted")
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	v1 := voyage.New("V1", voyage.Schedule{CarrierMovements: []voyage.CarrierMovement{
+		movement(location.CNHKG, location.SESTO, toDate(2009, time.March, 2), toDate(2009, time.March, 10)),
+	}})
+	v2 := voyage.New("V2", voyage.Schedule{CarrierMovements: []voyage.CarrierMovement{
+		movement(location.SESTO, location.USNYC, toDate(2009, time.March, 12), toDate(2009, time.March, 20)),
+	}})
+
+	if fingerprint([]*voyage.Voyage{v1, v2}) != fingerprint([]*voyage.Voyage{v2, v1}) {
+		t.Errorf("fingerprint should not depend on order")
+	}
+
+	delayed := voyage.New("V2", voyage.Schedule{CarrierMovements: []voyage.CarrierMovement{
+		movement(location.SESTO, location.USNYC, toDate(2009, time.March, 13), toDate(2009, time.March, 21)),
+	}})
+
+	if fingerprint([]*voyage.Voyage{v1, v2}) == fingerprint([]*voyage.Voyage{v1, delayed}) {
+		t.Errorf("fingerprint should change with the schedule")
+	}
+}
+
+type stubRoutingService func(cargo.RouteSpecification, Constraints) ([]cargo.Itinerary, error)
+
+func (f stubRoutingService) FetchRoutesForSpecification(rs cargo.RouteSpecification, c Constraints) ([]cargo.Itinerary, error) {
+	return f(rs, c)
+}
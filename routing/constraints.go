@@ -0,0 +1,204 @@
+package routing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/voyage"
+)
+
+// Constraints narrow down the routes found for a route specification, e.g.
+// to keep a cargo out of a congested port. The zero value allows any route.
+type Constraints struct {
+	// Avoid lists the locations the cargo must not pass through.
+	Avoid []location.UNLocode
+
+	// MaxTransshipments limits how many times the cargo may change voyage,
+	// if set.
+	MaxTransshipments *int
+
+	// Carriers restricts the route to voyages operated by any of the
+	// carriers, if any are given.
+	Carriers []string
+
+	// DepartAfter is the earliest time the cargo may be loaded, if set.
+	DepartAfter time.Time
+}
+
+// IsZero returns whether the constraints allow any route.
+func (c Constraints) IsZero() bool {
+	return len(c.Avoid) == 0 && c.MaxTransshipments == nil && len(c.Carriers) == 0 && c.DepartAfter.IsZero()
+}
+
+// avoids returns whether the location is to be avoided.
+func (c Constraints) avoids(loc location.UNLocode) bool {
+	for _, a := range c.Avoid {
+		if a == loc {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsCarrier returns whether voyages of the carrier may be used.
+func (c Constraints) allowsCarrier(carrier string) bool {
+	if len(c.Carriers) == 0 {
+		return true
+	}
+	for _, cr := range c.Carriers {
+		if cr == carrier {
+			return true
+		}
+	}
+	return false
+}
+
+// allows returns whether the itinerary satisfies the constraints, given its
+// voyages.
+func (c Constraints) allows(it cargo.Itinerary, voyages map[voyage.Number]*voyage.Voyage) bool {
+	if it.IsEmpty() {
+		return true
+	}
+	if c.MaxTransshipments != nil && len(it.Legs)-1 > *c.MaxTransshipments {
+		return false
+	}
+	if !c.DepartAfter.IsZero() && it.Legs[0].LoadTime.Before(c.DepartAfter) {
+		return false
+	}
+	for _, l := range it.Legs {
+		if c.avoids(l.LoadLocation) || c.avoids(l.UnloadLocation) {
+			return false
+		}
+
+		v, ok := voyages[l.VoyageNumber]
+		if !ok {
+			if len(c.Carriers) > 0 {
+				return false
+			}
+			continue
+		}
+		if !c.allowsCarrier(v.Carrier) {
+			return false
+		}
+		for _, loc := range portCalls(v, l) {
+			if c.avoids(loc) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// portCalls returns the locations the voyage calls at between loading and
+// unloading the cargo of the leg, where the cargo stays on board. Legs that
+// cannot be found in the schedule of the voyage have none.
+func portCalls(v *voyage.Voyage, l cargo.Leg) []location.UNLocode {
+	var (
+		calls  []location.UNLocode
+		loaded bool
+	)
+	for _, m := range v.Schedule.CarrierMovements {
+		if !loaded {
+			loaded = m.DepartureLocation == l.LoadLocation &&
+				(l.LoadTime.IsZero() || m.DepartureTime.Equal(l.LoadTime))
+			if !loaded {
+				continue
+			}
+		}
+		if m.ArrivalLocation == l.UnloadLocation {
+			return calls
+		}
+		calls = append(calls, m.ArrivalLocation)
+	}
+	return nil
+}
+
+// key returns a string that is equal for equal constraints, regardless of
+// the order of locations and carriers.
+func (c Constraints) key() string {
+	if c.IsZero() {
+		return ""
+	}
+
+	avoid := make([]string, len(c.Avoid))
+	for i, a := range c.Avoid {
+		avoid[i] = string(a)
+	}
+	sort.Strings(avoid)
+
+	carriers := make([]string, len(c.Carriers))
+	copy(carriers, c.Carriers)
+	sort.Strings(carriers)
+
+	max := -1
+	if c.MaxTransshipments != nil {
+		max = *c.MaxTransshipments
+	}
+
+	return fmt.Sprintf("%s|%d|%s|%d", strings.Join(avoid, ","), max, strings.Join(carriers, ","), c.DepartAfter.Unix())
+}
+
+type constrainingService struct {
+	voyages voyage.Repository
+	Service
+}
+
+func (s constrainingService) FetchRoutesForSpecification(rs cargo.RouteSpecification, c Constraints) ([]cargo.Itinerary, error) {
+	itineraries, err := s.Service.FetchRoutesForSpecification(rs, c)
+	if err != nil || c.IsZero() {
+		return itineraries, err
+	}
+
+	var voyages map[voyage.Number]*voyage.Voyage
+	if len(c.Carriers) > 0 || len(c.Avoid) > 0 {
+		if voyages, err = s.voyagesOf(itineraries); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]cargo.Itinerary, 0, len(itineraries))
+	for _, it := range itineraries {
+		if c.allows(it, voyages) {
+			result = append(result, it)
+		}
+	}
+
+	return result, nil
+}
+
+// voyagesOf returns the voyages of the itineraries, for their carriers and
+// schedules.
+func (s constrainingService) voyagesOf(itineraries []cargo.Itinerary) (map[voyage.Number]*voyage.Voyage, error) {
+	var numbers []voyage.Number
+	for _, it := range itineraries {
+		for _, l := range it.Legs {
+			numbers = append(numbers, l.VoyageNumber)
+		}
+	}
+
+	voyages, err := s.voyages.FindMany(numbers)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[voyage.Number]*voyage.Voyage)
+	for _, v := range voyages {
+		result[v.Number] = v
+	}
+
+	return result, nil
+}
+
+// NewConstrainingMiddleware returns a new instance of a middleware that
+// leaves out the routes violating the constraints, for routing services that
+// ignore some or all of them. Carriers and port calls are looked up in the
+// voyage repository.
+func NewConstrainingMiddleware(voyages voyage.Repository) ServiceMiddleware {
+	return func(next Service) Service {
+		return constrainingService{voyages, next}
+	}
+}
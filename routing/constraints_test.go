@@ -0,0 +1,84 @@
+package routing
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/voyage"
+)
+
+func TestConstrainingService(t *testing.T) {
+	var (
+		viaHamburg = cargo.Itinerary{Legs: []cargo.Leg{
+			cargo.NewLeg("V1", location.CNHKG, location.DEHAM, toDate(2009, time.March, 1), toDate(2009, time.March, 20)),
+			cargo.NewLeg("V2", location.DEHAM, location.SESTO, toDate(2009, time.March, 21), toDate(2009, time.March, 23)),
+		}}
+		direct = cargo.Itinerary{Legs: []cargo.Leg{
+			cargo.NewLeg("V3", location.CNHKG, location.SESTO, toDate(2009, time.March, 5), toDate(2009, time.March, 30)),
+		}}
+	)
+
+	voyages := stubVoyageRepository{
+		&voyage.Voyage{Number: "V1", Carrier: "Pacific Line"},
+		&voyage.Voyage{Number: "V2", Carrier: "Baltic Feeder"},
+		// Calls at Tokyo on the way.
+		&voyage.Voyage{Number: "V3", Carrier: "Pacific Line", Schedule: voyage.Schedule{CarrierMovements: []voyage.CarrierMovement{
+			movement(location.CNHKG, location.JNTKO, toDate(2009, time.March, 5), toDate(2009, time.March, 10)),
+			movement(location.JNTKO, location.SESTO, toDate(2009, time.March, 11), toDate(2009, time.March, 30)),
+		}}},
+	}
+
+	// The backend ignores the constraints.
+	rs := stubRoutingService(func(cargo.RouteSpecification, Constraints) ([]cargo.Itinerary, error) {
+		return []cargo.Itinerary{viaHamburg, direct}, nil
+	})
+
+	s := NewConstrainingMiddleware(voyages)(rs)
+
+	none := 0
+
+	tests := []struct {
+		constraints Constraints
+		want        []cargo.Itinerary
+	}{
+		{Constraints{}, []cargo.Itinerary{viaHamburg, direct}},
+		{Constraints{Avoid: []location.UNLocode{location.DEHAM}}, []cargo.Itinerary{direct}},
+		{Constraints{MaxTransshipments: &none}, []cargo.Itinerary{direct}},
+		{Constraints{Carriers: []string{"Pacific Line"}}, []cargo.Itinerary{direct}},
+		{Constraints{Carriers: []string{"Pacific Line", "Baltic Feeder"}}, []cargo.Itinerary{viaHamburg, direct}},
+		{Constraints{DepartAfter: toDate(2009, time.March, 2)}, []cargo.Itinerary{direct}},
+		{Constraints{Avoid: []location.UNLocode{location.SESTO}}, []cargo.Itinerary{}},
+		{Constraints{Avoid: []location.UNLocode{location.JNTKO}}, []cargo.Itinerary{viaHamburg}},
+	}
+
+	for i, tt := range tests {
+		got, err := s.FetchRoutesForSpecification(cargo.RouteSpecification{
+			Origin:      location.CNHKG,
+			Destination: location.SESTO,
+		}, tt.constraints)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%d: got = %v; want = %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestConstraintsKey(t *testing.T) {
+	a := Constraints{Avoid: []location.UNLocode{location.DEHAM, location.NLRTM}}
+	b := Constraints{Avoid: []location.UNLocode{location.NLRTM, location.DEHAM}}
+
+	if a.key() != b.key() {
+		t.Errorf("key should not depend on order")
+	}
+
+	none := 0
+	if (Constraints{}).key() == (Constraints{MaxTransshipments: &none}).key() {
+		t.Errorf("key should tell no limit from no transshipments")
+	}
+}
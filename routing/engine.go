@@ -0,0 +1,253 @@
+package routing
+
+import (
+	"container/heap"
+	"sort"
+	"time"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/voyage"
+)
+
+// DefaultConnectionTime is the least time needed to transship a cargo from
+// one voyage to another.
+const DefaultConnectionTime = 6 * time.Hour
+
+// DefaultPaths is the number of itineraries found for each route
+// specification.
+const DefaultPaths = 5
+
+// hop is a carrier movement of a voyage. The hops are the nodes of a
+// time-expanded graph, where a hop is connected to the next movement of the
+// same voyage, and to the movements of other voyages departing from where
+// it arrives once the cargo has had time to connect. Voyages of any mode
+// connect, so that e.g. a truck may take the cargo on from a port.
+type hop struct {
+	voyage voyage.Number
+	mode   voyage.Mode
+	voyage.CarrierMovement
+
+	// next is the index of the next movement of the same voyage, or -1 if
+	// this is its last.
+	next int
+}
+
+// label is a path through the graph, ending at a hop.
+type label struct {
+	hop  int
+	prev *label
+	legs int
+	seq  int
+}
+
+type engine struct {
+	voyages    voyage.Repository
+	paths      int
+	connection time.Duration
+	now        func() time.Time
+}
+
+// NewEngine returns a routing service that searches the schedules of the
+// voyages in the repository, instead of asking an external pathfinder. At
+// most paths itineraries are returned, earliest arrival first, and
+// transshipments allow for at least the given connection time.
+func NewEngine(voyages voyage.Repository, paths int, connection time.Duration) Service {
+	return &engine{
+		voyages:    voyages,
+		paths:      paths,
+		connection: connection,
+		now:        time.Now,
+	}
+}
+
+// FetchRoutesForSpecification returns the k earliest arriving itineraries
+// departing after now, using a best-first search in which each hop may be
+// reached by at most k paths. The constraints are applied during the search,
+// rather than to the itineraries found.
+func (e *engine) FetchRoutesForSpecification(rs cargo.RouteSpecification, c Constraints) ([]cargo.Itinerary, error) {
+	var (
+		hops       = e.graph(rs, c)
+		departures = departuresByLocation(hops)
+		now        = e.now()
+	)
+
+	if c.DepartAfter.After(now) {
+		now = c.DepartAfter
+	}
+
+	maxLegs := -1
+	if c.MaxTransshipments != nil {
+		maxLegs = *c.MaxTransshipments + 1
+	}
+
+	reached := make([]int, len(hops))
+
+	q := &queue{hops: hops}
+	for _, i := range departures[rs.Origin] {
+		if !hops[i].DepartureTime.Before(now) {
+			heap.Push(q, &label{hop: i, legs: 1})
+		}
+	}
+
+	var itineraries []cargo.Itinerary
+	for q.Len() > 0 && len(itineraries) < e.paths {
+		l := heap.Pop(q).(*label)
+
+		if reached[l.hop] >= e.paths {
+			continue
+		}
+		reached[l.hop]++
+
+		h := hops[l.hop]
+
+		if h.ArrivalLocation == rs.Destination {
+			itineraries = append(itineraries, itinerary(hops, l))
+			continue
+		}
+
+		// Stay on board.
+		if h.next >= 0 && !visits(hops, l, hops[h.next].ArrivalLocation) {
+			heap.Push(q, &label{hop: h.next, prev: l, legs: l.legs})
+		}
+
+		// Transship to another voyage.
+		ready := h.ArrivalTime.Add(e.connection)
+		for _, i := range departures[h.ArrivalLocation] {
+			n := hops[i]
+			if n.voyage == h.voyage || n.DepartureTime.Before(ready) {
+				continue
+			}
+			if visits(hops, l, n.ArrivalLocation) {
+				continue
+			}
+			if maxLegs >= 0 && l.legs+1 > maxLegs {
+				continue
+			}
+			heap.Push(q, &label{hop: i, prev: l, legs: l.legs + 1})
+		}
+	}
+
+	return itineraries, nil
+}
+
+// graph returns the carrier movements of the voyages allowed by the
+// constraints, leaving out those arriving after the arrival deadline and
+// those calling at locations to be avoided.
+func (e *engine) graph(rs cargo.RouteSpecification, c Constraints) []hop {
+	voyages := e.voyages.FindAll()
+	sort.Sort(byNumber(voyages))
+
+	var hops []hop
+	for _, v := range voyages {
+		if !c.allowsCarrier(v.Carrier) {
+			continue
+		}
+		first := len(hops)
+		for _, m := range v.Schedule.CarrierMovements {
+			if !m.ArrivalTime.After(m.DepartureTime) {
+				continue
+			}
+			if !rs.ArrivalDeadline.IsZero() && m.ArrivalTime.After(rs.ArrivalDeadline) {
+				break
+			}
+			if c.avoids(m.DepartureLocation) || c.avoids(m.ArrivalLocation) {
+				continue
+			}
+			hops = append(hops, hop{voyage: v.Number, mode: v.Mode, CarrierMovement: m, next: -1})
+		}
+		for i := first; i < len(hops)-1; i++ {
+			if hops[i+1].DepartureLocation == hops[i].ArrivalLocation {
+				hops[i].next = i + 1
+			}
+		}
+	}
+
+	return hops
+}
+
+// departuresByLocation returns the indices of the hops departing from each
+// location.
+func departuresByLocation(hops []hop) map[location.UNLocode][]int {
+	d := make(map[location.UNLocode][]int)
+	for i, h := range hops {
+		d[h.DepartureLocation] = append(d[h.DepartureLocation], i)
+	}
+	return d
+}
+
+// visits returns whether the path has already called at a location, so that
+// the cargo never passes the same place twice.
+func visits(hops []hop, l *label, loc location.UNLocode) bool {
+	for ; l != nil; l = l.prev {
+		if hops[l.hop].DepartureLocation == loc || hops[l.hop].ArrivalLocation == loc {
+			return true
+		}
+	}
+	return false
+}
+
+// itinerary returns the legs of a path, where consecutive movements of the
+// same voyage make up one leg.
+func itinerary(hops []hop, l *label) cargo.Itinerary {
+	var path []hop
+	for ; l != nil; l = l.prev {
+		path = append([]hop{hops[l.hop]}, path...)
+	}
+
+	var legs []cargo.Leg
+	for _, h := range path {
+		if n := len(legs); n > 0 && legs[n-1].VoyageNumber == h.voyage {
+			legs[n-1].UnloadLocation = h.ArrivalLocation
+			legs[n-1].UnloadTime = h.ArrivalTime
+			continue
+		}
+		leg := cargo.NewLeg(h.voyage, h.DepartureLocation, h.ArrivalLocation, h.DepartureTime, h.ArrivalTime)
+		leg.Mode = h.mode
+		legs = append(legs, leg)
+	}
+
+	return cargo.Itinerary{Legs: legs}
+}
+
+// queue orders paths by arrival time, and then by the number of legs.
+type queue struct {
+	hops   []hop
+	labels []*label
+	pushed int
+}
+
+func (q *queue) Len() int      { return len(q.labels) }
+func (q *queue) Swap(i, j int) { q.labels[i], q.labels[j] = q.labels[j], q.labels[i] }
+func (q *queue) Less(i, j int) bool {
+	a, b := q.hops[q.labels[i].hop].ArrivalTime, q.hops[q.labels[j].hop].ArrivalTime
+	if !a.Equal(b) {
+		return a.Before(b)
+	}
+	if q.labels[i].legs != q.labels[j].legs {
+		return q.labels[i].legs < q.labels[j].legs
+	}
+	return q.labels[i].seq < q.labels[j].seq
+}
+
+// Push adds a path to the queue. Paths that are otherwise equal are taken
+// in the order they were found, for the search to be deterministic.
+func (q *queue) Push(x interface{}) {
+	l := x.(*label)
+	l.seq = q.pushed
+	q.pushed++
+	q.labels = append(q.labels, l)
+}
+
+func (q *queue) Pop() interface{} {
+	n := len(q.labels)
+	l := q.labels[n-1]
+	q.labels = q.labels[:n-1]
+	return l
+}
+
+type byNumber []*voyage.Voyage
+
+func (s byNumber) Len() int           { return len(s) }
+func (s byNumber) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byNumber) Less(i, j int) bool { return s[i].Number < s[j].Number }
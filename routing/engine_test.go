@@ -0,0 +1,198 @@
+package routing
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/voyage"
+)
+
+func TestEngine(t *testing.T) {
+	var (
+		v1 = voyage.New("V1", voyage.Schedule{CarrierMovements: []voyage.CarrierMovement{
+			movement(location.CNHKG, location.JNTKO, toDate(2009, time.March, 1), toDate(2009, time.March, 4)),
+			movement(location.JNTKO, location.USNYC, toDate(2009, time.March, 5), toDate(2009, time.March, 15)),
+		}})
+		v2 = voyage.New("V2", voyage.Schedule{CarrierMovements: []voyage.CarrierMovement{
+			movement(location.JNTKO, location.NLRTM, toDate(2009, time.March, 6), toDate(2009, time.March, 20)),
+			movement(location.NLRTM, location.DEHAM, toDate(2009, time.March, 21), toDate(2009, time.March, 22)),
+		}})
+		v3 = voyage.New("V3", voyage.Schedule{CarrierMovements: []voyage.CarrierMovement{
+			movement(location.DEHAM, location.SESTO, toDate(2009, time.March, 23), toDate(2009, time.March, 25)),
+		}})
+		// Departs from Tokyo before V1 arrives.
+		v4 = voyage.New("V4", voyage.Schedule{CarrierMovements: []voyage.CarrierMovement{
+			movement(location.JNTKO, location.SESTO, toDate(2009, time.March, 3), toDate(2009, time.March, 10)),
+		}})
+		// Departs from Tokyo within the connection time.
+		v5 = voyage.New("V5", voyage.Schedule{CarrierMovements: []voyage.CarrierMovement{
+			movement(location.JNTKO, location.SESTO, toDate(2009, time.March, 4).Add(2*time.Hour), toDate(2009, time.March, 11)),
+		}})
+		// Slow, but direct.
+		v6 = voyage.New("V6", voyage.Schedule{CarrierMovements: []voyage.CarrierMovement{
+			movement(location.CNHKG, location.SESTO, toDate(2009, time.March, 2), toDate(2009, time.March, 30)),
+		}})
+	)
+
+	v6.Carrier = "Slow Line"
+
+	e := &engine{
+		voyages:    stubVoyageRepository{v6, v5, v4, v3, v2, v1},
+		paths:      DefaultPaths,
+		connection: DefaultConnectionTime,
+		now:        func() time.Time { return toDate(2009, time.February, 1) },
+	}
+
+	viaHamburg := cargo.Itinerary{Legs: []cargo.Leg{
+		cargo.NewLeg("V1", location.CNHKG, location.JNTKO, toDate(2009, time.March, 1), toDate(2009, time.March, 4)),
+		cargo.NewLeg("V2", location.JNTKO, location.DEHAM, toDate(2009, time.March, 6), toDate(2009, time.March, 22)),
+		cargo.NewLeg("V3", location.DEHAM, location.SESTO, toDate(2009, time.March, 23), toDate(2009, time.March, 25)),
+	}}
+	direct := cargo.Itinerary{Legs: []cargo.Leg{
+		cargo.NewLeg("V6", location.CNHKG, location.SESTO, toDate(2009, time.March, 2), toDate(2009, time.March, 30)),
+	}}
+
+	none := 0
+
+	tests := []struct {
+		deadline    time.Time
+		now         time.Time
+		paths       int
+		constraints Constraints
+		want        []cargo.Itinerary
+	}{
+		{time.Time{}, toDate(2009, time.February, 1), DefaultPaths, Constraints{}, []cargo.Itinerary{viaHamburg, direct}},
+		{toDate(2009, time.March, 26), toDate(2009, time.February, 1), DefaultPaths, Constraints{}, []cargo.Itinerary{viaHamburg}},
+		{time.Time{}, toDate(2009, time.February, 1), 1, Constraints{}, []cargo.Itinerary{viaHamburg}},
+		{time.Time{}, toDate(2009, time.March, 2), DefaultPaths, Constraints{}, []cargo.Itinerary{direct}},
+		{toDate(2009, time.March, 20), toDate(2009, time.February, 1), DefaultPaths, Constraints{}, nil},
+		{time.Time{}, toDate(2009, time.February, 1), DefaultPaths, Constraints{Avoid: []location.UNLocode{location.DEHAM}}, []cargo.Itinerary{direct}},
+		{time.Time{}, toDate(2009, time.February, 1), DefaultPaths, Constraints{MaxTransshipments: &none}, []cargo.Itinerary{direct}},
+		{time.Time{}, toDate(2009, time.February, 1), DefaultPaths, Constraints{Carriers: []string{"Slow Line"}}, []cargo.Itinerary{direct}},
+		{time.Time{}, toDate(2009, time.February, 1), DefaultPaths, Constraints{DepartAfter: toDate(2009, time.March, 2)}, []cargo.Itinerary{direct}},
+	}
+
+	for i, tt := range tests {
+		e.now = func() time.Time { return tt.now }
+		e.paths = tt.paths
+
+		got, err := e.FetchRoutesForSpecification(cargo.RouteSpecification{
+			Origin:          location.CNHKG,
+			Destination:     location.SESTO,
+			ArrivalDeadline: tt.deadline,
+		}, tt.constraints)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%d: got = %v; want = %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestEngineModes(t *testing.T) {
+	truck := voyage.New("T1", voyage.Schedule{CarrierMovements: []voyage.CarrierMovement{
+		movement(location.NLRTM, location.DEHAM, toDate(2009, time.March, 1), toDate(2009, time.March, 2)),
+	}})
+	truck.Mode = voyage.Road
+
+	ship := voyage.New("V1", voyage.Schedule{CarrierMovements: []voyage.CarrierMovement{
+		movement(location.DEHAM, location.SESTO, toDate(2009, time.March, 3), toDate(2009, time.March, 5)),
+	}})
+
+	e := &engine{
+		voyages:    stubVoyageRepository{truck, ship},
+		paths:      DefaultPaths,
+		connection: DefaultConnectionTime,
+		now:        func() time.Time { return toDate(2009, time.February, 1) },
+	}
+
+	got, err := e.FetchRoutesForSpecification(cargo.RouteSpecification{
+		Origin:      location.NLRTM,
+		Destination: location.SESTO,
+	}, Constraints{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 1 || len(got[0].Legs) != 2 {
+		t.Fatalf("got = %v", got)
+	}
+	if m := got[0].Legs[0].Mode; m != voyage.Road {
+		t.Errorf("Legs[0].Mode = %s; want = %s", m, voyage.Road)
+	}
+	if m := got[0].Legs[1].Mode; m != voyage.Sea {
+		t.Errorf("Legs[1].Mode = %s; want = %s", m, voyage.Sea)
+	}
+}
+
+type stubVoyageRepository []*voyage.Voyage
+
+func (r stubVoyageRepository) Find(n voyage.Number) (*voyage.Voyage, error) {
+	for _, v := range r {
+		if v.Number == n {
+			return v, nil
+		}
+	}
+	return nil, voyage.ErrUnknown
+}
+
+func (r stubVoyageRepository) FindMany(numbers []voyage.Number) ([]*voyage.Voyage, error) {
+	var result []*voyage.Voyage
+	for _, n := range numbers {
+		if v, err := r.Find(n); err == nil {
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
+
+func (r stubVoyageRepository) FindAll() []*voyage.Voyage {
+	return r
+}
+
+func movement(from, to location.UNLocode, departure, arrival time.Time) voyage.CarrierMovement {
+	return voyage.CarrierMovement{
+		DepartureLocation: from,
+		ArrivalLocation:   to,
+		DepartureTime:     departure,
+		ArrivalTime:       arrival,
+	}
+}
+
+func TestEngineSampleVoyages(t *testing.T) {
+	voyages := stubVoyageRepository{voyage.V100, voyage.V300, voyage.V400, voyage.R100, voyage.T100}
+
+	// The sample cargos can be routed in time when the application has just
+	// been started, and there are still routes long after.
+	for _, days := range []int{0, 200} {
+		now := time.Now().AddDate(0, 0, days)
+
+		e := &engine{
+			voyages:    voyages,
+			paths:      DefaultPaths,
+			connection: DefaultConnectionTime,
+			now:        func() time.Time { return now },
+		}
+
+		for _, rs := range []cargo.RouteSpecification{
+			{Origin: location.CNHKG, Destination: location.SESTO, ArrivalDeadline: now.AddDate(0, 0, 45)},
+			{Origin: location.SESTO, Destination: location.AUMEL, ArrivalDeadline: now.AddDate(0, 0, 60)},
+		} {
+			if days > 0 {
+				rs.ArrivalDeadline = time.Time{}
+			}
+			got, err := e.FetchRoutesForSpecification(rs, Constraints{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) == 0 {
+				t.Errorf("%d days in: no routes from %s to %s", days, rs.Origin, rs.Destination)
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package routing
+
+import "github.com/marcusolsson/goddd/cargo"
+
+// Policy selects which of a number of ranked candidates a cargo should be
+// assigned to.
+type Policy interface {
+	// Select returns the chosen candidate, or false if none qualifies.
+	Select(rs cargo.RouteSpecification, candidates []Candidate) (Candidate, bool)
+}
+
+// PolicyFunc is an adapter to allow the use of ordinary functions as
+// policies.
+type PolicyFunc func(rs cargo.RouteSpecification, candidates []Candidate) (Candidate, bool)
+
+// Select calls f(rs, candidates).
+func (f PolicyFunc) Select(rs cargo.RouteSpecification, candidates []Candidate) (Candidate, bool) {
+	return f(rs, candidates)
+}
+
+// HighestRanked selects the first candidate satisfying the route
+// specification.
+var HighestRanked Policy = PolicyFunc(func(rs cargo.RouteSpecification, candidates []Candidate) (Candidate, bool) {
+	for _, c := range candidates {
+		if rs.IsSatisfiedBy(c.Itinerary) {
+			return c, true
+		}
+	}
+	return Candidate{}, false
+})
+
+// MaxTransshipments returns a policy selecting the highest ranked candidate
+// with at most n transshipments.
+func MaxTransshipments(n int) Policy {
+	return PolicyFunc(func(rs cargo.RouteSpecification, candidates []Candidate) (Candidate, bool) {
+		var eligible []Candidate
+		for _, c := range candidates {
+			if len(c.Legs)-1 <= n {
+				eligible = append(eligible, c)
+			}
+		}
+		return HighestRanked.Select(rs, eligible)
+	})
+}
@@ -2,12 +2,19 @@ package routing
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/afex/hystrix-go/hystrix"
 	"github.com/go-kit/kit/circuitbreaker"
 	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/loadbalancer"
 	kithttp "github.com/go-kit/kit/transport/http"
 	"golang.org/x/net/context"
 
@@ -22,13 +29,16 @@ type proxyService struct {
 	Service
 }
 
-func (s proxyService) FetchRoutesForSpecification(rs cargo.RouteSpecification) []cargo.Itinerary {
+// FetchRoutesForSpecification forwards the constraints to the pathfinder,
+// which may or may not apply them.
+func (s proxyService) FetchRoutesForSpecification(rs cargo.RouteSpecification, c Constraints) ([]cargo.Itinerary, error) {
 	response, err := s.FetchRoutesEndpoint(s.Context, fetchRoutesRequest{
-		From: string(rs.Origin),
-		To:   string(rs.Destination),
+		From:        string(rs.Origin),
+		To:          string(rs.Destination),
+		Constraints: c,
 	})
 	if err != nil {
-		return []cargo.Itinerary{}
+		return nil, proxyError(err)
 	}
 
 	resp := response.(fetchRoutesResponse)
@@ -43,31 +53,148 @@ func (s proxyService) FetchRoutesForSpecification(rs cargo.RouteSpecification) [
 				UnloadLocation: location.UNLocode(e.Destination),
 				LoadTime:       e.Departure,
 				UnloadTime:     e.Arrival,
+				Mode:           parseMode(e.Mode),
 			})
 		}
 
 		itineraries = append(itineraries, cargo.Itinerary{Legs: legs})
 	}
 
-	return itineraries
+	return itineraries, nil
+}
+
+// proxyError translates the errors of the load balancer, so that callers can
+// tell a slow pathfinder from an unavailable one. Other errors, such as
+// malformed responses or rejected requests, are passed on unchanged.
+func proxyError(err error) error {
+	if err == context.Canceled {
+		return err
+	}
+	if isTimeout(err) {
+		return ErrTimeout
+	}
+	if isUnavailable(err) {
+		return ErrUnavailable
+	}
+	return err
+}
+
+// isTimeout checks whether an error means that the pathfinder did not respond
+// in time, either to us or to its circuit breaker.
+func isTimeout(err error) bool {
+	if err == context.DeadlineExceeded || err == hystrix.ErrTimeout {
+		return true
+	}
+	if e, ok := err.(kithttp.Error); ok && e.Domain == kithttp.DomainDo {
+		if e.Err == context.DeadlineExceeded {
+			return true
+		}
+		if ne, ok := e.Err.(net.Error); ok && ne.Timeout() {
+			return true
+		}
+	}
+	return false
+}
+
+// isUnavailable checks whether an error means that the pathfinder could not
+// be reached or failed to respond, in which case another instance may do
+// better. Timeouts are not included, since they are reported as such.
+func isUnavailable(err error) bool {
+	switch err {
+	case loadbalancer.ErrNoEndpoints, hystrix.ErrCircuitOpen, hystrix.ErrMaxConcurrency:
+		return true
+	}
+	if e, ok := err.(kithttp.Error); ok {
+		if e.Domain == kithttp.DomainDo {
+			return !isTimeout(err)
+		}
+		if se, ok := e.Err.(statusError); ok {
+			return se.code >= 500
+		}
+	}
+	return false
+}
+
+// retry balances requests over the instances, trying the next one as long as
+// the previous one was unavailable, up to max attempts or until the timeout
+// has elapsed. Any other error is returned right away, since another instance
+// would most likely respond the same.
+func retry(max int, timeout time.Duration, lb loadbalancer.LoadBalancer) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		var err error
+		for i := 0; i < max; i++ {
+			var e endpoint.Endpoint
+			if e, err = lb.Endpoint(); err != nil {
+				return nil, err
+			}
+
+			var response interface{}
+			if response, err = e(ctx, request); err == nil {
+				return response, nil
+			}
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if !isUnavailable(err) {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
 }
 
 // ServiceMiddleware defines a middleware for a routing service.
 type ServiceMiddleware func(Service) Service
 
-// NewProxyingMiddleware returns a new instance of a proxying middleware.
-func NewProxyingMiddleware(proxyURL string, ctx context.Context) ServiceMiddleware {
+// NewProxyingMiddleware returns a new instance of a proxying middleware,
+// balancing requests over the pathfinder instances of the publisher in
+// round-robin order. Failed requests are retried on the next instance, up to
+// max attempts, as long as the timeout has not elapsed.
+func NewProxyingMiddleware(instances loadbalancer.Publisher, max int, timeout time.Duration, ctx context.Context) ServiceMiddleware {
 	return func(next Service) Service {
 		var e endpoint.Endpoint
-		e = makeFetchRoutesEndpoint(ctx, proxyURL)
-		e = circuitbreaker.Hystrix("fetch-routes")(e)
+		e = retry(max, timeout, loadbalancer.NewRoundRobin(instances))
 		return proxyService{ctx, e, next}
 	}
 }
 
+// NewFactory returns a factory of endpoints fetching routes from pathfinder
+// instances, each behind a circuit breaker of its own. The circuit breakers
+// give up on requests after the timeout, which should be no shorter than the
+// one of the proxying middleware. Instances are either URLs, or host:port as
+// resolved from DNS SRV records.
+func NewFactory(timeout time.Duration) loadbalancer.Factory {
+	return func(instance string) (endpoint.Endpoint, io.Closer, error) {
+		if !strings.Contains(instance, "://") {
+			instance = "http://" + instance
+		}
+		u, err := url.Parse(instance)
+		if err != nil {
+			return nil, nil, err
+		}
+		if u.Path == "" {
+			u.Path = "/paths"
+		}
+
+		name := "fetch-routes " + u.Host
+		hystrix.ConfigureCommand(name, hystrix.CommandConfig{
+			Timeout: int(timeout / time.Millisecond),
+		})
+
+		var e endpoint.Endpoint
+		e = makeFetchRoutesEndpoint(u)
+		e = circuitbreaker.Hystrix(name)(e)
+		return e, nil, nil
+	}
+}
+
 type fetchRoutesRequest struct {
-	From string
-	To   string
+	From        string
+	To          string
+	Constraints Constraints
 }
 
 type fetchRoutesResponse struct {
@@ -78,18 +205,16 @@ type fetchRoutesResponse struct {
 			Voyage      string    `json:"voyage"`
 			Departure   time.Time `json:"departure"`
 			Arrival     time.Time `json:"arrival"`
+
+			// Mode is left out by pathfinders that only know of vessels.
+			// It is decoded leniently, so that a mode unknown to us does
+			// not fail the whole response.
+			Mode string `json:"mode"`
 		} `json:"edges"`
 	} `json:"paths"`
 }
 
-func makeFetchRoutesEndpoint(ctx context.Context, instance string) endpoint.Endpoint {
-	u, err := url.Parse(instance)
-	if err != nil {
-		panic(err)
-	}
-	if u.Path == "" {
-		u.Path = "/paths"
-	}
+func makeFetchRoutesEndpoint(u *url.URL) endpoint.Endpoint {
 	return kithttp.NewClient(
 		"GET", u,
 		encodeFetchRoutesRequest,
@@ -97,7 +222,21 @@ func makeFetchRoutesEndpoint(ctx context.Context, instance string) endpoint.Endp
 	).Endpoint()
 }
 
+// statusError is used when a pathfinder responds with an error status.
+type statusError struct {
+	code   int
+	status string
+}
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("pathfinder responded %s", e.status)
+}
+
 func decodeFetchRoutesResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, statusError{code: resp.StatusCode, status: resp.Status}
+	}
+
 	var response fetchRoutesResponse
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
 		return nil, err
@@ -111,7 +250,30 @@ func encodeFetchRoutesRequest(_ context.Context, r *http.Request, request interf
 	vals := r.URL.Query()
 	vals.Add("from", req.From)
 	vals.Add("to", req.To)
+
+	for _, a := range req.Constraints.Avoid {
+		vals.Add("avoid", string(a))
+	}
+	if max := req.Constraints.MaxTransshipments; max != nil {
+		vals.Add("max_transshipments", strconv.Itoa(*max))
+	}
+	for _, c := range req.Constraints.Carriers {
+		vals.Add("carrier", c)
+	}
+	if t := req.Constraints.DepartAfter; !t.IsZero() {
+		vals.Add("depart_after", t.Format(time.RFC3339))
+	}
 	r.URL.RawQuery = vals.Encode()
 
 	return nil
 }
+
+// parseMode returns the mode of an edge, taking missing and unknown modes to
+// be sea.
+func parseMode(s string) voyage.Mode {
+	m, err := voyage.ParseMode(s)
+	if err != nil {
+		return voyage.Sea
+	}
+	return m
+}
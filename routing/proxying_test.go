@@ -0,0 +1,197 @@
+package routing
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/loadbalancer/static"
+	"github.com/go-kit/kit/log"
+	"golang.org/x/net/context"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/voyage"
+)
+
+func TestProxyRetriesOnNextInstance(t *testing.T) {
+	var calls int32
+
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"paths": [{"edges": [{"origin": "SESTO", "destination": "CNHKG", "voyage": "V100"}]}]}`)
+	}))
+	defer good.Close()
+
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}))
+	defer bad.Close()
+
+	instances := static.NewPublisher([]string{good.URL, bad.URL}, NewFactory(time.Second), log.NewNopLogger())
+
+	s := NewProxyingMiddleware(instances, 2, time.Second, context.Background())(nil)
+
+	spec := cargo.RouteSpecification{Origin: location.SESTO, Destination: location.CNHKG}
+
+	for i := 0; i < 4; i++ {
+		got, err := s.FetchRoutesForSpecification(spec, Constraints{})
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if len(got) != 1 || got[0].Legs[0].VoyageNumber != "V100" {
+			t.Errorf("%d: got = %v", i, got)
+		}
+	}
+
+	// Retries move the rotation along, so every request but the first
+	// reaches the bad instance before being retried on the good one.
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("calls = %d; want = 3", got)
+	}
+}
+
+func TestProxyUnavailable(t *testing.T) {
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}))
+	defer bad.Close()
+
+	instances := static.NewPublisher([]string{bad.URL}, NewFactory(time.Second), log.NewNopLogger())
+
+	s := NewProxyingMiddleware(instances, 2, time.Second, context.Background())(nil)
+
+	if _, err := s.FetchRoutesForSpecification(cargo.RouteSpecification{}, Constraints{}); err != ErrUnavailable {
+		t.Errorf("err = %v; want = %v", err, ErrUnavailable)
+	}
+}
+
+func TestProxyTimeout(t *testing.T) {
+	for _, tt := range []struct {
+		breaker time.Duration
+		timeout time.Duration
+	}{
+		{breaker: 50 * time.Millisecond, timeout: time.Second},
+		{breaker: time.Second, timeout: 50 * time.Millisecond},
+	} {
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+			fmt.Fprint(w, `{"paths": []}`)
+		}))
+
+		instances := static.NewPublisher([]string{slow.URL}, NewFactory(tt.breaker), log.NewNopLogger())
+
+		s := NewProxyingMiddleware(instances, 2, tt.timeout, context.Background())(nil)
+
+		if _, err := s.FetchRoutesForSpecification(cargo.RouteSpecification{}, Constraints{}); err != ErrTimeout {
+			t.Errorf("breaker %s, timeout %s: err = %v; want = %v", tt.breaker, tt.timeout, err, ErrTimeout)
+		}
+
+		slow.Close()
+	}
+}
+
+func TestProxyBadResponse(t *testing.T) {
+	for _, h := range []http.HandlerFunc{
+		func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unknown location", http.StatusBadRequest)
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"paths": [`)
+		},
+	} {
+		var calls int32
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			h(w, r)
+		}))
+
+		instances := static.NewPublisher([]string{srv.URL}, NewFactory(time.Second), log.NewNopLogger())
+
+		s := NewProxyingMiddleware(instances, 2, time.Second, context.Background())(nil)
+
+		// The pathfinder is up, so the error is neither reported as it
+		// being unavailable nor retried.
+		_, err := s.FetchRoutesForSpecification(cargo.RouteSpecification{}, Constraints{})
+		if err == nil || err == ErrUnavailable {
+			t.Errorf("err = %v; want error other than %v", err, ErrUnavailable)
+		}
+		if got := atomic.LoadInt32(&calls); got != 1 {
+			t.Errorf("calls = %d; want = 1", got)
+		}
+
+		srv.Close()
+	}
+}
+
+func TestProxyUnknownMode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"paths": [{"edges": [
+			{"origin": "NLRTM", "destination": "DEHAM", "voyage": "T100", "mode": "road"},
+			{"origin": "DEHAM", "destination": "SESTO", "voyage": "H100", "mode": "hovercraft"}
+		]}]}`)
+	}))
+	defer srv.Close()
+
+	instances := static.NewPublisher([]string{srv.URL}, NewFactory(time.Second), log.NewNopLogger())
+
+	s := NewProxyingMiddleware(instances, 1, time.Second, context.Background())(nil)
+
+	got, err := s.FetchRoutesForSpecification(cargo.RouteSpecification{}, Constraints{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 1 || len(got[0].Legs) != 2 {
+		t.Fatalf("got = %v", got)
+	}
+	if m := got[0].Legs[0].Mode; m != voyage.Road {
+		t.Errorf("Legs[0].Mode = %s; want = %s", m, voyage.Road)
+	}
+	if m := got[0].Legs[1].Mode; m != voyage.Sea {
+		t.Errorf("Legs[1].Mode = %s; want = %s", m, voyage.Sea)
+	}
+}
+
+func TestFilePublisher(t *testing.T) {
+	f, err := ioutil.TempFile("", "instances")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	fmt.Fprint(f, "# pathfinder\nhttp://pathfinder-1:8080\n\nhttp://pathfinder-2:8080\n")
+	f.Close()
+
+	p := NewFilePublisher(f.Name(), 10*time.Millisecond, NewFactory(time.Second), log.NewNopLogger())
+	defer p.Stop()
+
+	if got := endpoints(t, p); got != 2 {
+		t.Fatalf("len(endpoints) = %d; want = 2", got)
+	}
+
+	if err := ioutil.WriteFile(f.Name(), []byte("http://pathfinder-1:8080\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for start := time.Now(); endpoints(t, p) != 1; {
+		if time.Since(start) > time.Second {
+			t.Fatal("instances were not updated")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func endpoints(t *testing.T, p *FilePublisher) int {
+	e, err := p.Endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(e)
+}
@@ -0,0 +1,102 @@
+package routing
+
+import (
+	"bufio"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/loadbalancer"
+	"github.com/go-kit/kit/log"
+)
+
+// FilePublisher yields endpoints for the instances listed in a file, one per
+// line. Blank lines and lines starting with # are ignored. The file is read
+// again on a fixed schedule, so that instances can be added and removed
+// without a restart.
+type FilePublisher struct {
+	path   string
+	cache  *loadbalancer.EndpointCache
+	logger log.Logger
+	quit   chan struct{}
+}
+
+// NewFilePublisher returns a file publisher. The file is read as part of
+// construction; if that fails, the publisher starts out without instances.
+func NewFilePublisher(path string, interval time.Duration, factory loadbalancer.Factory, logger log.Logger) *FilePublisher {
+	p := &FilePublisher{
+		path:   path,
+		cache:  loadbalancer.NewEndpointCache(factory, logger),
+		logger: logger,
+		quit:   make(chan struct{}),
+	}
+
+	instances, err := readInstances(path)
+	if err == nil {
+		logger.Log("path", path, "instances", len(instances))
+	} else {
+		logger.Log("path", path, "err", err)
+	}
+	p.cache.Replace(instances)
+
+	go p.loop(time.NewTicker(interval), instances)
+
+	return p
+}
+
+// Stop terminates the publisher.
+func (p *FilePublisher) Stop() {
+	close(p.quit)
+}
+
+func (p *FilePublisher) loop(ticker *time.Ticker, last []string) {
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			instances, err := readInstances(p.path)
+			if err != nil {
+				// Keep the instances we have until the file can be read.
+				p.logger.Log("path", p.path, "err", err)
+				continue
+			}
+			if reflect.DeepEqual(instances, last) {
+				continue
+			}
+			p.logger.Log("path", p.path, "instances", len(instances))
+			p.cache.Replace(instances)
+			last = instances
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// Endpoints implements the Publisher interface.
+func (p *FilePublisher) Endpoints() ([]endpoint.Endpoint, error) {
+	return p.cache.Endpoints()
+}
+
+func readInstances(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return []string{}, err
+	}
+	defer f.Close()
+
+	instances := []string{}
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		instances = append(instances, line)
+	}
+
+	return instances, s.Err()
+}
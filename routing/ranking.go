@@ -0,0 +1,106 @@
+package routing
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/marcusolsson/goddd/cargo"
+)
+
+// Weights determines how much each property of an itinerary contributes to
+// its score. Transit time and slack are weighed per day.
+type Weights struct {
+	TransitTime    float64
+	Transshipments float64
+	Slack          float64
+}
+
+// DefaultWeights favors short transit times and penalizes transshipments
+// more than a day of transit.
+var DefaultWeights = Weights{
+	TransitTime:    1.0,
+	Transshipments: 2.0,
+	Slack:          0.5,
+}
+
+// Candidate is an itinerary that has been evaluated against a route
+// specification. A higher score is better.
+type Candidate struct {
+	cargo.Itinerary
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// Ranker filters and orders itineraries suggested by a routing service.
+type Ranker interface {
+	// Rank removes the itineraries that violate the route specification
+	// and returns the rest, best first.
+	Rank(rs cargo.RouteSpecification, itineraries []cargo.Itinerary) []Candidate
+}
+
+type ranker struct {
+	weights Weights
+}
+
+func (r *ranker) Rank(rs cargo.RouteSpecification, itineraries []cargo.Itinerary) []Candidate {
+	candidates := make([]Candidate, 0, len(itineraries))
+	for _, it := range itineraries {
+		if it.IsEmpty() {
+			continue
+		}
+		if !rs.ArrivalDeadline.IsZero() && it.FinalArrivalTime().After(rs.ArrivalDeadline) {
+			continue
+		}
+		candidates = append(candidates, r.evaluate(rs, it))
+	}
+
+	sort.Stable(byScore(candidates))
+
+	return candidates
+}
+
+func (r *ranker) evaluate(rs cargo.RouteSpecification, it cargo.Itinerary) Candidate {
+	var (
+		transit        = it.FinalArrivalTime().Sub(it.Legs[0].LoadTime)
+		transshipments = len(it.Legs) - 1
+		slack          time.Duration
+	)
+
+	if !rs.ArrivalDeadline.IsZero() {
+		slack = rs.ArrivalDeadline.Sub(it.FinalArrivalTime())
+	}
+
+	score := -r.weights.TransitTime*days(transit) -
+		r.weights.Transshipments*float64(transshipments) +
+		r.weights.Slack*days(slack)
+
+	reasons := []string{
+		fmt.Sprintf("transit time of %.1f days", days(transit)),
+		fmt.Sprintf("%d transshipment(s)", transshipments),
+	}
+	if !rs.ArrivalDeadline.IsZero() {
+		reasons = append(reasons, fmt.Sprintf("arrives %.1f days before deadline", days(slack)))
+	}
+
+	return Candidate{
+		Itinerary: it,
+		Score:     score,
+		Reasons:   reasons,
+	}
+}
+
+type byScore []Candidate
+
+func (s byScore) Len() int           { return len(s) }
+func (s byScore) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byScore) Less(i, j int) bool { return s[i].Score > s[j].Score }
+
+func days(d time.Duration) float64 {
+	return d.Hours() / 24
+}
+
+// NewRanker returns a ranker scoring itineraries using the given weights.
+func NewRanker(w Weights) Ranker {
+	return &ranker{weights: w}
+}
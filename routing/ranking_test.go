@@ -0,0 +1,70 @@
+package routing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/location"
+)
+
+func TestRank(t *testing.T) {
+	rs := cargo.RouteSpecification{
+		Origin:          location.SESTO,
+		Destination:     location.AUMEL,
+		ArrivalDeadline: toDate(2009, time.March, 20),
+	}
+
+	var (
+		direct = cargo.Itinerary{Legs: []cargo.Leg{
+			cargo.NewLeg("V100", location.SESTO, location.AUMEL, toDate(2009, time.March, 1), toDate(2009, time.March, 10)),
+		}}
+		transshipped = cargo.Itinerary{Legs: []cargo.Leg{
+			cargo.NewLeg("V100", location.SESTO, location.DEHAM, toDate(2009, time.March, 1), toDate(2009, time.March, 3)),
+			cargo.NewLeg("V200", location.DEHAM, location.AUMEL, toDate(2009, time.March, 4), toDate(2009, time.March, 10)),
+		}}
+		late = cargo.Itinerary{Legs: []cargo.Leg{
+			cargo.NewLeg("V300", location.SESTO, location.AUMEL, toDate(2009, time.March, 1), toDate(2009, time.March, 21)),
+		}}
+	)
+
+	r := NewRanker(DefaultWeights)
+
+	got := r.Rank(rs, []cargo.Itinerary{late, transshipped, direct})
+
+	if len(got) != 2 {
+		t.Fatalf("len(got) = %d; want = %d", len(got), 2)
+	}
+	if got[0].Legs[0].VoyageNumber != "V100" || len(got[0].Legs) != 1 {
+		t.Errorf("got[0] = %v; want direct itinerary", got[0].Itinerary)
+	}
+	if got[0].Score <= got[1].Score {
+		t.Errorf("got[0].Score = %f; want > %f", got[0].Score, got[1].Score)
+	}
+	if len(got[0].Reasons) == 0 {
+		t.Errorf("missing reasons")
+	}
+}
+
+func TestRankPrefersSlack(t *testing.T) {
+	rs := cargo.RouteSpecification{ArrivalDeadline: toDate(2009, time.March, 20)}
+
+	var (
+		early = cargo.Itinerary{Legs: []cargo.Leg{
+			cargo.NewLeg("V100", location.SESTO, location.AUMEL, toDate(2009, time.March, 1), toDate(2009, time.March, 5)),
+		}}
+		tight = cargo.Itinerary{Legs: []cargo.Leg{
+			cargo.NewLeg("V200", location.SESTO, location.AUMEL, toDate(2009, time.March, 15), toDate(2009, time.March, 19)),
+		}}
+	)
+
+	got := NewRanker(Weights{Slack: 1}).Rank(rs, []cargo.Itinerary{tight, early})
+
+	if got[0].Legs[0].VoyageNumber != "V100" {
+		t.Errorf("got[0].Legs[0].VoyageNumber = %s; want = %s", got[0].Legs[0].VoyageNumber, "V100")
+	}
+}
+
+func toDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 12, 00, 00, 00, time.UTC)
+}
@@ -1,13 +1,25 @@
-// Package routing provides the routing domain service. It does not actually
-// implement the routing service but merely acts as a proxy for a separate
-// bounded context.
+// Package routing provides the routing domain service. Routes are either
+// fetched from the pathfinder, a separate bounded context, or searched for in
+// the voyage schedules by the built-in engine.
 package routing
 
-import "github.com/marcusolsson/goddd/cargo"
+import (
+	"errors"
 
-// Service provides access to an external routing service.
+	"github.com/marcusolsson/goddd/cargo"
+)
+
+// ErrUnavailable is returned when the routing service cannot be reached,
+// e.g. because the circuit breaker has opened after repeated failures.
+var ErrUnavailable = errors.New("routing service unavailable")
+
+// ErrTimeout is returned when the routing service did not respond in time.
+var ErrTimeout = errors.New("routing service timed out")
+
+// Service provides access to a routing service.
 type Service interface {
 	// FetchRoutesForSpecification finds all possible routes that satisfy a
-	// given specification.
-	FetchRoutesForSpecification(rs cargo.RouteSpecification) []cargo.Itinerary
+	// given specification, within the constraints. Finding no routes is not
+	// an error.
+	FetchRoutesForSpecification(rs cargo.RouteSpecification, c Constraints) ([]cargo.Itinerary, error)
 }
@@ -0,0 +1,114 @@
+package subscribing
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrForbiddenHost is used when a callback URL points at a host that is not
+// on the public internet, such as the loopback interface, a private network
+// or the metadata service of a cloud provider.
+var ErrForbiddenHost = errors.New("callback host is not allowed")
+
+// forbiddenNetworks are the address ranges callbacks may not be sent to.
+var forbiddenNetworks = parseNetworks(
+	"0.0.0.0/8",      // "This" network
+	"10.0.0.0/8",     // Private
+	"100.64.0.0/10",  // Shared address space
+	"127.0.0.0/8",    // Loopback
+	"169.254.0.0/16", // Link-local, including cloud metadata services
+	"172.16.0.0/12",  // Private
+	"192.168.0.0/16", // Private
+	"::/128",         // Unspecified
+	"::1/128",        // Loopback
+	"fc00::/7",       // Unique local
+	"fe80::/10",      // Link-local
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, s := range cidrs {
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, n)
+	}
+	return networks
+}
+
+// isAllowedIP checks whether callbacks may be sent to the address.
+func isAllowedIP(ip net.IP) bool {
+	if ip.IsMulticast() {
+		return false
+	}
+	for _, n := range forbiddenNetworks {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkHost looks up the addresses of a callback host, failing unless every
+// one of them is allowed.
+func checkHost(host string, lookupIP func(string) ([]net.IP, error)) ([]net.IP, error) {
+	ips, err := lookupIP(host)
+	if err != nil || len(ips) == 0 {
+		return nil, ErrInvalidArgument
+	}
+	for _, ip := range ips {
+		if !isAllowedIP(ip) {
+			return nil, ErrForbiddenHost
+		}
+	}
+	return ips, nil
+}
+
+// hostname returns the host of a URL without its port.
+func hostname(u *url.URL) string {
+	host, _, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		host = u.Host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+}
+
+// lookupIP resolves a host, taking IP addresses as they are.
+func lookupIP(host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	return net.LookupIP(host)
+}
+
+// NewCallbackClient returns an HTTP client for delivering to callback URLs.
+// The host is checked again when connecting, redirects included, since what
+// a name resolves to may have changed since the subscription was made.
+func NewCallbackClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Dial: func(network, addr string) (net.Conn, error) {
+				host, port, err := net.SplitHostPort(addr)
+				if err != nil {
+					return nil, err
+				}
+				ips, err := checkHost(host, lookupIP)
+				if err != nil {
+					return nil, err
+				}
+				// Connect to the address that was checked, rather than
+				// resolving the host again.
+				return dialer.Dial(network, net.JoinHostPort(ips[0].String(), port))
+			},
+			TLSHandshakeTimeout: timeout,
+		},
+	}
+}
@@ -0,0 +1,55 @@
+package subscribing
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIsAllowedIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"0.0.0.0", false},
+		{"127.0.0.1", false},
+		{"10.0.0.1", false},
+		{"100.64.0.1", false},
+		{"169.254.169.254", false},
+		{"172.16.0.1", false},
+		{"172.32.0.1", true},
+		{"192.168.1.1", false},
+		{"224.0.0.1", false},
+		{"::", false},
+		{"::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+	}
+
+	for _, tt := range tests {
+		if got := isAllowedIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("isAllowedIP(%s) = %v; want = %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestCallbackClient(t *testing.T) {
+	var called bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	_, err := NewCallbackClient(time.Second).Post(srv.URL, "application/json", nil)
+	if err == nil {
+		t.Fatal("expected loopback callback to be refused")
+	}
+	if called {
+		t.Error("loopback callback should not be delivered")
+	}
+}
@@ -0,0 +1,233 @@
+package subscribing
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/webhook"
+)
+
+// Headers sent along with each payload. The signature is the hex encoded
+// HMAC-SHA256 of the request body, keyed with the subscription secret.
+const (
+	EventHeader     = "X-Goddd-Event"
+	DeliveryHeader  = "X-Goddd-Delivery"
+	SignatureHeader = "X-Goddd-Signature"
+)
+
+// Deliverer posts status changes to the subscribers of a cargo. It is
+// notified by the inspection service, and retries failed deliveries in the
+// background until they succeed or are dead-lettered.
+type Deliverer struct {
+	subscriptions webhook.SubscriptionRepository
+	deliveries    webhook.DeliveryRepository
+	client        *http.Client
+	backoff       webhook.Backoff
+	logger        log.Logger
+	wake          chan struct{}
+}
+
+// NewDeliverer returns a new deliverer.
+func NewDeliverer(subscriptions webhook.SubscriptionRepository, deliveries webhook.DeliveryRepository, client *http.Client, backoff webhook.Backoff, logger log.Logger) *Deliverer {
+	return &Deliverer{
+		subscriptions: subscriptions,
+		deliveries:    deliveries,
+		client:        client,
+		backoff:       backoff,
+		logger:        logger,
+		wake:          make(chan struct{}, 1),
+	}
+}
+
+// CargoWasMisdirected is covered by DeliveryWasUpdated.
+func (d *Deliverer) CargoWasMisdirected(c *cargo.Cargo) {}
+
+// CargoHasArrived is covered by DeliveryWasUpdated.
+func (d *Deliverer) CargoHasArrived(c *cargo.Cargo) {}
+
+// DeliveryWasUpdated queues a status change for every subscription that
+// covers the cargo.
+func (d *Deliverer) DeliveryWasUpdated(c *cargo.Cargo) {
+	now := time.Now()
+
+	full, err := json.Marshal(assembleStatusChange(c, now))
+	if err != nil {
+		d.logger.Log("tracking_id", c.TrackingID, "err", err)
+		return
+	}
+
+	public, err := json.Marshal(assemblePublicStatusChange(c, now))
+	if err != nil {
+		d.logger.Log("tracking_id", c.TrackingID, "err", err)
+		return
+	}
+
+	var queued bool
+	for _, sub := range d.subscriptions.FindAll() {
+		if !sub.Matches(c) {
+			continue
+		}
+
+		payload := full
+		if sub.Public {
+			payload = public
+		}
+
+		dl := webhook.NewDelivery(webhook.NextDeliveryID(), sub.ID, c.TrackingID, payload)
+		if err := d.deliveries.Store(dl); err != nil {
+			d.logger.Log("tracking_id", c.TrackingID, "subscription_id", sub.ID, "err", err)
+			continue
+		}
+		queued = true
+	}
+
+	if queued {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run delivers due payloads whenever new ones are queued, and otherwise at
+// every interval, until done is closed.
+func (d *Deliverer) Run(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-d.wake:
+		case <-done:
+			return
+		}
+		d.DeliverDue(time.Now())
+	}
+}
+
+// DeliverDue makes an attempt at every pending delivery that is due.
+func (d *Deliverer) DeliverDue(now time.Time) {
+	for _, dl := range d.deliveries.FindDue(now) {
+		sub, err := d.subscriptions.Find(dl.SubscriptionID)
+		if err != nil {
+			dl.Attempts = append(dl.Attempts, webhook.Attempt{Time: now, Error: err.Error()})
+			dl.State = webhook.DeadLettered
+		} else {
+			dl.Record(d.post(sub, dl), d.backoff)
+		}
+
+		if err := d.deliveries.Store(dl); err != nil {
+			d.logger.Log("delivery_id", dl.ID, "err", err)
+			continue
+		}
+
+		if dl.State == webhook.DeadLettered {
+			d.logger.Log("delivery_id", dl.ID, "subscription_id", dl.SubscriptionID, "msg", "dead lettered")
+		}
+	}
+}
+
+func (d *Deliverer) post(sub *webhook.Subscription, dl *webhook.Delivery) webhook.Attempt {
+	a := webhook.Attempt{Time: time.Now()}
+
+	req, err := http.NewRequest("POST", sub.URL, bytes.NewReader(dl.Payload))
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set(EventHeader, statusChangeEvent)
+	req.Header.Set(DeliveryHeader, string(dl.ID))
+	req.Header.Set(SignatureHeader, "sha256="+sub.Sign(dl.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	defer resp.Body.Close()
+
+	// Drain the body so that the connection can be reused.
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	a.StatusCode = resp.StatusCode
+
+	return a
+}
+
+const statusChangeEvent = "cargo.delivery_updated"
+
+// statusChange is the payload posted to subscribers.
+type statusChange struct {
+	Event                 string    `json:"event"`
+	TrackingID            string    `json:"tracking_id"`
+	RoutingStatus         string    `json:"routing_status"`
+	TransportStatus       string    `json:"transport_status"`
+	LastKnownLocation     string    `json:"last_known_location,omitempty"`
+	CurrentVoyage         string    `json:"current_voyage,omitempty"`
+	NextExpectedActivity  *activity `json:"next_expected_activity,omitempty"`
+	ETA                   time.Time `json:"eta"`
+	Misdirected           bool      `json:"misdirected"`
+	UnloadedAtDestination bool      `json:"unloaded_at_destination"`
+	OccurredAt            time.Time `json:"occurred_at"`
+}
+
+type activity struct {
+	Type         string `json:"type"`
+	Location     string `json:"location"`
+	VoyageNumber string `json:"voyage_number,omitempty"`
+}
+
+func assembleStatusChange(c *cargo.Cargo, now time.Time) statusChange {
+	d := c.Delivery
+
+	sc := statusChange{
+		Event:                 statusChangeEvent,
+		TrackingID:            string(c.TrackingID),
+		RoutingStatus:         d.RoutingStatus.String(),
+		TransportStatus:       d.TransportStatus.String(),
+		LastKnownLocation:     string(d.LastKnownLocation),
+		CurrentVoyage:         string(d.CurrentVoyage),
+		ETA:                   d.ETA,
+		Misdirected:           d.IsMisdirected,
+		UnloadedAtDestination: d.IsUnloadedAtDestination,
+		OccurredAt:            now,
+	}
+
+	if a := d.NextExpectedActivity; a.Type != cargo.NotHandled {
+		sc.NextExpectedActivity = &activity{
+			Type:         a.Type.String(),
+			Location:     string(a.Location),
+			VoyageNumber: string(a.VoyageNumber),
+		}
+	}
+
+	return sc
+}
+
+// publicStatusChange is the payload posted to public subscribers, limited to
+// what the public view of the cargo shows.
+type publicStatusChange struct {
+	Event      string    `json:"event"`
+	TrackingID string    `json:"tracking_id"`
+	ETA        time.Time `json:"eta"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+func assemblePublicStatusChange(c *cargo.Cargo, now time.Time) publicStatusChange {
+	return publicStatusChange{
+		Event:      statusChangeEvent,
+		TrackingID: string(c.TrackingID),
+		ETA:        c.Delivery.ETA,
+		OccurredAt: now,
+	}
+}
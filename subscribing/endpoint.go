@@ -0,0 +1,125 @@
+package subscribing
+
+import (
+	"github.com/go-kit/kit/endpoint"
+	"golang.org/x/net/context"
+
+	"github.com/marcusolsson/goddd/access"
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/webhook"
+)
+
+type subscribeRequest struct {
+	TrackingID cargo.TrackingID
+	CustomerID customer.ID
+	URL        string
+	Token      access.Token
+}
+
+type subscribeResponse struct {
+	Subscription *Subscription `json:"subscription,omitempty"`
+	Err          error         `json:"error,omitempty"`
+}
+
+func (r subscribeResponse) error() error { return r.Err }
+
+func makeSubscribeEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(subscribeRequest)
+		sub, err := s.Subscribe(req.TrackingID, req.CustomerID, req.URL, req.Token)
+		if err != nil {
+			return subscribeResponse{Err: err}, nil
+		}
+		return subscribeResponse{Subscription: &sub}, nil
+	}
+}
+
+type unsubscribeRequest struct {
+	ID    webhook.SubscriptionID
+	Token access.Token
+}
+
+type unsubscribeResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r unsubscribeResponse) error() error { return r.Err }
+
+func makeUnsubscribeEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(unsubscribeRequest)
+		err := s.Unsubscribe(req.ID, req.Token)
+		return unsubscribeResponse{Err: err}, nil
+	}
+}
+
+type listSubscriptionsRequest struct{}
+
+type listSubscriptionsResponse struct {
+	Subscriptions []Subscription `json:"subscriptions,omitempty"`
+	Err           error          `json:"error,omitempty"`
+}
+
+func (r listSubscriptionsResponse) error() error { return r.Err }
+
+func makeListSubscriptionsEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		_ = request.(listSubscriptionsRequest)
+		return listSubscriptionsResponse{Subscriptions: s.Subscriptions()}, nil
+	}
+}
+
+type listDeliveriesRequest struct {
+	ID    webhook.SubscriptionID
+	Token access.Token
+}
+
+type listDeliveriesResponse struct {
+	Deliveries []Delivery `json:"deliveries,omitempty"`
+	Err        error      `json:"error,omitempty"`
+}
+
+func (r listDeliveriesResponse) error() error { return r.Err }
+
+func makeListDeliveriesEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(listDeliveriesRequest)
+		deliveries, err := s.Deliveries(req.ID, req.Token)
+		return listDeliveriesResponse{Deliveries: deliveries, Err: err}, nil
+	}
+}
+
+type listDeadLettersRequest struct{}
+
+type listDeadLettersResponse struct {
+	Deliveries []Delivery `json:"deliveries,omitempty"`
+	Err        error      `json:"error,omitempty"`
+}
+
+func (r listDeadLettersResponse) error() error { return r.Err }
+
+func makeListDeadLettersEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		_ = request.(listDeadLettersRequest)
+		return listDeadLettersResponse{Deliveries: s.DeadLetters()}, nil
+	}
+}
+
+type redeliverRequest struct {
+	ID webhook.DeliveryID
+}
+
+type redeliverResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r redeliverResponse) error() error { return r.Err }
+
+func makeRedeliverEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(redeliverRequest)
+		err := s.Redeliver(req.ID)
+		return redeliverResponse{Err: err}, nil
+	}
+}
@@ -0,0 +1,87 @@
+package subscribing
+
+import (
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+
+	"github.com/marcusolsson/goddd/access"
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/webhook"
+)
+
+type instrumentingService struct {
+	requestCount   metrics.Counter
+	requestLatency metrics.TimeHistogram
+	Service
+}
+
+// NewInstrumentingService returns an instance of an instrumenting Service.
+func NewInstrumentingService(counter metrics.Counter, latency metrics.TimeHistogram, s Service) Service {
+	return &instrumentingService{
+		requestCount:   counter,
+		requestLatency: latency,
+		Service:        s,
+	}
+}
+
+func (s *instrumentingService) Subscribe(trackingID cargo.TrackingID, customerID customer.ID, callbackURL string, token access.Token) (Subscription, error) {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "subscribe"}
+		s.requestCount.With(methodField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.Subscribe(trackingID, customerID, callbackURL, token)
+}
+
+func (s *instrumentingService) Unsubscribe(id webhook.SubscriptionID, token access.Token) error {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "unsubscribe"}
+		s.requestCount.With(methodField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.Unsubscribe(id, token)
+}
+
+func (s *instrumentingService) Subscriptions() []Subscription {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "list_subscriptions"}
+		s.requestCount.With(methodField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.Subscriptions()
+}
+
+func (s *instrumentingService) Deliveries(id webhook.SubscriptionID, token access.Token) ([]Delivery, error) {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "list_deliveries"}
+		s.requestCount.With(methodField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.Deliveries(id, token)
+}
+
+func (s *instrumentingService) DeadLetters() []Delivery {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "list_dead_letters"}
+		s.requestCount.With(methodField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.DeadLetters()
+}
+
+func (s *instrumentingService) Redeliver(id webhook.DeliveryID) error {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "redeliver"}
+		s.requestCount.With(methodField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.Redeliver(id)
+}
@@ -0,0 +1,94 @@
+package subscribing
+
+import (
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/marcusolsson/goddd/access"
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/webhook"
+)
+
+type loggingService struct {
+	logger log.Logger
+	Service
+}
+
+// NewLoggingService returns a new instance of a logging Service.
+func NewLoggingService(logger log.Logger, s Service) Service {
+	return &loggingService{logger, s}
+}
+
+func (s *loggingService) Subscribe(trackingID cargo.TrackingID, customerID customer.ID, callbackURL string, token access.Token) (sub Subscription, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "subscribe",
+			"tracking_id", trackingID,
+			"customer_id", customerID,
+			"url", callbackURL,
+			"subscription_id", sub.ID,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.Subscribe(trackingID, customerID, callbackURL, token)
+}
+
+func (s *loggingService) Unsubscribe(id webhook.SubscriptionID, token access.Token) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "unsubscribe",
+			"subscription_id", id,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.Unsubscribe(id, token)
+}
+
+func (s *loggingService) Subscriptions() []Subscription {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "list_subscriptions",
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+	return s.Service.Subscriptions()
+}
+
+func (s *loggingService) Deliveries(id webhook.SubscriptionID, token access.Token) (deliveries []Delivery, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "list_deliveries",
+			"subscription_id", id,
+			"took", time.Since(begin),
+			"count", len(deliveries),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.Deliveries(id, token)
+}
+
+func (s *loggingService) DeadLetters() []Delivery {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "list_dead_letters",
+			"took", time.Since(begin),
+		)
+	}(time.Now())
+	return s.Service.DeadLetters()
+}
+
+func (s *loggingService) Redeliver(id webhook.DeliveryID) (err error) {
+	defer func(begin time.Time) {
+		s.logger.Log(
+			"method", "redeliver",
+			"delivery_id", id,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+	return s.Service.Redeliver(id)
+}
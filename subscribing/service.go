@@ -0,0 +1,350 @@
+// Package subscribing provides the use-case of subscribing to changes in the
+// delivery of cargos. Subscribers register a callback URL, which is sent a
+// signed payload whenever the status of a cargo changes.
+package subscribing
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/marcusolsson/goddd/access"
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/webhook"
+)
+
+// ErrInvalidArgument is returned when one or more arguments are invalid.
+var ErrInvalidArgument = errors.New("invalid argument")
+
+// ErrUnauthorized is returned when the access token is missing, has expired
+// or been revoked, or does not give access to the cargo or customer.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Service is the interface that provides subscription methods.
+type Service interface {
+	// Subscribe registers a callback URL for either a single cargo or every
+	// cargo of a customer, on behalf of the bearer of an access token as in
+	// tracking. A share token only subscribes to the public view of its
+	// cargo, while every cargo of a customer takes a session of the
+	// customer. The returned subscription holds the secret used to sign the
+	// payloads; it is not shown again.
+	Subscribe(trackingID cargo.TrackingID, customerID customer.ID, callbackURL string, token access.Token) (Subscription, error)
+
+	// Unsubscribe removes a subscription, on behalf of the bearer of an
+	// access token that could have made it. Deliveries still pending for it
+	// end up among the dead letters.
+	Unsubscribe(id webhook.SubscriptionID, token access.Token) error
+
+	// Subscriptions returns all registered subscriptions. Meant for
+	// operators only.
+	Subscriptions() []Subscription
+
+	// Deliveries returns the log of deliveries made to a subscription, on
+	// behalf of the bearer of an access token that could have made it.
+	Deliveries(id webhook.SubscriptionID, token access.Token) ([]Delivery, error)
+
+	// DeadLetters returns the deliveries that have been given up on. Meant
+	// for operators only.
+	DeadLetters() []Delivery
+
+	// Redeliver gives a dead-lettered delivery a new set of attempts. Meant
+	// for operators only.
+	Redeliver(id webhook.DeliveryID) error
+}
+
+type service struct {
+	cargos        cargo.Repository
+	customers     customer.Repository
+	subscriptions webhook.SubscriptionRepository
+	deliveries    webhook.DeliveryRepository
+	grants        access.Repository
+
+	lookupIP func(host string) ([]net.IP, error)
+}
+
+func (s *service) Subscribe(trackingID cargo.TrackingID, customerID customer.ID, callbackURL string, token access.Token) (Subscription, error) {
+	if (trackingID == "") == (customerID == "") {
+		return Subscription{}, ErrInvalidArgument
+	}
+
+	u, err := url.Parse(callbackURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Subscription{}, ErrInvalidArgument
+	}
+	if _, err := checkHost(hostname(u), s.lookupIP); err != nil {
+		return Subscription{}, err
+	}
+
+	g, err := s.grant(token)
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	var public bool
+
+	if trackingID != "" {
+		// Share tokens are checked before the lookup, so that they cannot
+		// be used to find out what other cargos exist.
+		if g.TrackingID != "" && g.TrackingID != trackingID {
+			return Subscription{}, ErrUnauthorized
+		}
+
+		c, err := s.cargos.Find(trackingID)
+		if err != nil {
+			return Subscription{}, err
+		}
+
+		switch g.ViewOf(c, time.Now()) {
+		case access.FullView:
+		case access.PublicView:
+			public = true
+		default:
+			return Subscription{}, ErrUnauthorized
+		}
+	}
+
+	if customerID != "" {
+		if g.Customer != customerID {
+			return Subscription{}, ErrUnauthorized
+		}
+		if _, err := s.customers.Find(customerID); err != nil {
+			return Subscription{}, err
+		}
+	}
+
+	sub := webhook.NewSubscription(webhook.NextSubscriptionID(), trackingID, customerID, u.String())
+	sub.Public = public
+
+	if err := s.subscriptions.Store(sub); err != nil {
+		return Subscription{}, err
+	}
+
+	res := assembleSubscription(sub)
+	res.Secret = sub.Secret
+
+	return res, nil
+}
+
+func (s *service) Unsubscribe(id webhook.SubscriptionID, token access.Token) error {
+	if id == "" {
+		return ErrInvalidArgument
+	}
+
+	if _, err := s.subscription(id, token); err != nil {
+		return err
+	}
+
+	return s.subscriptions.Remove(id)
+}
+
+func (s *service) Subscriptions() []Subscription {
+	subs := s.subscriptions.FindAll()
+
+	sort.Stable(byCreated(subs))
+
+	res := make([]Subscription, 0, len(subs))
+	for _, sub := range subs {
+		res = append(res, assembleSubscription(sub))
+	}
+	return res
+}
+
+func (s *service) Deliveries(id webhook.SubscriptionID, token access.Token) ([]Delivery, error) {
+	if id == "" {
+		return nil, ErrInvalidArgument
+	}
+
+	if _, err := s.subscription(id, token); err != nil {
+		return nil, err
+	}
+
+	return assembleDeliveries(s.deliveries.FindBySubscription(id)), nil
+}
+
+func (s *service) DeadLetters() []Delivery {
+	return assembleDeliveries(s.deliveries.FindByState(webhook.DeadLettered))
+}
+
+func (s *service) Redeliver(id webhook.DeliveryID) error {
+	if id == "" {
+		return ErrInvalidArgument
+	}
+
+	d, err := s.deliveries.Find(id)
+	if err != nil {
+		return err
+	}
+
+	if d.State != webhook.DeadLettered {
+		return ErrInvalidArgument
+	}
+
+	if _, err := s.subscriptions.Find(d.SubscriptionID); err != nil {
+		return err
+	}
+
+	d.Redeliver()
+
+	return s.deliveries.Store(d)
+}
+
+// subscription returns a subscription, as long as the access token would
+// have allowed making it. Public subscriptions may be managed with the public
+// view of their cargo, while others take the same grant as when made, since
+// their payloads show the full view.
+func (s *service) subscription(id webhook.SubscriptionID, token access.Token) (*webhook.Subscription, error) {
+	g, err := s.grant(token)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := s.subscriptions.Find(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if sub.Customer != "" {
+		if g.Customer != sub.Customer {
+			return nil, ErrUnauthorized
+		}
+		return sub, nil
+	}
+
+	if g.TrackingID != "" && g.TrackingID != sub.TrackingID {
+		return nil, ErrUnauthorized
+	}
+
+	c, err := s.cargos.Find(sub.TrackingID)
+	if err == cargo.ErrUnknown {
+		return nil, ErrUnauthorized
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch g.ViewOf(c, time.Now()) {
+	case access.FullView:
+		return sub, nil
+	case access.PublicView:
+		if sub.Public {
+			return sub, nil
+		}
+	}
+	return nil, ErrUnauthorized
+}
+
+// grant returns the grant of an access token, as long as it is still active.
+func (s *service) grant(token access.Token) (*access.Grant, error) {
+	if token == "" {
+		return nil, ErrUnauthorized
+	}
+	g, err := s.grants.Find(token)
+	if err == access.ErrUnknown {
+		return nil, ErrUnauthorized
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !g.Active(time.Now()) {
+		return nil, ErrUnauthorized
+	}
+	return g, nil
+}
+
+// NewService creates a subscribing service with necessary dependencies.
+func NewService(cargos cargo.Repository, customers customer.Repository, subscriptions webhook.SubscriptionRepository, deliveries webhook.DeliveryRepository, grants access.Repository) Service {
+	return &service{
+		cargos:        cargos,
+		customers:     customers,
+		subscriptions: subscriptions,
+		deliveries:    deliveries,
+		grants:        grants,
+		lookupIP:      lookupIP,
+	}
+}
+
+// Subscription is a read model for subscription views.
+type Subscription struct {
+	ID         string    `json:"id"`
+	TrackingID string    `json:"tracking_id,omitempty"`
+	CustomerID string    `json:"customer_id,omitempty"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	Created    time.Time `json:"created"`
+	Public     bool      `json:"public"`
+}
+
+// Delivery is a read model for the delivery log.
+type Delivery struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscription_id"`
+	TrackingID     string          `json:"tracking_id"`
+	State          string          `json:"state"`
+	Attempts       []Attempt       `json:"attempts"`
+	NextAttempt    *time.Time      `json:"next_attempt,omitempty"`
+	Created        time.Time       `json:"created"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+// Attempt is a read model for a single try to deliver a payload.
+type Attempt struct {
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+func assembleSubscription(s *webhook.Subscription) Subscription {
+	return Subscription{
+		ID:         string(s.ID),
+		TrackingID: string(s.TrackingID),
+		CustomerID: string(s.Customer),
+		URL:        s.URL,
+		Created:    s.Created,
+		Public:     s.Public,
+	}
+}
+
+func assembleDeliveries(ds []*webhook.Delivery) []Delivery {
+	res := make([]Delivery, 0, len(ds))
+	for _, d := range ds {
+		res = append(res, assembleDelivery(d))
+	}
+	return res
+}
+
+func assembleDelivery(d *webhook.Delivery) Delivery {
+	res := Delivery{
+		ID:             string(d.ID),
+		SubscriptionID: string(d.SubscriptionID),
+		TrackingID:     string(d.TrackingID),
+		State:          d.State.String(),
+		Attempts:       make([]Attempt, 0, len(d.Attempts)),
+		Created:        d.Created,
+		Payload:        json.RawMessage(d.Payload),
+	}
+
+	if d.State == webhook.Pending {
+		next := d.NextAttempt
+		res.NextAttempt = &next
+	}
+
+	for _, a := range d.Attempts {
+		res.Attempts = append(res.Attempts, Attempt{
+			Time:       a.Time,
+			StatusCode: a.StatusCode,
+			Error:      a.Error,
+		})
+	}
+
+	return res
+}
+
+type byCreated []*webhook.Subscription
+
+func (s byCreated) Len() int           { return len(s) }
+func (s byCreated) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byCreated) Less(i, j int) bool { return s[i].Created.Before(s[j].Created) }
@@ -0,0 +1,349 @@
+package subscribing
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/marcusolsson/goddd/access"
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/inmem"
+	"github.com/marcusolsson/goddd/location"
+	"github.com/marcusolsson/goddd/webhook"
+)
+
+func TestSubscribe(t *testing.T) {
+	cargos := inmem.NewCargoRepository()
+	customers := inmem.NewCustomerRepository()
+	grants := inmem.NewGrantRepository()
+
+	c := cargo.New("ABC123", cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.AUMEL,
+	})
+	c.AttachParty(cargo.Shipper, "C1")
+	cargos.Store(c)
+	cargos.Store(cargo.New("DEF456", cargo.RouteSpecification{}))
+	customers.Store(customer.New("C1", "Acme", "", ""))
+
+	var (
+		share   = access.NewShareToken("ABC123", time.Now())
+		session = access.NewSession("C1", time.Now())
+		other   = access.NewSession("C2", time.Now())
+	)
+	for _, g := range []*access.Grant{share, session, other} {
+		grants.Store(g)
+	}
+
+	s := NewService(cargos, customers, inmem.NewSubscriptionRepository(), inmem.NewDeliveryRepository(), grants)
+	s.(*service).lookupIP = func(host string) ([]net.IP, error) {
+		switch host {
+		case "example.com":
+			return []net.IP{net.ParseIP("93.184.216.34")}, nil
+		case "internal.example.com":
+			return []net.IP{net.ParseIP("93.184.216.34"), net.ParseIP("10.1.2.3")}, nil
+		case "localhost":
+			return []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")}, nil
+		}
+		return lookupIP(host)
+	}
+
+	tests := []struct {
+		trackingID cargo.TrackingID
+		customerID customer.ID
+		url        string
+		token      access.Token
+		err        error
+		public     bool
+	}{
+		{"ABC123", "", "https://example.com/hook", share.Token, nil, true},
+		{"ABC123", "", "https://example.com/hook", session.Token, nil, false},
+		{"", "C1", "http://example.com/hook", session.Token, nil, false},
+		{"ABC123", "C1", "https://example.com/hook", session.Token, ErrInvalidArgument, false},
+		{"", "", "https://example.com/hook", session.Token, ErrInvalidArgument, false},
+		{"ABC123", "", "ftp://example.com/hook", session.Token, ErrInvalidArgument, false},
+		{"ABC123", "", "/hook", session.Token, ErrInvalidArgument, false},
+		{"ABC123", "", "http://localhost:8080/hook", session.Token, ErrForbiddenHost, false},
+		{"ABC123", "", "http://127.0.0.1/hook", session.Token, ErrForbiddenHost, false},
+		{"ABC123", "", "http://[::1]:8080/hook", session.Token, ErrForbiddenHost, false},
+		{"ABC123", "", "http://169.254.169.254/latest/meta-data", session.Token, ErrForbiddenHost, false},
+		{"ABC123", "", "https://10.0.0.1/hook", session.Token, ErrForbiddenHost, false},
+		{"ABC123", "", "https://192.168.1.1/hook", session.Token, ErrForbiddenHost, false},
+		{"ABC123", "", "https://internal.example.com/hook", session.Token, ErrForbiddenHost, false},
+		{"ABC123", "", "https://example.com/hook", "", ErrUnauthorized, false},
+		{"ABC123", "", "https://example.com/hook", "unknown", ErrUnauthorized, false},
+		{"ABC123", "", "https://example.com/hook", other.Token, ErrUnauthorized, false},
+		{"DEF456", "", "https://example.com/hook", share.Token, ErrUnauthorized, false},
+		{"DEF456", "", "https://example.com/hook", session.Token, ErrUnauthorized, false},
+		{"XYZ789", "", "https://example.com/hook", session.Token, cargo.ErrUnknown, false},
+		{"", "C1", "https://example.com/hook", share.Token, ErrUnauthorized, false},
+		{"", "C2", "https://example.com/hook", other.Token, customer.ErrUnknown, false},
+	}
+
+	for _, tt := range tests {
+		sub, err := s.Subscribe(tt.trackingID, tt.customerID, tt.url, tt.token)
+		if err != tt.err {
+			t.Errorf("Subscribe(%q, %q, %q, %q) err = %v; want = %v", tt.trackingID, tt.customerID, tt.url, tt.token, err, tt.err)
+			continue
+		}
+		if err == nil && sub.Secret == "" {
+			t.Errorf("secret should be returned when subscribing")
+		}
+		if err == nil && sub.Public != tt.public {
+			t.Errorf("Subscribe(%q, %q, %q, %q) Public = %v; want = %v", tt.trackingID, tt.customerID, tt.url, tt.token, sub.Public, tt.public)
+		}
+	}
+
+	subs := s.Subscriptions()
+	if len(subs) != 3 {
+		t.Fatalf("len(subs) = %d; want = %d", len(subs), 3)
+	}
+	for _, sub := range subs {
+		if sub.Secret != "" {
+			t.Errorf("secret should not be listed")
+		}
+	}
+}
+
+func TestSubscriptionAccess(t *testing.T) {
+	cargos := inmem.NewCargoRepository()
+	subscriptions := inmem.NewSubscriptionRepository()
+	grants := inmem.NewGrantRepository()
+
+	c := cargo.New("ABC123", cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.AUMEL,
+	})
+	c.AttachParty(cargo.Shipper, "C1")
+	cargos.Store(c)
+
+	public := webhook.NewSubscription("S1", "ABC123", "", "https://example.com/hook")
+	public.Public = true
+	subscriptions.Store(public)
+	subscriptions.Store(webhook.NewSubscription("S2", "ABC123", "", "https://example.com/hook"))
+	subscriptions.Store(webhook.NewSubscription("S3", "", "C1", "https://example.com/hook"))
+
+	var (
+		share   = access.NewShareToken("ABC123", time.Now())
+		session = access.NewSession("C1", time.Now())
+		other   = access.NewSession("C2", time.Now())
+	)
+	for _, g := range []*access.Grant{share, session, other} {
+		grants.Store(g)
+	}
+
+	s := NewService(cargos, inmem.NewCustomerRepository(), subscriptions, inmem.NewDeliveryRepository(), grants)
+
+	tests := []struct {
+		id    webhook.SubscriptionID
+		token access.Token
+		err   error
+	}{
+		{"S1", share.Token, nil},
+		{"S1", session.Token, nil},
+		{"S1", other.Token, ErrUnauthorized},
+		{"S1", "", ErrUnauthorized},
+		{"S2", share.Token, ErrUnauthorized},
+		{"S2", session.Token, nil},
+		{"S3", session.Token, nil},
+		{"S3", share.Token, ErrUnauthorized},
+		{"S3", other.Token, ErrUnauthorized},
+		{"S4", session.Token, webhook.ErrUnknownSubscription},
+	}
+
+	for _, tt := range tests {
+		if _, err := s.Deliveries(tt.id, tt.token); err != tt.err {
+			t.Errorf("Deliveries(%q, %q) err = %v; want = %v", tt.id, tt.token, err, tt.err)
+		}
+	}
+
+	if err := s.Unsubscribe("S2", share.Token); err != ErrUnauthorized {
+		t.Errorf("err = %v; want = %v", err, ErrUnauthorized)
+	}
+	if err := s.Unsubscribe("S2", session.Token); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Subscriptions()) != 2 {
+		t.Errorf("len(s.Subscriptions()) = %d; want = %d", len(s.Subscriptions()), 2)
+	}
+}
+
+func TestDeliverer(t *testing.T) {
+	var (
+		status   = http.StatusServiceUnavailable
+		received [][]byte
+		verified bool
+	)
+
+	subscriptions := inmem.NewSubscriptionRepository()
+	deliveries := inmem.NewDeliveryRepository()
+
+	sub := webhook.NewSubscription("S1", "", "C1", "")
+	other := webhook.NewSubscription("S2", "XYZ789", "", "")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received = append(received, body)
+		verified = r.Header.Get(SignatureHeader) == "sha256="+sub.Sign(body)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sub.URL = srv.URL
+	other.URL = srv.URL
+	subscriptions.Store(sub)
+	subscriptions.Store(other)
+
+	backoff := webhook.Backoff{Initial: time.Minute, Max: time.Hour, MaxAttempts: 2}
+	d := NewDeliverer(subscriptions, deliveries, http.DefaultClient, backoff, log.NewNopLogger())
+
+	c := cargo.New("ABC123", cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.AUMEL,
+	})
+	c.AttachParty(cargo.Shipper, "C1")
+	c.Delivery.TransportStatus = cargo.InPort
+	c.Delivery.LastKnownLocation = location.SESTO
+
+	d.DeliveryWasUpdated(c)
+
+	now := time.Now()
+
+	d.DeliverDue(now)
+
+	if len(received) != 1 {
+		t.Fatalf("len(received) = %d; want = %d", len(received), 1)
+	}
+	if !verified {
+		t.Errorf("signature could not be verified")
+	}
+
+	dls := deliveries.FindBySubscription("S1")
+	if len(dls) != 1 || dls[0].State != webhook.Pending {
+		t.Fatalf("delivery should be pending after a failed attempt")
+	}
+
+	// Not due until the backoff has passed.
+	d.DeliverDue(now)
+	if len(received) != 1 {
+		t.Errorf("len(received) = %d; want = %d", len(received), 1)
+	}
+
+	d.DeliverDue(now.Add(2 * time.Minute))
+	if got := deliveries.FindByState(webhook.DeadLettered); len(got) != 1 {
+		t.Fatalf("len(dead letters) = %d; want = %d", len(got), 1)
+	}
+
+	grants := inmem.NewGrantRepository()
+	session := access.NewSession("C1", time.Now())
+	grants.Store(session)
+
+	s := NewService(inmem.NewCargoRepository(), inmem.NewCustomerRepository(), subscriptions, deliveries, grants)
+
+	status = http.StatusNoContent
+
+	if err := s.Redeliver(dls[0].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	d.DeliverDue(time.Now())
+
+	entries, err := s.Deliveries("S1", session.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].State != webhook.Delivered.String() {
+		t.Errorf("delivery should have been delivered after redelivery")
+	}
+	if len(s.DeadLetters()) != 0 {
+		t.Errorf("len(s.DeadLetters()) = %d; want = %d", len(s.DeadLetters()), 0)
+	}
+}
+
+func TestDelivererUnsubscribed(t *testing.T) {
+	subscriptions := inmem.NewSubscriptionRepository()
+	deliveries := inmem.NewDeliveryRepository()
+
+	subscriptions.Store(webhook.NewSubscription("S1", "ABC123", "", "http://localhost"))
+
+	d := NewDeliverer(subscriptions, deliveries, http.DefaultClient, webhook.DefaultBackoff, log.NewNopLogger())
+
+	c := cargo.New("ABC123", cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.AUMEL,
+	})
+
+	c.AttachParty(cargo.Shipper, "C1")
+
+	d.DeliveryWasUpdated(c)
+
+	cargos := inmem.NewCargoRepository()
+	cargos.Store(c)
+
+	grants := inmem.NewGrantRepository()
+	session := access.NewSession("C1", time.Now())
+	grants.Store(session)
+
+	s := NewService(cargos, inmem.NewCustomerRepository(), subscriptions, deliveries, grants)
+
+	if err := s.Unsubscribe("S1", session.Token); err != nil {
+		t.Fatal(err)
+	}
+
+	d.DeliverDue(time.Now())
+
+	dead := s.DeadLetters()
+	if len(dead) != 1 {
+		t.Fatalf("len(dead) = %d; want = %d", len(dead), 1)
+	}
+	if err := s.Redeliver(webhook.DeliveryID(dead[0].ID)); err != webhook.ErrUnknownSubscription {
+		t.Errorf("err = %v; want = %v", err, webhook.ErrUnknownSubscription)
+	}
+}
+
+func TestDelivererPublic(t *testing.T) {
+	subscriptions := inmem.NewSubscriptionRepository()
+	deliveries := inmem.NewDeliveryRepository()
+
+	sub := webhook.NewSubscription("S1", "ABC123", "", "http://example.com/hook")
+	sub.Public = true
+	subscriptions.Store(sub)
+
+	d := NewDeliverer(subscriptions, deliveries, http.DefaultClient, webhook.DefaultBackoff, log.NewNopLogger())
+
+	c := cargo.New("ABC123", cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.AUMEL,
+	})
+	c.Delivery.TransportStatus = cargo.OnboardCarrier
+	c.Delivery.LastKnownLocation = location.SESTO
+	c.Delivery.CurrentVoyage = "V100"
+
+	d.DeliveryWasUpdated(c)
+
+	dls := deliveries.FindBySubscription("S1")
+	if len(dls) != 1 {
+		t.Fatalf("len(dls) = %d; want = %d", len(dls), 1)
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(dls[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, k := range []string{"last_known_location", "current_voyage", "transport_status", "next_expected_activity"} {
+		if _, ok := payload[k]; ok {
+			t.Errorf("public payload should not include %s", k)
+		}
+	}
+	if payload["tracking_id"] != "ABC123" {
+		t.Errorf("tracking_id = %v; want = ABC123", payload["tracking_id"])
+	}
+}
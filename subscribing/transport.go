@@ -0,0 +1,198 @@
+package subscribing
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	kitlog "github.com/go-kit/kit/log"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"golang.org/x/net/context"
+
+	"github.com/marcusolsson/goddd/access"
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/webhook"
+)
+
+// MakeHandler returns a handler for the subscribing service.
+func MakeHandler(ctx context.Context, ss Service, logger kitlog.Logger) http.Handler {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorLogger(logger),
+		kithttp.ServerErrorEncoder(encodeError),
+	}
+
+	subscribeHandler := kithttp.NewServer(
+		ctx,
+		makeSubscribeEndpoint(ss),
+		decodeSubscribeRequest,
+		encodeResponse,
+		opts...,
+	)
+	unsubscribeHandler := kithttp.NewServer(
+		ctx,
+		makeUnsubscribeEndpoint(ss),
+		decodeUnsubscribeRequest,
+		encodeResponse,
+		opts...,
+	)
+	listDeliveriesHandler := kithttp.NewServer(
+		ctx,
+		makeListDeliveriesEndpoint(ss),
+		decodeListDeliveriesRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	r := mux.NewRouter()
+
+	r.Handle("/subscribing/v1/subscriptions", subscribeHandler).Methods("POST")
+	r.Handle("/subscribing/v1/subscriptions/{id}/unsubscribe", unsubscribeHandler).Methods("POST")
+	r.Handle("/subscribing/v1/subscriptions/{id}/deliveries", listDeliveriesHandler).Methods("GET")
+	r.Handle("/subscribing/v1/docs", http.StripPrefix("/subscribing/v1/docs", http.FileServer(http.Dir("subscribing/docs"))))
+
+	return r
+}
+
+// MakeOperatorHandler returns a handler for the parts of the subscribing
+// service spanning every subscription, such as the dead letters. It is meant
+// to be served apart from MakeHandler, where only operators can reach it.
+func MakeOperatorHandler(ctx context.Context, ss Service, logger kitlog.Logger) http.Handler {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorLogger(logger),
+		kithttp.ServerErrorEncoder(encodeError),
+	}
+
+	listSubscriptionsHandler := kithttp.NewServer(
+		ctx,
+		makeListSubscriptionsEndpoint(ss),
+		decodeListSubscriptionsRequest,
+		encodeResponse,
+		opts...,
+	)
+	listDeadLettersHandler := kithttp.NewServer(
+		ctx,
+		makeListDeadLettersEndpoint(ss),
+		decodeListDeadLettersRequest,
+		encodeResponse,
+		opts...,
+	)
+	redeliverHandler := kithttp.NewServer(
+		ctx,
+		makeRedeliverEndpoint(ss),
+		decodeRedeliverRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	r := mux.NewRouter()
+
+	r.Handle("/subscribing/v1/subscriptions", listSubscriptionsHandler).Methods("GET")
+	r.Handle("/subscribing/v1/dead_letters", listDeadLettersHandler).Methods("GET")
+	r.Handle("/subscribing/v1/dead_letters/{id}/redeliver", redeliverHandler).Methods("POST")
+
+	return r
+}
+
+var errBadRoute = errors.New("bad route")
+
+func decodeSubscribeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var body struct {
+		TrackingID string `json:"tracking_id"`
+		CustomerID string `json:"customer_id"`
+		URL        string `json:"url"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	return subscribeRequest{
+		TrackingID: cargo.TrackingID(body.TrackingID),
+		CustomerID: customer.ID(body.CustomerID),
+		URL:        body.URL,
+		Token:      accessToken(r),
+	}, nil
+}
+
+// accessToken returns the bearer token of the request.
+func accessToken(r *http.Request) access.Token {
+	const prefix = "Bearer "
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, prefix) {
+		return access.Token(strings.TrimSpace(h[len(prefix):]))
+	}
+	return ""
+}
+
+func decodeUnsubscribeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+	return unsubscribeRequest{ID: webhook.SubscriptionID(id), Token: accessToken(r)}, nil
+}
+
+func decodeListSubscriptionsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return listSubscriptionsRequest{}, nil
+}
+
+func decodeListDeliveriesRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+	return listDeliveriesRequest{ID: webhook.SubscriptionID(id), Token: accessToken(r)}, nil
+}
+
+func decodeListDeadLettersRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return listDeadLettersRequest{}, nil
+}
+
+func decodeRedeliverRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+	return redeliverRequest{ID: webhook.DeliveryID(id)}, nil
+}
+
+func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if e, ok := response.(errorer); ok && e.error() != nil {
+		encodeError(ctx, e.error(), w)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(w).Encode(response)
+}
+
+type errorer interface {
+	error() error
+}
+
+// encode errors from business-logic
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+	if e, ok := err.(kithttp.Error); ok && e.Domain == kithttp.DomainDecode {
+		err = e.Err
+	}
+
+	switch err {
+	case cargo.ErrUnknown, customer.ErrUnknown, webhook.ErrUnknownSubscription, webhook.ErrUnknownDelivery:
+		w.WriteHeader(http.StatusNotFound)
+	case ErrUnauthorized:
+		w.Header().Set("WWW-Authenticate", `Bearer realm="subscribing"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	case ErrInvalidArgument, ErrForbiddenHost:
+		w.WriteHeader(http.StatusBadRequest)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,151 @@
+// Package task provides the Task aggregate, i.e. the follow-up of a cargo
+// that needs the attention of a dispatcher.
+package task
+
+import (
+	"errors"
+	"time"
+
+	"github.com/marcusolsson/goddd/cargo"
+)
+
+// Reason describes why a cargo needs attention.
+type Reason string
+
+// Reasons for a cargo to need attention.
+const (
+	NotRouted    Reason = "not_routed"
+	Misrouted    Reason = "misrouted"
+	Misdirected  Reason = "misdirected"
+	DeadlineRisk Reason = "deadline_risk"
+)
+
+// Status of a task.
+type Status int
+
+// Valid task statuses.
+const (
+	Open Status = iota
+	Claimed
+	Resolved
+)
+
+func (s Status) String() string {
+	switch s {
+	case Open:
+		return "Open"
+	case Claimed:
+		return "Claimed"
+	case Resolved:
+		return "Resolved"
+	}
+	return ""
+}
+
+// Note is a remark left on a task by a dispatcher.
+type Note struct {
+	Author  string
+	Text    string
+	Created time.Time
+}
+
+// Task tracks the work of dispatchers on a single cargo.
+type Task struct {
+	TrackingID cargo.TrackingID
+	Status     Status
+	Assignee   string
+	Notes      []Note
+
+	// Resolution describes how the task was resolved, and ResolvedReasons
+	// holds the reasons the cargo needed attention at the time.
+	Resolution      string
+	ResolvedReasons []Reason
+	Resolved        time.Time
+}
+
+// New creates a new, open task.
+func New(id cargo.TrackingID) *Task {
+	return &Task{
+		TrackingID: id,
+		Status:     Open,
+	}
+}
+
+// Claim assigns the task to the dispatcher, unless it has already been
+// claimed by someone else.
+func (t *Task) Claim(dispatcher string) error {
+	if t.Status == Claimed && t.Assignee != dispatcher {
+		return ErrClaimed
+	}
+	t.Status = Claimed
+	t.Assignee = dispatcher
+	return nil
+}
+
+// Assign hands the task over to the dispatcher regardless of who holds it.
+// An empty dispatcher returns the task to the queue.
+func (t *Task) Assign(dispatcher string) {
+	if dispatcher == "" {
+		t.Status = Open
+		t.Assignee = ""
+		return
+	}
+	t.Status = Claimed
+	t.Assignee = dispatcher
+}
+
+// AddNote adds a note to the task.
+func (t *Task) AddNote(author, text string, created time.Time) {
+	t.Notes = append(t.Notes, Note{
+		Author:  author,
+		Text:    text,
+		Created: created,
+	})
+}
+
+// Resolve closes the task for the given reasons.
+func (t *Task) Resolve(dispatcher, resolution string, reasons []Reason, resolved time.Time) {
+	t.Status = Resolved
+	t.Assignee = dispatcher
+	t.Resolution = resolution
+	t.ResolvedReasons = append([]Reason(nil), reasons...)
+	t.Resolved = resolved
+}
+
+// Covers checks whether the task has been resolved for all of the given
+// reasons. A resolved task reappears in the queue once the cargo needs
+// attention for a new reason.
+func (t *Task) Covers(reasons []Reason) bool {
+	if t.Status != Resolved {
+		return false
+	}
+	for _, r := range reasons {
+		var found bool
+		for _, rr := range t.ResolvedReasons {
+			if r == rr {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// ErrUnknown is used when a task could not be found.
+var ErrUnknown = errors.New("unknown task")
+
+// ErrClaimed is used when a task has been claimed by another dispatcher.
+var ErrClaimed = errors.New("task has been claimed by another dispatcher")
+
+// Repository provides access a task store.
+type Repository interface {
+	Store(t *Task) error
+	Find(id cargo.TrackingID) (*Task, error)
+
+	// FindMany returns the tasks of any of the cargos. Cargos that have not
+	// been worked on are left out.
+	FindMany(ids []cargo.TrackingID) ([]*Task, error)
+}
@@ -0,0 +1,52 @@
+package task
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClaim(t *testing.T) {
+	tk := New("ABC")
+
+	if err := tk.Claim("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tk.Claim("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tk.Claim("bob"); err != ErrClaimed {
+		t.Errorf("err = %v; want = %v", err, ErrClaimed)
+	}
+
+	tk.Assign("bob")
+
+	if tk.Assignee != "bob" {
+		t.Errorf("tk.Assignee = %s; want = %s", tk.Assignee, "bob")
+	}
+
+	tk.Assign("")
+
+	if tk.Status != Open {
+		t.Errorf("tk.Status = %s; want = %s", tk.Status, Open)
+	}
+	if err := tk.Claim("alice"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCovers(t *testing.T) {
+	tk := New("ABC")
+
+	if tk.Covers([]Reason{Misrouted}) {
+		t.Errorf("open task should not cover any reasons")
+	}
+
+	tk.Resolve("alice", "customer agreed to late delivery", []Reason{Misrouted, DeadlineRisk}, time.Now())
+
+	if !tk.Covers([]Reason{DeadlineRisk}) {
+		t.Errorf("task should cover %s", DeadlineRisk)
+	}
+	if tk.Covers([]Reason{DeadlineRisk, Misdirected}) {
+		t.Errorf("task should not cover %s", Misdirected)
+	}
+}
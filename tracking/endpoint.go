@@ -3,10 +3,14 @@ package tracking
 import (
 	"github.com/go-kit/kit/endpoint"
 	"golang.org/x/net/context"
+
+	"github.com/marcusolsson/goddd/access"
 )
 
 type trackCargoRequest struct {
-	ID string
+	ID       string
+	Token    access.Token
+	Language Language
 }
 
 type trackCargoResponse struct {
@@ -19,7 +23,49 @@ func (r trackCargoResponse) error() error { return r.Err }
 func makeTrackCargoEndpoint(ts Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(trackCargoRequest)
-		c, err := ts.Track(req.ID)
+		c, err := ts.Track(req.ID, req.Token, req.Language)
 		return trackCargoResponse{Cargo: &c, Err: err}, nil
 	}
 }
+
+type trackCargosRequest struct {
+	IDs      []string
+	Token    access.Token
+	Language Language
+}
+
+type trackCargosResponse struct {
+	Cargos map[string]Result `json:"cargos,omitempty"`
+	Err    error             `json:"error,omitempty"`
+}
+
+func (r trackCargosResponse) error() error { return r.Err }
+
+func makeTrackCargosEndpoint(ts Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(trackCargosRequest)
+		cargos, err := ts.TrackMany(req.IDs, req.Token, req.Language)
+		return trackCargosResponse{Cargos: cargos, Err: err}, nil
+	}
+}
+
+type searchCargosRequest struct {
+	Ref      string
+	Token    access.Token
+	Language Language
+}
+
+type searchCargosResponse struct {
+	Cargos []Cargo `json:"cargos,omitempty"`
+	Err    error   `json:"error,omitempty"`
+}
+
+func (r searchCargosResponse) error() error { return r.Err }
+
+func makeSearchCargosEndpoint(ts Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(searchCargosRequest)
+		cargos, err := ts.Search(req.Ref, req.Token, req.Language)
+		return searchCargosResponse{Cargos: cargos, Err: err}, nil
+	}
+}
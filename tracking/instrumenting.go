@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"github.com/go-kit/kit/metrics"
+
+	"github.com/marcusolsson/goddd/access"
 )
 
 type instrumentingService struct {
@@ -21,12 +23,32 @@ func NewInstrumentingService(counter metrics.Counter, latency metrics.TimeHistog
 	}
 }
 
-func (s *instrumentingService) Track(id string) (Cargo, error) {
+func (s *instrumentingService) Track(id string, token access.Token, lang Language) (Cargo, error) {
 	defer func(begin time.Time) {
 		methodField := metrics.Field{Key: "method", Value: "track"}
 		s.requestCount.With(methodField).Add(1)
 		s.requestLatency.With(methodField).Observe(time.Since(begin))
 	}(time.Now())
 
-	return s.Service.Track(id)
+	return s.Service.Track(id, token, lang)
+}
+
+func (s *instrumentingService) TrackMany(ids []string, token access.Token, lang Language) (map[string]Result, error) {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "track_many"}
+		s.requestCount.With(methodField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.TrackMany(ids, token, lang)
+}
+
+func (s *instrumentingService) Search(ref string, token access.Token, lang Language) ([]Cargo, error) {
+	defer func(begin time.Time) {
+		methodField := metrics.Field{Key: "method", Value: "search"}
+		s.requestCount.With(methodField).Add(1)
+		s.requestLatency.With(methodField).Observe(time.Since(begin))
+	}(time.Now())
+
+	return s.Service.Search(ref, token, lang)
 }
@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"github.com/go-kit/kit/log"
+
+	"github.com/marcusolsson/goddd/access"
 )
 
 type loggingService struct {
@@ -16,9 +18,23 @@ func NewLoggingService(logger log.Logger, s Service) Service {
 	return &loggingService{logger, s}
 }
 
-func (s *loggingService) Track(id string) (c Cargo, err error) {
+func (s *loggingService) Track(id string, token access.Token, lang Language) (c Cargo, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log("method", "track", "tracking_id", id, "language", lang, "took", time.Since(begin), "err", err)
+	}(time.Now())
+	return s.Service.Track(id, token, lang)
+}
+
+func (s *loggingService) TrackMany(ids []string, token access.Token, lang Language) (res map[string]Result, err error) {
+	defer func(begin time.Time) {
+		s.logger.Log("method", "track_many", "count", len(ids), "language", lang, "took", time.Since(begin), "err", err)
+	}(time.Now())
+	return s.Service.TrackMany(ids, token, lang)
+}
+
+func (s *loggingService) Search(ref string, token access.Token, lang Language) (cargos []Cargo, err error) {
 	defer func(begin time.Time) {
-		s.logger.Log("method", "track", "tracking_id", id, "took", time.Since(begin), "err", err)
+		s.logger.Log("method", "search", "ref", ref, "matches", len(cargos), "language", lang, "took", time.Since(begin), "err", err)
 	}(time.Now())
-	return s.Service.Track(id)
+	return s.Service.Search(ref, token, lang)
 }
@@ -0,0 +1,199 @@
+package tracking
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Language identifies a message catalogue by its ISO 639-1 code.
+type Language string
+
+// Supported languages.
+const (
+	English Language = "en"
+	Swedish Language = "sv"
+	German  Language = "de"
+)
+
+// DefaultLanguage is used when none of the requested languages are
+// supported.
+const DefaultLanguage = English
+
+// catalogue holds the texts shown to the end-user. Format verbs are filled in
+// with voyage numbers, location names and times, in the order given by the
+// comment on each field.
+type catalogue struct {
+	notReceived    string
+	inPort         string // location
+	onboardCarrier string // voyage
+	claimed        string
+	unknown        string
+
+	nextLoad       string // voyage, location
+	nextUnload     string // voyage, location
+	nextGateOut    string // voyage, location
+	nextGateIn     string // voyage, location
+	nextReceive    string // location
+	nextClaim      string // location
+	nextCustoms    string // location
+	noNextExpected string
+
+	notYetReceived string
+	received       string // location, time
+	loaded         string // voyage, location, time
+	unloaded       string // voyage, location, time
+	gatedOut       string // voyage, location, time
+	gatedIn        string // voyage, location, time
+	claimedIn      string // location, time
+	customs        string // location, time
+	unknownEvent   string
+	unknownTime    string
+
+	timeLayout string
+}
+
+var catalogues = map[Language]catalogue{
+	English: {
+		notReceived:    "Not received",
+		inPort:         "In port %s",
+		onboardCarrier: "Onboard voyage %s",
+		claimed:        "Claimed",
+		unknown:        "Unknown",
+
+		nextLoad:       "Next expected activity is to load cargo onto voyage %s in %s.",
+		nextUnload:     "Next expected activity is to unload cargo off of voyage %s in %s.",
+		nextGateOut:    "Next expected activity is for cargo to leave the gate by truck %s in %s.",
+		nextGateIn:     "Next expected activity is for cargo to arrive at the gate by truck %s in %s.",
+		nextReceive:    "Next expected activity is to receive cargo in %s.",
+		nextClaim:      "Next expected activity is to claim cargo in %s.",
+		nextCustoms:    "Next expected activity is to customs cargo in %s.",
+		noNextExpected: "There are currently no expected activities for this cargo.",
+
+		notYetReceived: "Cargo has not yet been received.",
+		received:       "Received in %s, at %s.",
+		loaded:         "Loaded onto voyage %s in %s, at %s.",
+		unloaded:       "Unloaded off voyage %s in %s, at %s.",
+		gatedOut:       "Left the gate by truck %s in %s, at %s.",
+		gatedIn:        "Arrived at the gate by truck %s in %s, at %s.",
+		claimedIn:      "Claimed in %s, at %s.",
+		customs:        "Cleared customs in %s, at %s.",
+		unknownEvent:   "[Unknown status]",
+		unknownTime:    "an unknown time",
+
+		timeLayout: "Jan 2, 2006 15:04 MST",
+	},
+	Swedish: {
+		notReceived:    "Ej mottagen",
+		inPort:         "I hamn i %s",
+		onboardCarrier: "Ombord på resa %s",
+		claimed:        "Utlämnad",
+		unknown:        "Okänd",
+
+		nextLoad:       "Nästa förväntade aktivitet är lastning ombord på resa %s i %s.",
+		nextUnload:     "Nästa förväntade aktivitet är lossning från resa %s i %s.",
+		nextGateOut:    "Nästa förväntade aktivitet är utleverans genom grinden med lastbil %s i %s.",
+		nextGateIn:     "Nästa förväntade aktivitet är inleverans genom grinden med lastbil %s i %s.",
+		nextReceive:    "Nästa förväntade aktivitet är mottagning i %s.",
+		nextClaim:      "Nästa förväntade aktivitet är utlämning i %s.",
+		nextCustoms:    "Nästa förväntade aktivitet är tullklarering i %s.",
+		noNextExpected: "Det finns för närvarande inga förväntade aktiviteter för godset.",
+
+		notYetReceived: "Godset har ännu inte tagits emot.",
+		received:       "Mottagen i %s, %s.",
+		loaded:         "Lastad ombord på resa %s i %s, %s.",
+		unloaded:       "Lossad från resa %s i %s, %s.",
+		gatedOut:       "Utlevererad genom grinden med lastbil %s i %s, %s.",
+		gatedIn:        "Inlevererad genom grinden med lastbil %s i %s, %s.",
+		claimedIn:      "Utlämnad i %s, %s.",
+		customs:        "Tullklarerad i %s, %s.",
+		unknownEvent:   "[Okänd status]",
+		unknownTime:    "okänd tidpunkt",
+
+		timeLayout: "2006-01-02 15:04 MST",
+	},
+	German: {
+		notReceived:    "Nicht angenommen",
+		inPort:         "Im Hafen %s",
+		onboardCarrier: "An Bord der Reise %s",
+		claimed:        "Abgeholt",
+		unknown:        "Unbekannt",
+
+		nextLoad:       "Als Nächstes wird die Ladung in %[2]s auf die Reise %[1]s verladen.",
+		nextUnload:     "Als Nächstes wird die Ladung in %[2]s von der Reise %[1]s entladen.",
+		nextGateOut:    "Als Nächstes verlässt die Ladung in %[2]s das Terminal mit dem Lkw %[1]s.",
+		nextGateIn:     "Als Nächstes trifft die Ladung in %[2]s mit dem Lkw %[1]s am Terminal ein.",
+		nextReceive:    "Als Nächstes wird die Ladung in %s angenommen.",
+		nextClaim:      "Als Nächstes wird die Ladung in %s abgeholt.",
+		nextCustoms:    "Als Nächstes wird die Ladung in %s verzollt.",
+		noNextExpected: "Für diese Ladung sind derzeit keine Aktivitäten zu erwarten.",
+
+		notYetReceived: "Die Ladung wurde noch nicht angenommen.",
+		received:       "Angenommen in %s, %s.",
+		loaded:         "Verladen auf die Reise %s in %s, %s.",
+		unloaded:       "Entladen von der Reise %s in %s, %s.",
+		gatedOut:       "Mit dem Lkw %s aus dem Terminal in %s ausgefahren, %s.",
+		gatedIn:        "Mit dem Lkw %s am Terminal in %s eingetroffen, %s.",
+		claimedIn:      "Abgeholt in %s, %s.",
+		customs:        "Verzollt in %s, %s.",
+		unknownEvent:   "[Unbekannter Status]",
+		unknownTime:    "zu einem unbekannten Zeitpunkt",
+
+		timeLayout: "am 02.01.2006 15:04 MST",
+	},
+}
+
+// catalogueFor returns the message catalogue of a language, falling back to
+// the default language.
+func catalogueFor(lang Language) catalogue {
+	if c, ok := catalogues[lang]; ok {
+		return c
+	}
+	return catalogues[DefaultLanguage]
+}
+
+// ParseAcceptLanguage returns the supported language preferred by an
+// Accept-Language header, such as "sv-SE,sv;q=0.9,en;q=0.8".
+func ParseAcceptLanguage(header string) Language {
+	var prefs []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if i := strings.Index(tag, "-"); i >= 0 {
+			tag = tag[:i]
+		}
+
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+
+		if _, ok := catalogues[Language(tag)]; ok && q > 0 {
+			prefs = append(prefs, weighted{Language(tag), q})
+		}
+	}
+
+	sort.Stable(byQuality(prefs))
+
+	if len(prefs) == 0 {
+		return DefaultLanguage
+	}
+	return prefs[0].lang
+}
+
+type weighted struct {
+	lang Language
+	q    float64
+}
+
+type byQuality []weighted
+
+func (s byQuality) Len() int           { return len(s) }
+func (s byQuality) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byQuality) Less(i, j int) bool { return s[i].q > s[j].q }
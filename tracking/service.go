@@ -3,66 +3,320 @@
 package tracking
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/marcusolsson/goddd/access"
 	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/location"
 )
 
 // ErrInvalidArgument is returned when one or more arguments are invalid.
 var ErrInvalidArgument = errors.New("invalid argument")
 
-// Service is the interface that provides the basic Track method.
+// ErrUnauthorized is returned when the access token is missing, has expired
+// or been revoked, or does not give access to the cargo.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// MaxBatchSize is the largest number of cargos that can be tracked in one
+// call to TrackMany.
+const MaxBatchSize = 500
+
+// Service is the interface that provides the basic Track method. Cargos are
+// only tracked on behalf of the bearer of an access token, i.e. either a
+// share token for the cargo, which gives the public view, or the session of
+// a customer that is a party of the cargo, which gives the full view.
 type Service interface {
-	// Track returns a cargo matching a tracking ID.
-	Track(id string) (Cargo, error)
+	// Track returns a cargo matching a tracking ID, with texts in the given
+	// language.
+	Track(id string, token access.Token, lang Language) (Cargo, error)
+
+	// TrackMany returns the cargos matching the tracking IDs, keyed by
+	// tracking ID. Tracking IDs that could not be tracked are given an error
+	// instead.
+	TrackMany(ids []string, token access.Token, lang Language) (map[string]Result, error)
+
+	// Search returns the cargos known by an identifier, i.e. either a
+	// tracking ID or a reference such as a purchase order, container or B/L
+	// number, ordered by tracking ID. Since references may be shared, there
+	// may be several matches. Cargos the token does not give access to are
+	// left out.
+	Search(ref string, token access.Token, lang Language) ([]Cargo, error)
 }
 
 type service struct {
 	cargos         cargo.Repository
 	handlingEvents cargo.HandlingEventRepository
+	locations      location.Repository
+	grants         access.Repository
 }
 
-func (s *service) Track(id string) (Cargo, error) {
+func (s *service) Track(id string, token access.Token, lang Language) (Cargo, error) {
 	if id == "" {
 		return Cargo{}, ErrInvalidArgument
 	}
+
+	g, err := s.grant(token)
+	if err != nil {
+		return Cargo{}, err
+	}
+
+	// Share tokens are checked before the lookup, so that they cannot be
+	// used to find out what other cargos exist.
+	if g.TrackingID != "" && g.TrackingID != cargo.TrackingID(id) {
+		return Cargo{}, ErrUnauthorized
+	}
+
 	c, err := s.cargos.Find(cargo.TrackingID(id))
 	if err != nil {
 		return Cargo{}, err
 	}
-	return assemble(c, s.handlingEvents), nil
+
+	switch g.ViewOf(c, time.Now()) {
+	case access.FullView:
+		h := s.handlingEvents.QueryHandlingHistory(c.TrackingID)
+		return assemble(c, h, newLocator(s.locations), catalogueFor(lang)), nil
+	case access.PublicView:
+		return assemblePublic(c, newLocator(s.locations), catalogueFor(lang)), nil
+	}
+
+	return Cargo{}, ErrUnauthorized
+}
+
+func (s *service) TrackMany(ids []string, token access.Token, lang Language) (map[string]Result, error) {
+	if len(ids) == 0 || len(ids) > MaxBatchSize {
+		return nil, ErrInvalidArgument
+	}
+
+	g, err := s.grant(token)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]Result, len(ids))
+
+	var tids []cargo.TrackingID
+	for _, id := range ids {
+		if _, ok := res[id]; ok {
+			continue
+		}
+		if id == "" {
+			res[id] = Result{Error: ErrInvalidArgument.Error()}
+			continue
+		}
+		if g.TrackingID != "" && g.TrackingID != cargo.TrackingID(id) {
+			res[id] = Result{Error: ErrUnauthorized.Error()}
+			continue
+		}
+		res[id] = Result{Error: cargo.ErrUnknown.Error()}
+		tids = append(tids, cargo.TrackingID(id))
+	}
+
+	if len(tids) == 0 {
+		return res, nil
+	}
+
+	cargos, err := s.cargos.FindMany(tids)
+	if err != nil {
+		return nil, err
+	}
+
+	views := s.assembleAll(cargos, g, lang)
+
+	for _, c := range cargos {
+		tc, ok := views[c.TrackingID]
+		if !ok {
+			res[string(c.TrackingID)] = Result{Error: ErrUnauthorized.Error()}
+			continue
+		}
+		res[string(c.TrackingID)] = Result{Cargo: &tc}
+	}
+
+	return res, nil
+}
+
+func (s *service) Search(ref string, token access.Token, lang Language) ([]Cargo, error) {
+	if strings.TrimSpace(ref) == "" {
+		return nil, ErrInvalidArgument
+	}
+
+	g, err := s.grant(token)
+	if err != nil {
+		return nil, err
+	}
+
+	cargos, err := s.cargos.FindByReference(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := s.cargos.Find(cargo.TrackingID(ref))
+	switch err {
+	case nil:
+		cargos = append(cargos, c)
+	case cargo.ErrUnknown:
+	default:
+		return nil, err
+	}
+
+	views := s.assembleAll(cargos, g, lang)
+	if len(views) == 0 {
+		return nil, cargo.ErrUnknown
+	}
+
+	res := make([]Cargo, 0, len(views))
+	for _, tc := range views {
+		res = append(res, tc)
+	}
+	sort.Sort(byTrackingID(res))
+
+	return res, nil
+}
+
+// assembleAll returns the cargos that the grant gives access to, keyed by
+// tracking ID. Handling histories are only queried for the full view, and
+// in bulk.
+func (s *service) assembleAll(cargos []*cargo.Cargo, g *access.Grant, lang Language) map[cargo.TrackingID]Cargo {
+	var (
+		now   = time.Now()
+		views = make(map[cargo.TrackingID]access.View, len(cargos))
+		full  = make([]cargo.TrackingID, 0, len(cargos))
+	)
+
+	for _, c := range cargos {
+		v := g.ViewOf(c, now)
+		views[c.TrackingID] = v
+		if v == access.FullView {
+			full = append(full, c.TrackingID)
+		}
+	}
+
+	var (
+		histories = s.handlingEvents.QueryHandlingHistories(full)
+		l         = newLocator(s.locations)
+		m         = catalogueFor(lang)
+		res       = make(map[cargo.TrackingID]Cargo, len(cargos))
+	)
+
+	for _, c := range cargos {
+		switch views[c.TrackingID] {
+		case access.FullView:
+			res[c.TrackingID] = assemble(c, histories[c.TrackingID], l, m)
+		case access.PublicView:
+			res[c.TrackingID] = assemblePublic(c, l, m)
+		}
+	}
+
+	return res
+}
+
+// grant returns the grant of an access token, as long as it is still active.
+func (s *service) grant(token access.Token) (*access.Grant, error) {
+	if token == "" {
+		return nil, ErrUnauthorized
+	}
+	g, err := s.grants.Find(token)
+	if err == access.ErrUnknown {
+		return nil, ErrUnauthorized
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !g.Active(time.Now()) {
+		return nil, ErrUnauthorized
+	}
+	return g, nil
 }
 
 // NewService returns a new instance of the default Service.
-func NewService(cargos cargo.Repository, events cargo.HandlingEventRepository) Service {
+func NewService(cargos cargo.Repository, events cargo.HandlingEventRepository, locations location.Repository, grants access.Repository) Service {
 	return &service{
 		cargos:         cargos,
 		handlingEvents: events,
+		locations:      locations,
+		grants:         grants,
 	}
 }
 
-// Cargo is a read model for tracking views.
+// View is how much of a cargo is shown.
+type View string
+
+// Valid views.
+const (
+	PublicView View = "public"
+	FullView   View = "full"
+)
+
+// Cargo is a read model for tracking views. The public view only has the
+// status and ETA of the cargo.
 type Cargo struct {
 	TrackingID           string    `json:"tracking_id"`
+	View                 View      `json:"view"`
 	StatusText           string    `json:"status_text"`
 	Origin               string    `json:"origin"`
 	Destination          string    `json:"destination"`
 	ETA                  time.Time `json:"eta"`
 	NextExpectedActivity string    `json:"next_expected_activity"`
 	ArrivalDeadline      time.Time `json:"arrival_deadline"`
+	Legs                 []Leg     `json:"legs"`
+	Progress             int       `json:"progress"`
 	Events               []Event   `json:"events"`
 }
 
-// Leg is a read model for booking views.
+// MarshalJSON leaves out everything but the status and ETA from the public
+// view.
+func (c Cargo) MarshalJSON() ([]byte, error) {
+	if c.View == PublicView {
+		return json.Marshal(struct {
+			TrackingID string    `json:"tracking_id"`
+			View       View      `json:"view"`
+			StatusText string    `json:"status_text"`
+			ETA        time.Time `json:"eta"`
+		}{c.TrackingID, c.View, c.StatusText, c.ETA})
+	}
+	type full Cargo
+	return json.Marshal(full(c))
+}
+
+type byTrackingID []Cargo
+
+func (s byTrackingID) Len() int           { return len(s) }
+func (s byTrackingID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byTrackingID) Less(i, j int) bool { return s[i].TrackingID < s[j].TrackingID }
+
+// Result is either a tracked cargo or the reason it could not be tracked.
+type Result struct {
+	Cargo *Cargo `json:"cargo,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// LegState describes how far the cargo has come on a leg.
+type LegState string
+
+// Valid leg states.
+const (
+	LegCompleted LegState = "completed"
+	LegCurrent   LegState = "current"
+	LegUpcoming  LegState = "upcoming"
+	LegDeviated  LegState = "deviated"
+)
+
+// Leg is a read model for the planned route in tracking views. Actual times
+// are only given once the cargo has been loaded or unloaded.
 type Leg struct {
-	VoyageNumber string    `json:"voyage_number"`
-	From         string    `json:"from"`
-	To           string    `json:"to"`
-	LoadTime     time.Time `json:"load_time"`
-	UnloadTime   time.Time `json:"unload_time"`
+	VoyageNumber     string     `json:"voyage_number"`
+	Mode             string     `json:"mode"`
+	From             string     `json:"from"`
+	To               string     `json:"to"`
+	LoadTime         time.Time  `json:"load_time"`
+	UnloadTime       time.Time  `json:"unload_time"`
+	ActualLoadTime   *time.Time `json:"actual_load_time,omitempty"`
+	ActualUnloadTime *time.Time `json:"actual_unload_time,omitempty"`
+	State            LegState   `json:"state"`
 }
 
 // Event is a read model for tracking views.
@@ -71,86 +325,174 @@ type Event struct {
 	Expected    bool   `json:"expected"`
 }
 
-func assemble(c *cargo.Cargo, events cargo.HandlingEventRepository) Cargo {
+func assemble(c *cargo.Cargo, h cargo.HandlingHistory, l *locator, m catalogue) Cargo {
 	return Cargo{
 		TrackingID:           string(c.TrackingID),
+		View:                 FullView,
 		Origin:               string(c.Origin),
 		Destination:          string(c.RouteSpecification.Destination),
 		ETA:                  c.Delivery.ETA,
-		NextExpectedActivity: nextExpectedActivity(c),
+		NextExpectedActivity: nextExpectedActivity(c, l, m),
 		ArrivalDeadline:      c.RouteSpecification.ArrivalDeadline,
-		StatusText:           assembleStatusText(c),
-		Events:               assembleEvents(c, events),
+		StatusText:           assembleStatusText(c, l, m),
+		Legs:                 assembleLegs(c, h),
+		Progress:             progress(c, h),
+		Events:               assembleEvents(c, h, l, m),
+	}
+}
+
+func assemblePublic(c *cargo.Cargo, l *locator, m catalogue) Cargo {
+	return Cargo{
+		TrackingID: string(c.TrackingID),
+		View:       PublicView,
+		ETA:        c.Delivery.ETA,
+		StatusText: assembleStatusText(c, l, m),
 	}
 }
 
-func assembleLegs(c cargo.Cargo) []Leg {
-	var legs []Leg
+// assembleLegs matches the handling history against the itinerary. Once a
+// cargo has been misdirected, the legs it has yet to complete are deviated
+// from until it has been rerouted.
+func assembleLegs(c *cargo.Cargo, h cargo.HandlingHistory) []Leg {
+	legs := make([]Leg, 0, len(c.Itinerary.Legs))
 	for _, l := range c.Itinerary.Legs {
-		legs = append(legs, Leg{
+		leg := Leg{
 			VoyageNumber: string(l.VoyageNumber),
+			Mode:         strings.ToLower(l.Mode.String()),
 			From:         string(l.LoadLocation),
 			To:           string(l.UnloadLocation),
 			LoadTime:     l.LoadTime,
 			UnloadTime:   l.UnloadTime,
-		})
+			State:        LegUpcoming,
+		}
+
+		if e, ok := lastEvent(h, l.LoadActivity()); ok {
+			leg.ActualLoadTime = &e.Completed
+			leg.State = LegCurrent
+		}
+		if e, ok := lastEvent(h, l.UnloadActivity()); ok {
+			leg.ActualUnloadTime = &e.Completed
+			leg.State = LegCompleted
+		}
+
+		if c.Delivery.IsMisdirected && leg.State != LegCompleted {
+			leg.State = LegDeviated
+		}
+
+		legs = append(legs, leg)
 	}
 	return legs
 }
 
-func nextExpectedActivity(c *cargo.Cargo) string {
+// progress returns how much of the planned handling has been done, in
+// percent. Each leg accounts for a load and an unload, or a gate-out and a
+// gate-in by truck, in addition to receiving the cargo at the origin and
+// claiming it at the destination.
+func progress(c *cargo.Cargo, h cargo.HandlingHistory) int {
+	if c.Delivery.TransportStatus == cargo.Claimed {
+		return 100
+	}
+
+	var (
+		total = 2 + 2*len(c.Itinerary.Legs)
+		done  int
+	)
+
+	for _, e := range h.HandlingEvents {
+		if e.Activity.Type == cargo.Receive && e.Activity.Location == c.Origin {
+			done++
+			break
+		}
+	}
+	for _, l := range c.Itinerary.Legs {
+		if _, ok := lastEvent(h, l.LoadActivity()); ok {
+			done++
+		}
+		if _, ok := lastEvent(h, l.UnloadActivity()); ok {
+			done++
+		}
+	}
+
+	return done * 100 / total
+}
+
+// lastEvent returns the latest handling event of the given activity.
+func lastEvent(h cargo.HandlingHistory, a cargo.HandlingActivity) (cargo.HandlingEvent, bool) {
+	for i := len(h.HandlingEvents) - 1; i >= 0; i-- {
+		e := h.HandlingEvents[i]
+		if e.Activity == a {
+			return e, true
+		}
+	}
+	return cargo.HandlingEvent{}, false
+}
+
+func nextExpectedActivity(c *cargo.Cargo, l *locator, m catalogue) string {
 	a := c.Delivery.NextExpectedActivity
-	prefix := "Next expected activity is to"
 
 	switch a.Type {
 	case cargo.Load:
-		return fmt.Sprintf("%s %s cargo onto voyage %s in %s.", prefix, strings.ToLower(a.Type.String()), a.VoyageNumber, a.Location)
+		return fmt.Sprintf(m.nextLoad, a.VoyageNumber, l.name(a.Location))
 	case cargo.Unload:
-		return fmt.Sprintf("%s %s cargo off of voyage %s in %s.", prefix, strings.ToLower(a.Type.String()), a.VoyageNumber, a.Location)
-	case cargo.NotHandled:
-		return "There are currently no expected activities for this cargo."
+		return fmt.Sprintf(m.nextUnload, a.VoyageNumber, l.name(a.Location))
+	case cargo.GateOut:
+		return fmt.Sprintf(m.nextGateOut, a.VoyageNumber, l.name(a.Location))
+	case cargo.GateIn:
+		return fmt.Sprintf(m.nextGateIn, a.VoyageNumber, l.name(a.Location))
+	case cargo.Receive:
+		return fmt.Sprintf(m.nextReceive, l.name(a.Location))
+	case cargo.Claim:
+		return fmt.Sprintf(m.nextClaim, l.name(a.Location))
+	case cargo.Customs:
+		return fmt.Sprintf(m.nextCustoms, l.name(a.Location))
 	}
 
-	return fmt.Sprintf("%s %s cargo in %s.", prefix, strings.ToLower(a.Type.String()), a.Location)
+	return m.noNextExpected
 }
 
-func assembleStatusText(c *cargo.Cargo) string {
+func assembleStatusText(c *cargo.Cargo, l *locator, m catalogue) string {
 	switch c.Delivery.TransportStatus {
 	case cargo.NotReceived:
-		return "Not received"
+		return m.notReceived
 	case cargo.InPort:
-		return fmt.Sprintf("In port %s", c.Delivery.LastKnownLocation)
+		return fmt.Sprintf(m.inPort, l.name(c.Delivery.LastKnownLocation))
 	case cargo.OnboardCarrier:
-		return fmt.Sprintf("Onboard voyage %s", c.Delivery.CurrentVoyage)
+		return fmt.Sprintf(m.onboardCarrier, c.Delivery.CurrentVoyage)
 	case cargo.Claimed:
-		return "Claimed"
+		return m.claimed
 	default:
-		return "Unknown"
+		return m.unknown
 	}
 }
 
-func assembleEvents(c *cargo.Cargo, handlingEvents cargo.HandlingEventRepository) []Event {
-	h := handlingEvents.QueryHandlingHistory(c.TrackingID)
-
+func assembleEvents(c *cargo.Cargo, h cargo.HandlingHistory, l *locator, m catalogue) []Event {
 	var events []Event
 	for _, e := range h.HandlingEvents {
-		var description string
+		var (
+			description string
+			where       = l.name(e.Activity.Location)
+			when        = l.localTime(e.Activity.Location, e.Completed, m)
+		)
 
 		switch e.Activity.Type {
 		case cargo.NotHandled:
-			description = "Cargo has not yet been received."
+			description = m.notYetReceived
 		case cargo.Receive:
-			description = fmt.Sprintf("Received in %s, at %s", e.Activity.Location, time.Now().Format(time.RFC3339))
+			description = fmt.Sprintf(m.received, where, when)
 		case cargo.Load:
-			description = fmt.Sprintf("Loaded onto voyage %s in %s, at %s.", e.Activity.VoyageNumber, e.Activity.Location, time.Now().Format(time.RFC3339))
+			description = fmt.Sprintf(m.loaded, e.Activity.VoyageNumber, where, when)
 		case cargo.Unload:
-			description = fmt.Sprintf("Unloaded off voyage %s in %s, at %s.", e.Activity.VoyageNumber, e.Activity.Location, time.Now().Format(time.RFC3339))
+			description = fmt.Sprintf(m.unloaded, e.Activity.VoyageNumber, where, when)
+		case cargo.GateOut:
+			description = fmt.Sprintf(m.gatedOut, e.Activity.VoyageNumber, where, when)
+		case cargo.GateIn:
+			description = fmt.Sprintf(m.gatedIn, e.Activity.VoyageNumber, where, when)
 		case cargo.Claim:
-			description = fmt.Sprintf("Claimed in %s, at %s.", e.Activity.Location, time.Now().Format(time.RFC3339))
+			description = fmt.Sprintf(m.claimedIn, where, when)
 		case cargo.Customs:
-			description = fmt.Sprintf("Cleared customs in %s, at %s.", e.Activity.Location, time.Now().Format(time.RFC3339))
+			description = fmt.Sprintf(m.customs, where, when)
 		default:
-			description = "[Unknown status]"
+			description = m.unknownEvent
 		}
 
 		events = append(events, Event{
@@ -161,3 +503,43 @@ func assembleEvents(c *cargo.Cargo, handlingEvents cargo.HandlingEventRepository
 
 	return events
 }
+
+// locator looks up locations, remembering them for the duration of a single
+// request.
+type locator struct {
+	locations location.Repository
+	found     map[location.UNLocode]*location.Location
+}
+
+func newLocator(locations location.Repository) *locator {
+	return &locator{
+		locations: locations,
+		found:     make(map[location.UNLocode]*location.Location),
+	}
+}
+
+func (l *locator) find(code location.UNLocode) *location.Location {
+	if loc, ok := l.found[code]; ok {
+		return loc
+	}
+	loc, err := l.locations.Find(code)
+	if err != nil {
+		// Fall back to the UN locode of locations that are not registered.
+		loc = &location.Location{UNLocode: code, Name: string(code)}
+	}
+	l.found[code] = loc
+	return loc
+}
+
+// name returns the name of a location.
+func (l *locator) name(code location.UNLocode) string {
+	return l.find(code).Name
+}
+
+// localTime formats a time in the time zone of a location.
+func (l *locator) localTime(code location.UNLocode, t time.Time, m catalogue) string {
+	if t.IsZero() {
+		return m.unknownTime
+	}
+	return t.In(l.find(code).Zone()).Format(m.timeLayout)
+}
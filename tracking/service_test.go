@@ -1,20 +1,38 @@
 package tracking
 
 import (
+	"encoding/json"
+	"reflect"
 	"testing"
+	"time"
 
+	"github.com/marcusolsson/goddd/access"
 	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+	"github.com/marcusolsson/goddd/inmem"
 	"github.com/marcusolsson/goddd/location"
 	"github.com/marcusolsson/goddd/mock"
+	"github.com/marcusolsson/goddd/voyage"
 )
 
+// newGrants returns a grant repository with a session for the shipper of
+// the cargos used in the tests.
+func newGrants() (access.Repository, access.Token) {
+	g := access.NewSession("C1", time.Now())
+	r := inmem.NewGrantRepository()
+	r.Store(g)
+	return r, g.Token
+}
+
 func TestTrack(t *testing.T) {
 	var cargos mock.CargoRepository
 	cargos.FindFn = func(id cargo.TrackingID) (*cargo.Cargo, error) {
-		return cargo.New("FTL456", cargo.RouteSpecification{
+		c := cargo.New("FTL456", cargo.RouteSpecification{
 			Origin:      location.AUMEL,
 			Destination: location.SESTO,
-		}), nil
+		})
+		c.AttachParty(cargo.Shipper, "C1")
+		return c, nil
 	}
 
 	var events mock.HandlingEventRepository
@@ -22,13 +40,18 @@ func TestTrack(t *testing.T) {
 		return cargo.HandlingHistory{}
 	}
 
-	s := NewService(&cargos, &events)
+	grants, token := newGrants()
+
+	s := NewService(&cargos, &events, inmem.NewLocationRepository(), grants)
 
-	c, err := s.Track("FTL456")
+	c, err := s.Track("FTL456", token, English)
 	if err != nil {
 		t.Fatal(err)
 	}
 
+	if c.View != FullView {
+		t.Errorf("c.View = %v; want = %v", c.View, FullView)
+	}
 	if c.TrackingID != "FTL456" {
 		t.Errorf("c.TrackingID = %v; want = %v", c.TrackingID, "FTL456")
 	}
@@ -42,3 +65,430 @@ func TestTrack(t *testing.T) {
 		t.Errorf("c.StatusText = %v; want = %v", c.StatusText, cargo.NotReceived.String())
 	}
 }
+
+func TestTrackLocalized(t *testing.T) {
+	completed := time.Date(2009, time.March, 1, 9, 30, 0, 0, time.UTC)
+
+	c := cargo.New("FTL456", cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.AUMEL,
+	})
+	c.AttachParty(cargo.Shipper, "C1")
+	c.Delivery.TransportStatus = cargo.InPort
+	c.Delivery.LastKnownLocation = location.SESTO
+
+	var cargos mock.CargoRepository
+	cargos.FindFn = func(id cargo.TrackingID) (*cargo.Cargo, error) {
+		return c, nil
+	}
+
+	var events mock.HandlingEventRepository
+	events.QueryHandlingHistoryFn = func(id cargo.TrackingID) cargo.HandlingHistory {
+		return cargo.HandlingHistory{HandlingEvents: []cargo.HandlingEvent{
+			{
+				TrackingID: "FTL456",
+				Activity:   cargo.HandlingActivity{Type: cargo.Receive, Location: location.SESTO},
+				Completed:  completed,
+			},
+		}}
+	}
+
+	grants, token := newGrants()
+
+	s := NewService(&cargos, &events, inmem.NewLocationRepository(), grants)
+
+	tests := []struct {
+		lang   Language
+		status string
+		event  string
+	}{
+		{English, "In port Stockholm", "Received in Stockholm, at Mar 1, 2009 10:30 CET."},
+		{Swedish, "I hamn i Stockholm", "Mottagen i Stockholm, 2009-03-01 10:30 CET."},
+		{German, "Im Hafen Stockholm", "Angenommen in Stockholm, am 01.03.2009 10:30 CET."},
+	}
+
+	for _, tt := range tests {
+		got, err := s.Track("FTL456", token, tt.lang)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.StatusText != tt.status {
+			t.Errorf("StatusText = %q; want = %q", got.StatusText, tt.status)
+		}
+		if got.Events[0].Description != tt.event {
+			t.Errorf("Description = %q; want = %q", got.Events[0].Description, tt.event)
+		}
+	}
+
+	// Events completed at an unknown time still read as a sentence.
+	completed = time.Time{}
+
+	got, err := s.Track("FTL456", token, German)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Angenommen in Stockholm, zu einem unbekannten Zeitpunkt."; got.Events[0].Description != want {
+		t.Errorf("Description = %q; want = %q", got.Events[0].Description, want)
+	}
+}
+
+func TestTrackAccess(t *testing.T) {
+	c := cargo.New("FTL456", cargo.RouteSpecification{
+		Origin:      location.AUMEL,
+		Destination: location.SESTO,
+	})
+	c.AttachParty(cargo.Shipper, "C1")
+
+	var cargos mock.CargoRepository
+	cargos.FindFn = func(id cargo.TrackingID) (*cargo.Cargo, error) {
+		if id != c.TrackingID {
+			return nil, cargo.ErrUnknown
+		}
+		return c, nil
+	}
+
+	var events mock.HandlingEventRepository
+	events.QueryHandlingHistoryFn = func(id cargo.TrackingID) cargo.HandlingHistory {
+		return cargo.HandlingHistory{}
+	}
+
+	var (
+		now     = time.Now()
+		share   = access.NewShareToken("FTL456", now)
+		other   = access.NewShareToken("XYZ789", now)
+		session = access.NewSession("C2", now)
+		revoked = access.NewShareToken("FTL456", now)
+	)
+	revoked.Revoke(now)
+
+	grants := inmem.NewGrantRepository()
+	for _, g := range []*access.Grant{share, other, session, revoked} {
+		grants.Store(g)
+	}
+
+	s := NewService(&cargos, &events, inmem.NewLocationRepository(), grants)
+
+	got, err := s.Track("FTL456", share.Token, English)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.View != PublicView {
+		t.Errorf("View = %v; want = %v", got.View, PublicView)
+	}
+	if events.QueryHandlingHistoryInvoked {
+		t.Errorf("handling history should not be queried for the public view")
+	}
+
+	b, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"origin", "destination", "legs", "events"} {
+		if _, ok := fields[f]; ok {
+			t.Errorf("public view should not include %q", f)
+		}
+	}
+
+	for _, token := range []access.Token{"", "unknown", other.Token, session.Token, revoked.Token} {
+		if _, err := s.Track("FTL456", token, English); err != ErrUnauthorized {
+			t.Errorf("Track(%q) = %v; want = %v", token, err, ErrUnauthorized)
+		}
+	}
+
+	cargos.FindInvoked = false
+	if _, err := s.Track("ABC123", share.Token, English); err != ErrUnauthorized {
+		t.Errorf("err = %v; want = %v", err, ErrUnauthorized)
+	}
+	if cargos.FindInvoked {
+		t.Errorf("share tokens should not be used to look up other cargos")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	cargos := inmem.NewCargoRepository()
+
+	for _, c := range []struct {
+		id       cargo.TrackingID
+		customer customer.ID
+		refs     []cargo.Reference
+	}{
+		{"AAA111", "C1", []cargo.Reference{
+			{Type: cargo.CustomerReference, Value: "PO-1"},
+			{Type: cargo.ContainerNumber, Value: "CSQU3054383"},
+		}},
+		{"BBB222", "C1", []cargo.Reference{{Type: cargo.CustomerReference, Value: "PO-1"}}},
+		{"CCC333", "C2", []cargo.Reference{{Type: cargo.CustomerReference, Value: "PO-1"}}},
+	} {
+		tc := cargo.New(c.id, cargo.RouteSpecification{
+			Origin:      location.SESTO,
+			Destination: location.AUMEL,
+		})
+		tc.AttachParty(cargo.Shipper, c.customer)
+		for _, r := range c.refs {
+			tc.AddReference(r)
+		}
+		cargos.Store(tc)
+	}
+
+	grants, token := newGrants()
+
+	share := access.NewShareToken("CCC333", time.Now())
+	grants.Store(share)
+
+	s := NewService(cargos, inmem.NewHandlingEventRepository(), inmem.NewLocationRepository(), grants)
+
+	tests := []struct {
+		ref   string
+		token access.Token
+		want  []string
+		err   error
+	}{
+		{" po-1", token, []string{"AAA111", "BBB222"}, nil},
+		{"CSQU3054383", token, []string{"AAA111"}, nil},
+		{"AAA111", token, []string{"AAA111"}, nil},
+		{"PO-1", share.Token, []string{"CCC333"}, nil},
+		{"AAA111", share.Token, nil, cargo.ErrUnknown},
+		{"PO-2", token, nil, cargo.ErrUnknown},
+		{"", token, nil, ErrInvalidArgument},
+	}
+
+	for _, tt := range tests {
+		got, err := s.Search(tt.ref, tt.token, English)
+		if err != tt.err {
+			t.Errorf("Search(%q) err = %v; want = %v", tt.ref, err, tt.err)
+			continue
+		}
+
+		var ids []string
+		for _, c := range got {
+			ids = append(ids, c.TrackingID)
+		}
+		if !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("Search(%q) = %v; want = %v", tt.ref, ids, tt.want)
+		}
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   Language
+	}{
+		{"", English},
+		{"sv-SE,sv;q=0.9,en;q=0.8", Swedish},
+		{"fr-FR,de;q=0.5,en;q=0.7", English},
+		{"fr, de-CH;q=0.3", German},
+		{"de;q=0", English},
+	}
+
+	for _, tt := range tests {
+		if got := ParseAcceptLanguage(tt.header); got != tt.want {
+			t.Errorf("ParseAcceptLanguage(%q) = %s; want = %s", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestTrackMany(t *testing.T) {
+	c := cargo.New("FTL456", cargo.RouteSpecification{
+		Origin:      location.AUMEL,
+		Destination: location.SESTO,
+	})
+	c.AttachParty(cargo.Shipper, "C1")
+
+	var cargos mock.CargoRepository
+	cargos.FindManyFn = func(ids []cargo.TrackingID) ([]*cargo.Cargo, error) {
+		var res []*cargo.Cargo
+		for _, id := range ids {
+			if id == c.TrackingID {
+				res = append(res, c)
+			}
+		}
+		return res, nil
+	}
+
+	var events mock.HandlingEventRepository
+	events.QueryHandlingHistoriesFn = func(ids []cargo.TrackingID) map[cargo.TrackingID]cargo.HandlingHistory {
+		return map[cargo.TrackingID]cargo.HandlingHistory{}
+	}
+
+	grants, token := newGrants()
+
+	s := NewService(&cargos, &events, inmem.NewLocationRepository(), grants)
+
+	res, err := s.TrackMany([]string{"FTL456", "XYZ789", "FTL456", ""}, token, English)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cargos.FindManyInvoked || !events.QueryHandlingHistoriesInvoked {
+		t.Errorf("cargos should be looked up in bulk")
+	}
+	if cargos.FindInvoked {
+		t.Errorf("cargos should not be looked up one by one")
+	}
+
+	if len(res) != 3 {
+		t.Fatalf("len(res) = %d; want = %d", len(res), 3)
+	}
+	if got := res["FTL456"]; got.Cargo == nil || got.Cargo.TrackingID != "FTL456" {
+		t.Errorf("res[FTL456] = %+v; want cargo", got)
+	}
+	if got := res["XYZ789"]; got.Cargo != nil || got.Error != cargo.ErrUnknown.Error() {
+		t.Errorf("res[XYZ789] = %+v; want error %q", got, cargo.ErrUnknown)
+	}
+	if got := res[""]; got.Error != ErrInvalidArgument.Error() {
+		t.Errorf("res[\"\"] = %+v; want error %q", got, ErrInvalidArgument)
+	}
+
+	if _, err := s.TrackMany(nil, token, English); err != ErrInvalidArgument {
+		t.Errorf("err = %v; want = %v", err, ErrInvalidArgument)
+	}
+}
+
+func TestTrackLegs(t *testing.T) {
+	var (
+		loaded   = time.Date(2009, time.March, 2, 8, 0, 0, 0, time.UTC)
+		unloaded = time.Date(2009, time.March, 5, 16, 0, 0, 0, time.UTC)
+	)
+
+	c := cargo.New("FTL456", cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.AUMEL,
+	})
+	c.AttachParty(cargo.Shipper, "C1")
+	c.AssignToRoute(cargo.Itinerary{Legs: []cargo.Leg{
+		{VoyageNumber: "V100", LoadLocation: location.SESTO, UnloadLocation: location.CNHKG},
+		{VoyageNumber: "V200", LoadLocation: location.CNHKG, UnloadLocation: location.AUMEL},
+	}})
+
+	history := []cargo.HandlingEvent{
+		{TrackingID: "FTL456", Activity: cargo.HandlingActivity{Type: cargo.Receive, Location: location.SESTO}},
+		{TrackingID: "FTL456", Activity: cargo.HandlingActivity{Type: cargo.Load, Location: location.SESTO, VoyageNumber: "V100"}, Completed: loaded},
+		{TrackingID: "FTL456", Activity: cargo.HandlingActivity{Type: cargo.Unload, Location: location.CNHKG, VoyageNumber: "V100"}, Completed: unloaded},
+	}
+
+	var cargos mock.CargoRepository
+	cargos.FindFn = func(id cargo.TrackingID) (*cargo.Cargo, error) {
+		return c, nil
+	}
+
+	var events mock.HandlingEventRepository
+	events.QueryHandlingHistoryFn = func(id cargo.TrackingID) cargo.HandlingHistory {
+		return cargo.HandlingHistory{HandlingEvents: history}
+	}
+
+	grants, token := newGrants()
+
+	s := NewService(&cargos, &events, inmem.NewLocationRepository(), grants)
+
+	tests := []struct {
+		events      int
+		misdirected bool
+		states      []LegState
+		progress    int
+	}{
+		{0, false, []LegState{LegUpcoming, LegUpcoming}, 0},
+		{1, false, []LegState{LegUpcoming, LegUpcoming}, 16},
+		{2, false, []LegState{LegCurrent, LegUpcoming}, 33},
+		{3, false, []LegState{LegCompleted, LegUpcoming}, 50},
+		{3, true, []LegState{LegCompleted, LegDeviated}, 50},
+	}
+
+	for _, tt := range tests {
+		events.QueryHandlingHistoryFn = func(id cargo.TrackingID) cargo.HandlingHistory {
+			return cargo.HandlingHistory{HandlingEvents: history[:tt.events]}
+		}
+		c.Delivery.IsMisdirected = tt.misdirected
+
+		got, err := s.Track("FTL456", token, English)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, leg := range got.Legs {
+			if leg.State != tt.states[i] {
+				t.Errorf("%d events: Legs[%d].State = %s; want = %s", tt.events, i, leg.State, tt.states[i])
+			}
+		}
+		if got.Progress != tt.progress {
+			t.Errorf("%d events: Progress = %d; want = %d", tt.events, got.Progress, tt.progress)
+		}
+	}
+
+	got, _ := s.Track("FTL456", token, English)
+	if leg := got.Legs[0]; leg.ActualLoadTime == nil || !leg.ActualLoadTime.Equal(loaded) {
+		t.Errorf("ActualLoadTime = %v; want = %v", leg.ActualLoadTime, loaded)
+	}
+	if leg := got.Legs[0]; leg.ActualUnloadTime == nil || !leg.ActualUnloadTime.Equal(unloaded) {
+		t.Errorf("ActualUnloadTime = %v; want = %v", leg.ActualUnloadTime, unloaded)
+	}
+	if leg := got.Legs[1]; leg.ActualLoadTime != nil {
+		t.Errorf("ActualLoadTime = %v; want = nil", leg.ActualLoadTime)
+	}
+}
+
+func TestTrackLegsByTruck(t *testing.T) {
+	c := cargo.New("TRK789", cargo.RouteSpecification{
+		Origin:      location.NLRTM,
+		Destination: location.SESTO,
+	})
+	c.AttachParty(cargo.Shipper, "C1")
+	c.AssignToRoute(cargo.Itinerary{Legs: []cargo.Leg{
+		{VoyageNumber: "T100", LoadLocation: location.NLRTM, UnloadLocation: location.DEHAM, Mode: voyage.Road},
+		{VoyageNumber: "V400", LoadLocation: location.DEHAM, UnloadLocation: location.SESTO},
+	}})
+
+	history := []cargo.HandlingEvent{
+		{TrackingID: "TRK789", Activity: cargo.HandlingActivity{Type: cargo.Receive, Location: location.NLRTM}},
+		{TrackingID: "TRK789", Activity: cargo.HandlingActivity{Type: cargo.GateOut, Location: location.NLRTM, VoyageNumber: "T100"}},
+		{TrackingID: "TRK789", Activity: cargo.HandlingActivity{Type: cargo.GateIn, Location: location.DEHAM, VoyageNumber: "T100"}},
+	}
+
+	var cargos mock.CargoRepository
+	cargos.FindFn = func(id cargo.TrackingID) (*cargo.Cargo, error) {
+		return c, nil
+	}
+
+	var events mock.HandlingEventRepository
+
+	grants, token := newGrants()
+
+	s := NewService(&cargos, &events, inmem.NewLocationRepository(), grants)
+
+	tests := []struct {
+		events   int
+		states   []LegState
+		progress int
+	}{
+		{1, []LegState{LegUpcoming, LegUpcoming}, 16},
+		{2, []LegState{LegCurrent, LegUpcoming}, 33},
+		{3, []LegState{LegCompleted, LegUpcoming}, 50},
+	}
+
+	for _, tt := range tests {
+		events.QueryHandlingHistoryFn = func(id cargo.TrackingID) cargo.HandlingHistory {
+			return cargo.HandlingHistory{HandlingEvents: history[:tt.events]}
+		}
+
+		got, err := s.Track("TRK789", token, English)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, leg := range got.Legs {
+			if leg.State != tt.states[i] {
+				t.Errorf("%d events: Legs[%d].State = %s; want = %s", tt.events, i, leg.State, tt.states[i])
+			}
+		}
+		if got.Progress != tt.progress {
+			t.Errorf("%d events: Progress = %d; want = %d", tt.events, got.Progress, tt.progress)
+		}
+		if got.Legs[0].Mode != "road" || got.Legs[1].Mode != "sea" {
+			t.Errorf("modes = %s, %s; want = road, sea", got.Legs[0].Mode, got.Legs[1].Mode)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package tracking
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+	"golang.org/x/net/context"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/eventbus"
+)
+
+var errStreamingUnsupported = errors.New("streaming unsupported")
+
+// DefaultHeartbeat is how often a comment is sent on an otherwise idle
+// stream, to keep proxies from closing the connection.
+const DefaultHeartbeat = 15 * time.Second
+
+// streamHandler pushes the tracking view of a cargo as server-sent events
+// whenever the cargo changes. Each event carries the sequence number of the
+// publication as its id, so that clients reconnecting with Last-Event-ID are
+// only sent the cargo if they have missed a change. The access token is
+// checked again on every heartbeat, so that the stream ends soon after the
+// token has been revoked.
+type streamHandler struct {
+	ts        Service
+	bus       *eventbus.Bus
+	heartbeat time.Duration
+	logger    kitlog.Logger
+}
+
+func (h *streamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := cargo.TrackingID(mux.Vars(r)["id"])
+	lang := ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	token := accessToken(r)
+
+	f, ok := w.(http.Flusher)
+	if !ok {
+		encodeError(context.Background(), errStreamingUnsupported, w)
+		return
+	}
+
+	// Subscribe before the first lookup, so that no change goes unnoticed.
+	sub := h.bus.Subscribe(id)
+	defer sub.Close()
+
+	c, err := h.ts.Track(string(id), token, lang)
+	if err != nil {
+		encodeError(context.Background(), err, w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Vary", "Accept-Language")
+	w.WriteHeader(http.StatusOK)
+
+	seq := h.bus.Last(id)
+
+	lastID, err := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+	if err != nil || lastID != seq {
+		if err := writeEvent(w, seq, "cargo", c); err != nil {
+			return
+		}
+	}
+	f.Flush()
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sub.C:
+			seq = h.bus.Last(id)
+
+			c, err := h.ts.Track(string(id), token, lang)
+			if err != nil {
+				h.fail(w, id, seq, err)
+				f.Flush()
+				return
+			}
+			if err := writeEvent(w, seq, "cargo", c); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := h.ts.Track(string(id), token, lang); err != nil {
+				h.fail(w, id, h.bus.Last(id), err)
+				f.Flush()
+				return
+			}
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+		f.Flush()
+	}
+}
+
+// fail sends an error event, after which the stream ends. Most likely the
+// booking was cancelled or the access token revoked, neither of which is
+// worth logging.
+func (h *streamHandler) fail(w io.Writer, id cargo.TrackingID, seq uint64, err error) {
+	if err != cargo.ErrUnknown && err != ErrUnauthorized {
+		h.logger.Log("tracking_id", id, "err", err)
+	}
+	writeEvent(w, seq, "error", map[string]interface{}{"error": err.Error()})
+}
+
+func writeEvent(w io.Writer, seq uint64, event string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", seq, event, b)
+	return err
+}
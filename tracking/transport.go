@@ -4,17 +4,21 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strings"
 
 	kitlog "github.com/go-kit/kit/log"
 	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
 	"golang.org/x/net/context"
 
+	"github.com/marcusolsson/goddd/access"
 	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/eventbus"
 )
 
-// MakeHandler returns a handler for the tracking service.
-func MakeHandler(ctx context.Context, ts Service, logger kitlog.Logger) http.Handler {
+// MakeHandler returns a handler for the tracking service. Changes to cargos
+// published on the bus are pushed to clients of the stream endpoint.
+func MakeHandler(ctx context.Context, ts Service, bus *eventbus.Bus, logger kitlog.Logger) http.Handler {
 	r := mux.NewRouter()
 
 	opts := []kithttp.ServerOption{
@@ -30,7 +34,33 @@ func MakeHandler(ctx context.Context, ts Service, logger kitlog.Logger) http.Han
 		opts...,
 	)
 
+	trackCargosHandler := kithttp.NewServer(
+		ctx,
+		makeTrackCargosEndpoint(ts),
+		decodeTrackCargosRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	searchCargosHandler := kithttp.NewServer(
+		ctx,
+		makeSearchCargosEndpoint(ts),
+		decodeSearchCargosRequest,
+		encodeResponse,
+		opts...,
+	)
+
+	streamCargoHandler := &streamHandler{
+		ts:        ts,
+		bus:       bus,
+		heartbeat: DefaultHeartbeat,
+		logger:    logger,
+	}
+
+	r.Handle("/tracking/v1/cargos", searchCargosHandler).Methods("GET")
+	r.Handle("/tracking/v1/cargos/batch", trackCargosHandler).Methods("POST")
 	r.Handle("/tracking/v1/cargos/{id}", trackCargoHandler).Methods("GET")
+	r.Handle("/tracking/v1/cargos/{id}/stream", streamCargoHandler).Methods("GET")
 	r.Handle("/tracking/v1/docs", http.StripPrefix("/tracking/v1/docs", http.FileServer(http.Dir("tracking/docs"))))
 
 	return r
@@ -42,7 +72,46 @@ func decodeTrackCargoRequest(_ context.Context, r *http.Request) (interface{}, e
 	if !ok {
 		return nil, errors.New("bad route")
 	}
-	return trackCargoRequest{ID: id}, nil
+	return trackCargoRequest{
+		ID:       id,
+		Token:    accessToken(r),
+		Language: ParseAcceptLanguage(r.Header.Get("Accept-Language")),
+	}, nil
+}
+
+func decodeTrackCargosRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var body struct {
+		TrackingIDs []string `json:"tracking_ids"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	return trackCargosRequest{
+		IDs:      body.TrackingIDs,
+		Token:    accessToken(r),
+		Language: ParseAcceptLanguage(r.Header.Get("Accept-Language")),
+	}, nil
+}
+
+func decodeSearchCargosRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return searchCargosRequest{
+		Ref:      r.URL.Query().Get("ref"),
+		Token:    accessToken(r),
+		Language: ParseAcceptLanguage(r.Header.Get("Accept-Language")),
+	}, nil
+}
+
+// accessToken returns the bearer token of the request. Since browsers cannot
+// set headers on event streams, the token may also be given as a query
+// parameter.
+func accessToken(r *http.Request) access.Token {
+	const prefix = "Bearer "
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, prefix) {
+		return access.Token(strings.TrimSpace(h[len(prefix):]))
+	}
+	return access.Token(r.URL.Query().Get("token"))
 }
 
 func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
@@ -51,6 +120,7 @@ func encodeResponse(ctx context.Context, w http.ResponseWriter, response interfa
 		return nil
 	}
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Vary", "Accept-Language")
 	return json.NewEncoder(w).Encode(response)
 }
 
@@ -60,11 +130,18 @@ type errorer interface {
 
 // encode errors from business-logic
 func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+	if e, ok := err.(kithttp.Error); ok && e.Domain == kithttp.DomainDecode {
+		err = e.Err
+	}
+
 	switch err {
 	case cargo.ErrUnknown:
 		w.WriteHeader(http.StatusNotFound)
 	case ErrInvalidArgument:
 		w.WriteHeader(http.StatusBadRequest)
+	case ErrUnauthorized:
+		w.Header().Set("WWW-Authenticate", `Bearer realm="tracking"`)
+		w.WriteHeader(http.StatusUnauthorized)
 	default:
 		w.WriteHeader(http.StatusInternalServerError)
 	}
@@ -1,18 +1,23 @@
 package tracking
 
 import (
+	"bufio"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-kit/kit/log"
 	"golang.org/x/net/context"
 
+	"github.com/marcusolsson/goddd/access"
 	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/eventbus"
+	"github.com/marcusolsson/goddd/inmem"
 	"github.com/marcusolsson/goddd/mock"
 )
 
@@ -24,13 +29,16 @@ func TestTrackCargo(t *testing.T) {
 		return cargo.HandlingHistory{}
 	}
 
-	s := NewService(&cargos, &events)
+	grants, token := newGrants()
+
+	s := NewService(&cargos, &events, inmem.NewLocationRepository(), grants)
 
 	c := cargo.New("TEST", cargo.RouteSpecification{
 		Origin:          "SESTO",
 		Destination:     "FIHEL",
 		ArrivalDeadline: time.Date(2005, 12, 4, 0, 0, 0, 0, time.UTC),
 	})
+	c.AttachParty(cargo.Shipper, "C1")
 
 	cargos.Store(c)
 
@@ -38,9 +46,10 @@ func TestTrackCargo(t *testing.T) {
 
 	logger := log.NewLogfmtLogger(ioutil.Discard)
 
-	h := MakeHandler(ctx, s, logger)
+	h := MakeHandler(ctx, s, eventbus.New(), logger)
 
 	req, _ := http.NewRequest("GET", "http://example.com/tracking/v1/cargos/TEST", nil)
+	req.Header.Set("Authorization", "Bearer "+string(token))
 	rec := httptest.NewRecorder()
 
 	h.ServeHTTP(rec, req)
@@ -66,12 +75,14 @@ func TestTrackCargo(t *testing.T) {
 
 	want := Cargo{
 		TrackingID:           "TEST",
+		View:                 FullView,
 		Origin:               "SESTO",
 		Destination:          "FIHEL",
 		ArrivalDeadline:      time.Date(2005, 12, 4, 0, 0, 0, 0, time.UTC),
 		ETA:                  eta.In(time.UTC),
 		StatusText:           "Not received",
 		NextExpectedActivity: "There are currently no expected activities for this cargo.",
+		Legs:                 []Leg{},
 		Events:               nil,
 	}
 
@@ -88,15 +99,18 @@ func TestTrackUnknownCargo(t *testing.T) {
 		return cargo.HandlingHistory{}
 	}
 
-	s := NewService(&cargos, &events)
+	grants, token := newGrants()
+
+	s := NewService(&cargos, &events, inmem.NewLocationRepository(), grants)
 
 	ctx := context.Background()
 
 	logger := log.NewLogfmtLogger(ioutil.Discard)
 
-	h := MakeHandler(ctx, s, logger)
+	h := MakeHandler(ctx, s, eventbus.New(), logger)
 
 	req, _ := http.NewRequest("GET", "http://example.com/tracking/v1/cargos/not_found", nil)
+	req.Header.Set("Authorization", "Bearer "+string(token))
 	rec := httptest.NewRecorder()
 
 	h.ServeHTTP(rec, req)
@@ -124,6 +138,118 @@ func TestTrackUnknownCargo(t *testing.T) {
 	}
 }
 
+func TestTrackCargoUnauthorized(t *testing.T) {
+	var cargos mockCargoRepository
+
+	var events mock.HandlingEventRepository
+	events.QueryHandlingHistoryFn = func(cargo.TrackingID) cargo.HandlingHistory {
+		return cargo.HandlingHistory{}
+	}
+
+	s := NewService(&cargos, &events, inmem.NewLocationRepository(), inmem.NewGrantRepository())
+
+	cargos.Store(cargo.New("TEST", cargo.RouteSpecification{
+		Origin:      "SESTO",
+		Destination: "FIHEL",
+	}))
+
+	h := MakeHandler(context.Background(), s, eventbus.New(), log.NewNopLogger())
+
+	req, _ := http.NewRequest("GET", "http://example.com/tracking/v1/cargos/TEST", nil)
+	rec := httptest.NewRecorder()
+
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("rec.Code = %d; want = %d", rec.Code, http.StatusUnauthorized)
+	}
+	if got := rec.Header().Get("WWW-Authenticate"); got == "" {
+		t.Errorf("missing WWW-Authenticate header")
+	}
+}
+
+func TestStreamCargo(t *testing.T) {
+	var cargos mockCargoRepository
+
+	var events mock.HandlingEventRepository
+	events.QueryHandlingHistoryFn = func(cargo.TrackingID) cargo.HandlingHistory {
+		return cargo.HandlingHistory{}
+	}
+
+	share := access.NewShareToken("TEST", time.Now())
+	grants := inmem.NewGrantRepository()
+	grants.Store(share)
+
+	s := NewService(&cargos, &events, inmem.NewLocationRepository(), grants)
+
+	cargos.Store(cargo.New("TEST", cargo.RouteSpecification{
+		Origin:      "SESTO",
+		Destination: "FIHEL",
+	}))
+
+	bus := eventbus.New()
+	bus.Publish("TEST")
+
+	srv := httptest.NewServer(MakeHandler(context.Background(), s, bus, log.NewNopLogger()))
+	defer srv.Close()
+
+	// Resuming from the latest publication should not repeat the cargo.
+	// EventSource cannot set headers, so the token is given in the query.
+	req, _ := http.NewRequest("GET", srv.URL+"/tracking/v1/cargos/TEST/stream?token="+string(share.Token), nil)
+	req.Header.Set("Last-Event-ID", "1")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q; want = %q", ct, "text/event-stream")
+	}
+
+	cargos.cargo.Delivery.TransportStatus = cargo.InPort
+	cargos.cargo.Delivery.LastKnownLocation = "SESTO"
+
+	// The headers are written once the subscription is in place.
+	bus.Publish("TEST")
+
+	r := bufio.NewReader(resp.Body)
+
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line == "\n" {
+			break
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+
+	if len(lines) != 3 {
+		t.Fatalf("lines = %q; want 3 lines", lines)
+	}
+	if lines[0] != "id: 2" {
+		t.Errorf("lines[0] = %q; want = %q", lines[0], "id: 2")
+	}
+	if lines[1] != "event: cargo" {
+		t.Errorf("lines[1] = %q; want = %q", lines[1], "event: cargo")
+	}
+
+	var c Cargo
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(lines[2], "data: ")), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.StatusText != "In port Stockholm" {
+		t.Errorf("c.StatusText = %q; want = %q", c.StatusText, "In port Stockholm")
+	}
+	if c.View != PublicView {
+		t.Errorf("c.View = %q; want = %q", c.View, PublicView)
+	}
+}
+
 type mockCargoRepository struct {
 	cargo *cargo.Cargo
 }
@@ -140,6 +266,41 @@ func (r *mockCargoRepository) Find(id cargo.TrackingID) (*cargo.Cargo, error) {
 	return nil, cargo.ErrUnknown
 }
 
+func (r *mockCargoRepository) FindMany(ids []cargo.TrackingID) ([]*cargo.Cargo, error) {
+	var cargos []*cargo.Cargo
+	for _, id := range ids {
+		if r.cargo != nil && r.cargo.TrackingID == id {
+			cargos = append(cargos, r.cargo)
+		}
+	}
+	return cargos, nil
+}
+
+func (r *mockCargoRepository) FindByReference(value string) ([]*cargo.Cargo, error) {
+	var cargos []*cargo.Cargo
+	if r.cargo != nil {
+		for _, ref := range r.cargo.References {
+			if ref.Value == cargo.NormalizeReference(value) {
+				cargos = append(cargos, r.cargo)
+				break
+			}
+		}
+	}
+	return cargos, nil
+}
+
 func (r *mockCargoRepository) FindAll() []*cargo.Cargo {
 	return []*cargo.Cargo{r.cargo}
 }
+
+func (r *mockCargoRepository) Query(q cargo.Query) (cargo.Page, error) {
+	return cargo.Page{Cargos: r.FindAll()}, nil
+}
+
+func (r *mockCargoRepository) Remove(id cargo.TrackingID) error {
+	if r.cargo == nil {
+		return cargo.ErrUnknown
+	}
+	r.cargo = nil
+	return nil
+}
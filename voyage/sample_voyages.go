@@ -1,34 +1,87 @@
 package voyage
 
-import "github.com/marcusolsson/goddd/location"
+import (
+	"time"
 
-// A set of sample voyages.
+	"github.com/marcusolsson/goddd/location"
+)
+
+// A set of sample voyages. Together they connect Hongkong with Stockholm,
+// via Tokyo and Hamburg, and with Chicago by rail via New York. Each of them
+// sails its rotation over and over again, so that there are always upcoming
+// departures to route cargos onto.
 var (
-	V100 = New("V100", Schedule{
-		[]CarrierMovement{
-			{DepartureLocation: location.CNHKG, ArrivalLocation: location.JNTKO},
-			{DepartureLocation: location.JNTKO, ArrivalLocation: location.USNYC},
-		},
-	})
-
-	V300 = New("V300", Schedule{
-		[]CarrierMovement{
-			{DepartureLocation: location.JNTKO, ArrivalLocation: location.NLRTM},
-			{DepartureLocation: location.NLRTM, ArrivalLocation: location.DEHAM},
-			{DepartureLocation: location.DEHAM, ArrivalLocation: location.AUMEL},
-			{DepartureLocation: location.AUMEL, ArrivalLocation: location.JNTKO},
-		},
-	})
-
-	V400 = New("V400", Schedule{
-		[]CarrierMovement{
-			{DepartureLocation: location.DEHAM, ArrivalLocation: location.SESTO},
-			{DepartureLocation: location.SESTO, ArrivalLocation: location.FIHEL},
-			{DepartureLocation: location.FIHEL, ArrivalLocation: location.DEHAM},
-		},
-	})
+	V100 = &Voyage{Number: "V100", Carrier: "Pacific Line", Schedule: sampleSchedule(21,
+		CarrierMovement{location.CNHKG, location.JNTKO, sampleTime(0, 8), sampleTime(3, 16)},
+		CarrierMovement{location.JNTKO, location.USNYC, sampleTime(4, 8), sampleTime(16, 12)},
+	)}
+
+	V300 = &Voyage{Number: "V300", Carrier: "Atlantic Line", Schedule: sampleSchedule(70,
+		CarrierMovement{location.JNTKO, location.NLRTM, sampleTime(5, 6), sampleTime(26, 18)},
+		CarrierMovement{location.NLRTM, location.DEHAM, sampleTime(27, 12), sampleTime(28, 18)},
+		CarrierMovement{location.DEHAM, location.AUMEL, sampleTime(30, 6), sampleTime(52, 12)},
+		CarrierMovement{location.AUMEL, location.JNTKO, sampleTime(54, 6), sampleTime(66, 12)},
+	)}
+
+	V400 = &Voyage{Number: "V400", Carrier: "Baltic Feeder", Schedule: sampleSchedule(7,
+		CarrierMovement{location.DEHAM, location.SESTO, sampleTime(29, 6), sampleTime(31, 12)},
+		CarrierMovement{location.SESTO, location.FIHEL, sampleTime(32, 6), sampleTime(32, 18)},
+		CarrierMovement{location.FIHEL, location.DEHAM, sampleTime(33, 6), sampleTime(35, 12)},
+	)}
+
+	R100 = &Voyage{Number: "R100", Carrier: "Atlantic Rail", Mode: Rail, Schedule: sampleSchedule(7,
+		CarrierMovement{location.USNYC, location.USCHI, sampleTime(17, 8), sampleTime(18, 20)},
+	)}
+
+	T100 = &Voyage{Number: "T100", Carrier: "Rhine Haulage", Mode: Road, Schedule: sampleSchedule(1,
+		CarrierMovement{location.NLRTM, location.DEHAM, sampleTime(27, 6), sampleTime(27, 14)},
+	)}
 )
 
+// sampleStart is midnight UTC the day after the application was started.
+var sampleStart = time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+
+// sampleDays is how many days ahead of the start the sample voyages are
+// scheduled.
+const sampleDays = 365
+
+// sampleTime returns the time at the given hour of a day of the sample
+// schedules.
+func sampleTime(day, hour int) time.Time {
+	return sampleStart.AddDate(0, 0, day).Add(time.Duration(hour) * time.Hour)
+}
+
+// sampleSchedule repeats the rotation of a voyage every period days, from
+// the rotation under way at the start until sampleDays ahead. The period must
+// be at least as long as the rotation, so that the movements stay in order.
+func sampleSchedule(period int, rotation ...CarrierMovement) Schedule {
+	var (
+		first = rotation[0].DepartureTime
+		last  = rotation[len(rotation)-1].ArrivalTime
+		end   = sampleStart.AddDate(0, 0, sampleDays)
+	)
+
+	// Start with the rotation under way at the start, if there is one.
+	n := 0
+	for first.AddDate(0, 0, n*period).After(sampleStart) {
+		n--
+	}
+	for last.AddDate(0, 0, n*period).Before(sampleStart) {
+		n++
+	}
+
+	var movements []CarrierMovement
+	for ; first.AddDate(0, 0, n*period).Before(end); n++ {
+		for _, m := range rotation {
+			m.DepartureTime = m.DepartureTime.AddDate(0, 0, n*period)
+			m.ArrivalTime = m.ArrivalTime.AddDate(0, 0, n*period)
+			movements = append(movements, m)
+		}
+	}
+
+	return Schedule{movements}
+}
+
 // These voyages are hard-coded into the current pathfinder. Make sure
 // they exist.
 var (
@@ -3,6 +3,7 @@ package voyage
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/marcusolsson/goddd/location"
@@ -15,6 +16,67 @@ type Number string
 type Voyage struct {
 	Number   Number
 	Schedule Schedule
+
+	// Carrier is the shipping line operating the voyage, if known.
+	Carrier string
+
+	// Mode is the means of transport, a vessel unless stated otherwise.
+	Mode Mode
+}
+
+// Mode is the means of transport of a voyage.
+type Mode int
+
+// Valid transport modes.
+const (
+	Sea Mode = iota
+	Rail
+	Road
+	Barge
+)
+
+func (m Mode) String() string {
+	switch m {
+	case Sea:
+		return "Sea"
+	case Rail:
+		return "Rail"
+	case Road:
+		return "Road"
+	case Barge:
+		return "Barge"
+	}
+	return ""
+}
+
+// ErrUnknownMode is used when a transport mode could not be parsed.
+var ErrUnknownMode = errors.New("unknown transport mode")
+
+// ParseMode returns the transport mode with the given name, regardless of
+// case.
+func ParseMode(s string) (Mode, error) {
+	for _, m := range []Mode{Sea, Rail, Road, Barge} {
+		if strings.EqualFold(s, m.String()) {
+			return m, nil
+		}
+	}
+	return Sea, ErrUnknownMode
+}
+
+// MarshalText encodes the mode by its lower-case name, e.g. in legs of
+// itineraries.
+func (m Mode) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(m.String())), nil
+}
+
+// UnmarshalText decodes a mode by its name.
+func (m *Mode) UnmarshalText(text []byte) error {
+	mode, err := ParseMode(string(text))
+	if err != nil {
+		return err
+	}
+	*m = mode
+	return nil
 }
 
 // New creates a voyage with a voyage number and a provided schedule.
@@ -41,4 +103,12 @@ var ErrUnknown = errors.New("unknown voyage")
 // Repository provides access a voyage store.
 type Repository interface {
 	Find(Number) (*Voyage, error)
+
+	// FindMany returns the voyages matching any of the numbers. Unknown
+	// voyage numbers are left out.
+	FindMany([]Number) ([]*Voyage, error)
+
+	// FindAll returns all voyages, e.g. to search their schedules for
+	// routes.
+	FindAll() []*Voyage
 }
@@ -0,0 +1,225 @@
+// Package webhook provides the Subscription aggregate, i.e. a callback URL
+// that wants to be told about changes in the delivery of cargos, and the log
+// of deliveries made to it.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/pborman/uuid"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/customer"
+)
+
+// SubscriptionID uniquely identifies a subscription.
+type SubscriptionID string
+
+// Subscription is a callback URL registered for either a single cargo or
+// every cargo of a customer.
+type Subscription struct {
+	ID         SubscriptionID
+	TrackingID cargo.TrackingID
+	Customer   customer.ID
+	URL        string
+
+	// Secret is used to sign the payloads sent to the callback URL.
+	Secret  string
+	Created time.Time
+
+	// Public is set for subscriptions made with a share token, which are
+	// only sent what the public view of the cargo shows.
+	Public bool
+}
+
+// NewSubscription creates a new subscription with a random secret.
+func NewSubscription(id SubscriptionID, trackingID cargo.TrackingID, customerID customer.ID, url string) *Subscription {
+	return &Subscription{
+		ID:         id,
+		TrackingID: trackingID,
+		Customer:   customerID,
+		URL:        url,
+		Secret:     newSecret(),
+		Created:    time.Now(),
+	}
+}
+
+// Matches checks whether the subscription covers the cargo.
+func (s *Subscription) Matches(c *cargo.Cargo) bool {
+	if s.TrackingID != "" {
+		return s.TrackingID == c.TrackingID
+	}
+	return s.Customer != "" && c.Parties.Includes(s.Customer)
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of the payload, keyed with the
+// secret of the subscription.
+func (s *Subscription) Sign(payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(s.Secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// DeliveryID uniquely identifies a delivery.
+type DeliveryID string
+
+// DeliveryState describes how far a delivery has come.
+type DeliveryState int
+
+// Valid delivery states.
+const (
+	Pending DeliveryState = iota
+	Delivered
+	DeadLettered
+)
+
+func (s DeliveryState) String() string {
+	switch s {
+	case Pending:
+		return "Pending"
+	case Delivered:
+		return "Delivered"
+	case DeadLettered:
+		return "Dead lettered"
+	}
+	return ""
+}
+
+// Attempt is a single try to post a payload to a callback URL.
+type Attempt struct {
+	Time       time.Time
+	StatusCode int
+	Error      string
+}
+
+// Delivery is a payload on its way to a subscriber, along with the attempts
+// made so far.
+type Delivery struct {
+	ID             DeliveryID
+	SubscriptionID SubscriptionID
+	TrackingID     cargo.TrackingID
+	Payload        []byte
+	State          DeliveryState
+	Attempts       []Attempt
+	NextAttempt    time.Time
+	Created        time.Time
+}
+
+// NewDelivery creates a delivery that is due immediately.
+func NewDelivery(id DeliveryID, sub SubscriptionID, trackingID cargo.TrackingID, payload []byte) *Delivery {
+	now := time.Now()
+	return &Delivery{
+		ID:             id,
+		SubscriptionID: sub,
+		TrackingID:     trackingID,
+		Payload:        payload,
+		State:          Pending,
+		NextAttempt:    now,
+		Created:        now,
+	}
+}
+
+// Record adds an attempt to the delivery. Successful attempts complete the
+// delivery, failed ones schedule a new attempt according to the backoff, or
+// move the delivery to the dead letters once the attempts are used up.
+func (d *Delivery) Record(a Attempt, b Backoff) {
+	d.Attempts = append(d.Attempts, a)
+
+	if a.Error == "" && a.StatusCode >= 200 && a.StatusCode < 300 {
+		d.State = Delivered
+		return
+	}
+
+	if len(d.Attempts) >= b.MaxAttempts {
+		d.State = DeadLettered
+		return
+	}
+
+	d.NextAttempt = a.Time.Add(b.Delay(len(d.Attempts)))
+}
+
+// Redeliver returns a dead-lettered delivery to the pending ones, with a
+// fresh set of attempts.
+func (d *Delivery) Redeliver() {
+	d.State = Pending
+	d.Attempts = nil
+	d.NextAttempt = time.Now()
+}
+
+// Backoff decides how long to wait between failed attempts.
+type Backoff struct {
+	Initial     time.Duration
+	Max         time.Duration
+	MaxAttempts int
+}
+
+// DefaultBackoff retries for about a day before giving up.
+var DefaultBackoff = Backoff{
+	Initial:     10 * time.Second,
+	Max:         4 * time.Hour,
+	MaxAttempts: 12,
+}
+
+// Delay returns the time to wait after the given number of failed attempts,
+// doubling for each attempt.
+func (b Backoff) Delay(attempts int) time.Duration {
+	d := b.Initial
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= b.Max {
+			return b.Max
+		}
+	}
+	return d
+}
+
+// ErrUnknownSubscription is used when a subscription could not be found.
+var ErrUnknownSubscription = errors.New("unknown subscription")
+
+// ErrUnknownDelivery is used when a delivery could not be found.
+var ErrUnknownDelivery = errors.New("unknown delivery")
+
+// SubscriptionRepository provides access a subscription store.
+type SubscriptionRepository interface {
+	Store(s *Subscription) error
+	Find(id SubscriptionID) (*Subscription, error)
+	FindAll() []*Subscription
+	Remove(id SubscriptionID) error
+}
+
+// DeliveryRepository provides access a delivery store.
+type DeliveryRepository interface {
+	Store(d *Delivery) error
+	Find(id DeliveryID) (*Delivery, error)
+	FindBySubscription(id SubscriptionID) []*Delivery
+
+	// FindDue returns the pending deliveries whose next attempt is due at
+	// the given time.
+	FindDue(t time.Time) []*Delivery
+
+	FindByState(s DeliveryState) []*Delivery
+}
+
+// NextSubscriptionID generates a new subscription ID.
+func NextSubscriptionID() SubscriptionID {
+	return SubscriptionID(strings.ToUpper(uuid.New()))
+}
+
+// NextDeliveryID generates a new delivery ID.
+func NextDeliveryID() DeliveryID {
+	return DeliveryID(strings.ToUpper(uuid.New()))
+}
+
+func newSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
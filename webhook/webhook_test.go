@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marcusolsson/goddd/cargo"
+	"github.com/marcusolsson/goddd/location"
+)
+
+func TestRecord(t *testing.T) {
+	b := Backoff{Initial: time.Second, Max: 3 * time.Second, MaxAttempts: 4}
+
+	d := NewDelivery("D1", "S1", "ABC123", []byte("{}"))
+
+	now := time.Date(2016, time.May, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		status int
+		next   time.Duration
+		state  DeliveryState
+	}{
+		{500, 1 * time.Second, Pending},
+		{500, 2 * time.Second, Pending},
+		{0, 3 * time.Second, Pending},
+		{500, 0, DeadLettered},
+	}
+
+	for _, tt := range tests {
+		a := Attempt{Time: now, StatusCode: tt.status}
+		if tt.status == 0 {
+			a.Error = "connection refused"
+		}
+
+		d.Record(a, b)
+
+		if d.State != tt.state {
+			t.Errorf("d.State = %s; want = %s", d.State, tt.state)
+		}
+		if tt.state == Pending && !d.NextAttempt.Equal(now.Add(tt.next)) {
+			t.Errorf("d.NextAttempt = %v; want = %v", d.NextAttempt, now.Add(tt.next))
+		}
+	}
+
+	d.Redeliver()
+	d.Record(Attempt{Time: now, StatusCode: 204}, b)
+
+	if d.State != Delivered {
+		t.Errorf("d.State = %s; want = %s", d.State, Delivered)
+	}
+	if len(d.Attempts) != 1 {
+		t.Errorf("len(d.Attempts) = %d; want = %d", len(d.Attempts), 1)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	c := cargo.New("ABC123", cargo.RouteSpecification{
+		Origin:      location.SESTO,
+		Destination: location.AUMEL,
+	})
+	c.AttachParty(cargo.Consignee, "C1")
+
+	tests := []struct {
+		sub  *Subscription
+		want bool
+	}{
+		{NewSubscription("S1", "ABC123", "", "http://example.com"), true},
+		{NewSubscription("S2", "XYZ789", "", "http://example.com"), false},
+		{NewSubscription("S3", "", "C1", "http://example.com"), true},
+		{NewSubscription("S4", "", "C2", "http://example.com"), false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.sub.Matches(c); got != tt.want {
+			t.Errorf("Matches() for %s = %v; want = %v", tt.sub.ID, got, tt.want)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	s := &Subscription{Secret: "secret"}
+
+	got := s.Sign([]byte("payload"))
+	want := "b82fcb791acec57859b989b430a826488ce2e479fdf92326bd0a2e8375a42ba4"
+
+	if got != want {
+		t.Errorf("Sign() = %s; want = %s", got, want)
+	}
+}